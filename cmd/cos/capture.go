@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	cos "github.com/calamity-of-subterfuge/cos/pkg"
+	"github.com/gorilla/websocket"
+)
+
+func runCapture(args []string, stdout io.Writer) error {
+	fs := newFlagSet("capture")
+	cos.RegisterConfigFlags(fs)
+	duration := fs.Duration("duration", 0, "stop capturing after this long; 0 captures until the game ends")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	out, err := os.Create(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	cfg, auth, err := loginWithConfig(fs)
+	if err != nil {
+		return err
+	}
+
+	rawLobbyConn, _, err := cos.QueueAI(cfg.AIConfig, auth)
+	if err != nil {
+		return err
+	}
+	lobbyConn := keepAlive(rawLobbyConn)
+	defer lobbyConn.Close()
+
+	stop, stopListening := interrupted()
+	defer stopListening()
+
+	fmt.Fprintf(stdout, "Queued %s %s; waiting for a match...\n", cfg.AIConfig.AIName, cfg.AIConfig.Version)
+	url, jwt, err := waitForMatch(lobbyConn, stop)
+	if err != nil {
+		return err
+	}
+
+	rawGameConn, err := cos.ConnectGame(url, jwt)
+	if err != nil {
+		return err
+	}
+	gameConn := keepAlive(rawGameConn)
+	defer gameConn.Close()
+	fmt.Fprintf(stdout, "Connected to %s; capturing to %s\n", url, fs.Arg(0))
+
+	var deadline <-chan time.Time
+	if *duration > 0 {
+		deadline = time.After(*duration)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+		case <-deadline:
+		case <-done:
+			return
+		}
+		gameConn.Close()
+	}()
+
+	writer := newCaptureWriter(out)
+	start := time.Now()
+	frames := 0
+	for {
+		msgType, frame, err := gameConn.ReadMessage()
+		if err != nil {
+			fmt.Fprintf(stdout, "Captured %d frames over %s (%v)\n", frames, time.Since(start).Round(time.Millisecond), err)
+			return out.Sync()
+		}
+		if msgType != websocket.TextMessage {
+			fmt.Fprintf(stdout, "Skipping non-text frame of %d bytes\n", len(frame))
+			continue
+		}
+
+		err = writer.Write(&captureRecord{At: time.Since(start).Seconds(), Frame: frame})
+		if err != nil {
+			return err
+		}
+		frames++
+	}
+}
+
+// waitForMatch reads lobby notifications until a match is available,
+// returning its url and jwt. Frames are split into notifications the same
+// way cos.Conn does, since the lobby may batch them.
+func waitForMatch(lobbyConn *keptAliveConn, stop chan struct{}) (string, string, error) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			lobbyConn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		_, message, err := lobbyConn.ReadMessage()
+		if err != nil {
+			select {
+			case <-stop:
+				return "", "", errors.New("interrupted")
+			default:
+				return "", "", fmt.Errorf("reading from lobby: %w", err)
+			}
+		}
+
+		notifications, err := cos.JSONCodec{}.DecodeFrame(message)
+		if err != nil {
+			continue
+		}
+		for _, notification := range notifications {
+			if notification["type"] != "match-available" {
+				continue
+			}
+			url, _ := notification["url"].(string)
+			jwt, _ := notification["jwt"].(string)
+			return url, jwt, nil
+		}
+	}
+}
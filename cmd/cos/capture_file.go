@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// captureRecord is a single line of a capture file, which contains one
+// frame received from the game server. Capture files are JSON lines so
+// they can be inspected and edited with ordinary tools.
+type captureRecord struct {
+	// At is the number of seconds since the capture started when the frame
+	// was received
+	At float64 `json:"at"`
+
+	// Frame is the frame as it was received, either a single packet or an
+	// array of packets
+	Frame json.RawMessage `json:"frame"`
+}
+
+// captureWriter writes capture records to an underlying writer
+type captureWriter struct {
+	encoder *json.Encoder
+}
+
+func newCaptureWriter(w io.Writer) *captureWriter {
+	return &captureWriter{encoder: json.NewEncoder(w)}
+}
+
+// Write the given record to the capture
+func (w *captureWriter) Write(record *captureRecord) error {
+	if !json.Valid(record.Frame) {
+		return fmt.Errorf("frame is not valid JSON: %q", string(record.Frame))
+	}
+
+	err := w.encoder.Encode(record)
+	if err != nil {
+		return fmt.Errorf("encoding record: %w", err)
+	}
+	return nil
+}
+
+// readCapture reads every record from the capture in the given reader
+func readCapture(r io.Reader) ([]captureRecord, error) {
+	scanner := bufio.NewScanner(r)
+	// game syncs can be very large
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	var res []captureRecord
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record captureRecord
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Frame == nil {
+			return nil, fmt.Errorf("line %d: missing frame", line)
+		}
+		res = append(res, record)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("reading capture: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWaitForMatch_batched(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrading: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "welcome"}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(
+			`[{"type": "queued"}, {"type": "match-available", "url": "wss://game", "jwt": "token"}]`))
+		conn.ReadMessage()
+	}))
+	defer srv.Close()
+
+	rawConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dialing: %v", err)
+	}
+	conn := keepAlive(rawConn)
+	defer conn.Close()
+
+	url, jwt, err := waitForMatch(conn, make(chan struct{}))
+	if err != nil || url != "wss://game" || jwt != "token" {
+		t.Fatalf("expected the batched match, got %q, %q, %v", url, jwt, err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/clipkts"
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+)
+
+func runDecode(args []string, stdout io.Writer) error {
+	fs := newFlagSet("decode")
+	client := fs.Bool("client", false, "decode client packets rather than server packets")
+	frameIdx := fs.Int("frame", -1, "index of the frame to decode within a capture file; -1 decodes every frame")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return errUsage
+	}
+	if *frameIdx < -1 {
+		return fmt.Errorf("invalid frame %d; use -1 to decode every frame", *frameIdx)
+	}
+
+	var raw []byte
+	var err error
+	if fs.NArg() == 0 || fs.Arg(0) == "-" {
+		raw, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		raw, err = ioutil.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	parse := parseServerPacket
+	if *client {
+		parse = parseClientPacket
+	}
+
+	records, err := readCapture(bytes.NewReader(raw))
+	if err != nil || !isCapture(raw) {
+		// not a capture; the whole input is a single frame
+		return decodeFrame(stdout, raw, parse)
+	}
+
+	if *frameIdx >= len(records) {
+		return fmt.Errorf("frame %d out of range; the capture has %d frames", *frameIdx, len(records))
+	}
+	for idx, record := range records {
+		if *frameIdx >= 0 && idx != *frameIdx {
+			continue
+		}
+
+		fmt.Fprintf(stdout, "# frame %d at %.3fs\n", idx, record.At)
+		err = decodeFrame(stdout, record.Frame, parse)
+		if err != nil {
+			return fmt.Errorf("frame %d: %w", idx, err)
+		}
+	}
+	return nil
+}
+
+// isCapture returns true if the given input looks like a capture file rather
+// than a frame, i.e., its first value is an object with a frame key
+func isCapture(raw []byte) bool {
+	var first map[string]json.RawMessage
+	err := json.NewDecoder(bytes.NewReader(raw)).Decode(&first)
+	if err != nil {
+		return false
+	}
+	_, found := first["frame"]
+	return found
+}
+
+// decodeFrame pretty-prints each packet in the given frame using the given
+// parser. Packets which fail to parse are reported without stopping.
+func decodeFrame(stdout io.Writer, frame []byte, parse func([]byte) (interface{}, error)) error {
+	trimmed := bytes.TrimSpace(frame)
+	var packets []json.RawMessage
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		err := json.Unmarshal(trimmed, &packets)
+		if err != nil {
+			return fmt.Errorf("parsing frame: %w", err)
+		}
+	} else {
+		packets = []json.RawMessage{trimmed}
+	}
+
+	for _, raw := range packets {
+		packet, err := parse(raw)
+		if err != nil {
+			fmt.Fprintf(stdout, "error: %v\n%s\n", err, string(raw))
+			continue
+		}
+
+		fmt.Fprintf(stdout, "%T\n", packet)
+		printJSON(stdout, packet)
+	}
+	return nil
+}
+
+func parseServerPacket(raw []byte) (interface{}, error) {
+	packets, err := srvpkts.ParsePacket(raw)
+	if err != nil {
+		return nil, err
+	}
+	packets[0].PrepareForMarshal()
+	return packets[0], nil
+}
+
+func parseClientPacket(raw []byte) (interface{}, error) {
+	packets, err := clipkts.ParsePacket(raw)
+	if err != nil {
+		return nil, err
+	}
+	packets[0].PrepareForMarshal()
+	return packets[0], nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+	"github.com/gorilla/websocket"
+)
+
+// keptAliveConn is a websocket which is kept alive the same way cos.Conn
+// does, by pinging it regularly and extending the read deadline whenever
+// something is received. It's used instead of cos.Conn where we need the
+// frames exactly as they were received.
+type keptAliveConn struct {
+	conn *websocket.Conn
+
+	// writeMutex guards writes, since pings are written from another
+	// goroutine
+	writeMutex sync.Mutex
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// keepAlive starts pinging the given connection until it's closed with
+// Close
+func keepAlive(conn *websocket.Conn) *keptAliveConn {
+	res := &keptAliveConn{conn: conn, done: make(chan struct{})}
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(utils.CONN_READ_TIMEOUT))
+	})
+	go res.ping()
+	return res
+}
+
+// ping pings the connection slightly more often than the timeouts require
+func (c *keptAliveConn) ping() {
+	lowerTimeout := utils.CONN_READ_TIMEOUT
+	if utils.CONN_WRITE_TIMEOUT < lowerTimeout {
+		lowerTimeout = utils.CONN_WRITE_TIMEOUT
+	}
+	ticker := time.NewTicker((lowerTimeout * 9) / 10)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if c.write(websocket.PingMessage, nil) != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// write writes a message with the write timeout
+func (c *keptAliveConn) write(messageType int, data []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	err := c.conn.SetWriteDeadline(time.Now().Add(utils.CONN_WRITE_TIMEOUT))
+	if err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// ReadMessage reads the next message, failing if nothing, not even a pong,
+// is received within the read timeout
+func (c *keptAliveConn) ReadMessage() (int, []byte, error) {
+	err := c.conn.SetReadDeadline(time.Now().Add(utils.CONN_READ_TIMEOUT))
+	if err != nil {
+		return 0, nil, err
+	}
+	return c.conn.ReadMessage()
+}
+
+// Close stops pinging, tells the server we're closing normally and closes
+// the connection. This may be called from any goroutine and more than once.
+func (c *keptAliveConn) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		c.conn.Close()
+	})
+}
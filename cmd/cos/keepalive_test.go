@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+	"github.com/gorilla/websocket"
+)
+
+func TestKeepAlive(t *testing.T) {
+	readTimeout, writeTimeout := utils.CONN_READ_TIMEOUT, utils.CONN_WRITE_TIMEOUT
+	utils.CONN_READ_TIMEOUT, utils.CONN_WRITE_TIMEOUT = 200*time.Millisecond, 200*time.Millisecond
+	defer func() { utils.CONN_READ_TIMEOUT, utils.CONN_WRITE_TIMEOUT = readTimeout, writeTimeout }()
+
+	// the server stays quiet for several read timeouts, only answering
+	// pings, before sending anything
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrading: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		time.Sleep(800 * time.Millisecond)
+		conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	rawConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dialing: %v", err)
+	}
+	conn := keepAlive(rawConn)
+	defer conn.Close()
+
+	_, message, err := conn.ReadMessage()
+	if err != nil || string(message) != "hello" {
+		t.Fatalf("expected the connection to be kept alive until hello, got %q, %v", message, err)
+	}
+}
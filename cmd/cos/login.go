@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	cos "github.com/calamity-of-subterfuge/cos/pkg"
+)
+
+func runLogin(args []string, stdout io.Writer) error {
+	fs := newFlagSet("login")
+	cos.RegisterConfigFlags(fs)
+	logout := fs.Bool("logout", false, "try to logout afterward rather than leaving the token to expire")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	file, err := cos.LoadConfigFile(&cos.ConfigSources{Flags: fs})
+	if err != nil {
+		return err
+	}
+	if file.Email == "" || file.GrantIden == "" || file.Secret == "" {
+		return errors.New("email, grant_iden and secret are all required")
+	}
+
+	auth, err := cos.Login(file.Email, file.GrantIden, file.Secret)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Logged in as %s; token expires at %s (in %s)\n",
+		file.Email, auth.ExpiresAt.Format(time.RFC3339), time.Until(auth.ExpiresAt).Round(time.Second))
+
+	if *logout {
+		// the credentials worked whether or not logging out does
+		err = cos.Logout(auth)
+		if err != nil {
+			fmt.Fprintf(stdout, "WARN: failed to logout; the token will expire on its own: %v\n", err)
+			return nil
+		}
+		fmt.Fprintln(stdout, "Logged out")
+	}
+	return nil
+}
+
+// loginWithConfig loads and validates the config from the given flags and
+// logs in, for commands which need to queue the AI
+func loginWithConfig(fs *flag.FlagSet) (*cos.Config, *cos.AuthToken, error) {
+	cfg, err := cos.LoadConfig(&cos.ConfigSources{Flags: fs})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	auth, err := cos.Login(cfg.Email, cfg.GrantIden, cfg.Secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, auth, nil
+}
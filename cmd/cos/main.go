@@ -0,0 +1,150 @@
+// Command cos is a tool for debugging calamity of subterfuge AIs: checking
+// credentials and configuration, watching the lobby, and capturing, replaying
+// and decoding game sessions.
+//
+// Usage:
+//
+//	cos <command> [flags] [args]
+//
+// Run cos help for the list of commands, or cos <command> -help for the flags
+// of a command. Commands which need credentials or an AI configuration read
+// them as in cos.LoadConfig, i.e., from -config, COS_* environment variables
+// or flags.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+)
+
+// command is a single subcommand of the tool
+type command struct {
+	// usage is the arguments after the command name
+	usage string
+
+	// description is a one line summary of the command
+	description string
+
+	// run executes the command with the arguments after its name, writing
+	// its output to stdout
+	run func(args []string, stdout io.Writer) error
+}
+
+// commands are the subcommands by name, which are initialized in init since
+// they refer back to commands for their usage
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"login": {
+			usage:       "[flags]",
+			description: "check the credentials by logging in",
+			run:         runLogin,
+		},
+		"queue": {
+			usage:       "[flags]",
+			description: "register the AI with the lobby and print notifications until interrupted",
+			run:         runQueue,
+		},
+		"capture": {
+			usage:       "[flags] <file>",
+			description: "queue the AI and save the frames of the next game to a file",
+			run:         runCapture,
+		},
+		"replay": {
+			usage:       "[flags] <file>",
+			description: "feed a captured game through client.State and print what happened",
+			run:         runReplay,
+		},
+		"decode": {
+			usage:       "[flags] [file]",
+			description: "pretty-print the packets in a frame or a captured frame",
+			run:         runDecode,
+		},
+		"validate-config": {
+			usage:       "[flags]",
+			description: "load and validate the configuration",
+			run:         runValidateConfig,
+		},
+	}
+}
+
+// errUsage is returned by commands when they were given bad arguments,
+// after they've printed their usage
+var errUsage = errors.New("bad usage")
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-help" || os.Args[1] == "-h" {
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+
+	cmd, found := commands[os.Args[1]]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+
+	err := cmd.run(os.Args[2:], os.Stdout)
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cos %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: cos <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-16s %s\n", name, commands[name].description)
+	}
+}
+
+// newFlagSet returns the flag set for the given command, which prints the
+// command usage on errors
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("cos "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: cos %s %s\n\n%s\n\nflags:\n", name, commands[name].usage, commands[name].description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// interrupted returns a channel which is closed when the process receives
+// an interrupt, and a function to stop listening for interrupts
+func interrupted() (chan struct{}, func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	res := make(chan struct{})
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			close(res)
+		case <-stop:
+		}
+	}()
+
+	return res, func() {
+		signal.Stop(signals)
+		close(stop)
+	}
+}
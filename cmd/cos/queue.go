@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	cos "github.com/calamity-of-subterfuge/cos/pkg"
+)
+
+func runQueue(args []string, stdout io.Writer) error {
+	fs := newFlagSet("queue")
+	cos.RegisterConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	cfg, auth, err := loginWithConfig(fs)
+	if err != nil {
+		return err
+	}
+
+	rawLobbyConn, welcomeMsg, err := cos.QueueAI(cfg.AIConfig, auth)
+	if err != nil {
+		return err
+	}
+	lobbyConn := keepAlive(rawLobbyConn)
+	defer lobbyConn.Close()
+
+	fmt.Fprintf(stdout, "Queued %s %s; press Ctrl+C to stop. Welcome message:\n", cfg.AIConfig.AIName, cfg.AIConfig.Version)
+	printJSON(stdout, welcomeMsg)
+
+	stop, stopListening := interrupted()
+	defer stopListening()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			lobbyConn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		_, message, err := lobbyConn.ReadMessage()
+		if err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+				return fmt.Errorf("reading from lobby: %w", err)
+			}
+		}
+
+		var decoded interface{}
+		if json.Unmarshal(message, &decoded) != nil {
+			fmt.Fprintf(stdout, "%s\n", string(message))
+			continue
+		}
+		printJSON(stdout, decoded)
+	}
+}
+
+// printJSON writes the given value as indented JSON
+func printJSON(w io.Writer, v interface{}) {
+	formatted, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "%+v\n", v)
+		return
+	}
+	fmt.Fprintf(w, "%s\n", formatted)
+}
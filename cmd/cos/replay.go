@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+)
+
+func runReplay(args []string, stdout io.Writer) error {
+	fs := newFlagSet("replay")
+	verbose := fs.Bool("v", false, "print every packet, not just notable events")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	in, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	records, err := readCapture(in)
+	if err != nil {
+		return err
+	}
+	return replay(stdout, records, *verbose)
+}
+
+// replayer feeds captured frames through a client.State and reports on it
+type replayer struct {
+	out     io.Writer
+	verbose bool
+	state   *client.State
+	synced  bool
+
+	// at is the capture time of the frame being replayed
+	at float64
+
+	packetsByType map[string]int
+	skipped       int
+	failures      int
+}
+
+// replay the given records, printing what happened to out
+func replay(out io.Writer, records []captureRecord, verbose bool) error {
+	r := &replayer{
+		out:           out,
+		verbose:       verbose,
+		state:         client.NewState(),
+		packetsByType: make(map[string]int),
+	}
+
+	r.state.OnSelfLoaded(func(p *client.Player) {
+		r.event("self loaded: %s on team %d as %s", p.GameObject.UID, p.Team, p.Role.Name())
+	})
+	r.state.OnSelfLost(func(p *client.Player) {
+		r.event("self lost: %s", p.GameObject.UID)
+	})
+	r.state.OnControllableSmartObjectLoaded(func(so *client.SmartObject) {
+		r.event("controllable %s loaded: %s", so.UnitType, so.GameObject.UID)
+	})
+	r.state.OnControllableSmartObjectLost(func(so *client.SmartObject) {
+		r.event("controllable %s lost: %s", so.UnitType, so.GameObject.UID)
+	})
+
+	for idx, record := range records {
+		r.at = record.At
+		packets, err := srvpkts.ParsePacketJSON(record.Frame)
+		if err != nil {
+			r.failures++
+			r.event("frame %d could not be parsed: %v", idx, err)
+			continue
+		}
+
+		for _, packet := range packets {
+			r.handle(packet)
+		}
+	}
+
+	r.summarize(len(records))
+	return nil
+}
+
+// event prints a notable event at the current capture time
+func (r *replayer) event(format string, args ...interface{}) {
+	fmt.Fprintf(r.out, "[%8.3fs] %s\n", r.at, fmt.Sprintf(format, args...))
+}
+
+// handle a single packet, which the state may panic on if the capture is
+// inconsistent, e.g., it's missing packets
+func (r *replayer) handle(packet srvpkts.Packet) {
+	r.packetsByType[packet.GetType()]++
+
+	sync, isSync := packet.(*srvpkts.GameSyncPacket)
+	if !isSync && !r.synced {
+		// the state can't handle packets before the game sync
+		r.skipped++
+		return
+	}
+
+	if r.verbose {
+		r.event("%s", packet.GetType())
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.failures++
+			r.event("state failed to handle %s: %v", packet.GetType(), rec)
+		}
+	}()
+
+	switch v := packet.(type) {
+	case *srvpkts.ChatMessagePacket:
+		r.event("chat from %s: %s", v.AuthorUID, v.Text)
+	case *srvpkts.TeamResourceChangedPacket:
+		if !r.verbose {
+			break
+		}
+		r.event("resources changed: %v", v.Resources)
+	}
+
+	r.state.HandleMessage(packet)
+	if isSync {
+		r.synced = true
+		r.event(
+			"game sync at game time %.3f: %d players, %d smart objects, %d generic objects",
+			sync.GameTime, len(r.state.PlayersByUID), len(r.state.SmartObjectsByUID), len(r.state.GenericObjectsByUID),
+		)
+	}
+}
+
+// summarize the final state of the replay
+func (r *replayer) summarize(frames int) {
+	fmt.Fprintf(r.out, "\nReplayed %d frames\n", frames)
+	if r.skipped > 0 {
+		fmt.Fprintf(r.out, "Skipped %d packets before the first game sync\n", r.skipped)
+	}
+	if r.failures > 0 {
+		fmt.Fprintf(r.out, "Failed to handle %d frames or packets\n", r.failures)
+	}
+
+	fmt.Fprintln(r.out, "Packets by type:")
+	types := make([]string, 0, len(r.packetsByType))
+	for typ := range r.packetsByType {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		fmt.Fprintf(r.out, "  %-24s %d\n", typ, r.packetsByType[typ])
+	}
+
+	if !r.synced {
+		fmt.Fprintln(r.out, "No game sync was received")
+		return
+	}
+
+	s := r.state
+	fmt.Fprintf(r.out, "Final state at game time %.3f:\n", s.GameTime)
+	fmt.Fprintf(r.out, "  me: %s on team %d as %s\n", s.MyUID, s.MyTeam, s.MyRole.Name())
+	fmt.Fprintf(r.out, "  players: %d, generic objects: %d\n", len(s.PlayersByUID), len(s.GenericObjectsByUID))
+
+	unitTypes := make(map[string]int)
+	for _, so := range s.SmartObjectsByUID {
+		unitTypes[so.UnitType]++
+	}
+	fmt.Fprintf(r.out, "  smart objects: %d %s\n", len(s.SmartObjectsByUID), formatCounts(unitTypes))
+
+	resources := make(map[string]int)
+	for uid, resource := range s.ResourcesByUID {
+		resources[uid] = resource.Amount
+	}
+	fmt.Fprintf(r.out, "  resources: %s\n", formatCounts(resources))
+}
+
+// formatCounts formats the given counts sorted by key
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	res := "{"
+	for idx, key := range keys {
+		if idx > 0 {
+			res += ", "
+		}
+		res += fmt.Sprintf("%s: %d", key, counts[key])
+	}
+	return res + "}"
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testFrames = []string{
+	`{"type": "game-object-removed", "game_time": 1, "uid": "before-sync"}`,
+	`{"type": "game-sync", "game_time": 2, "player": {"uid": "me", "team": 1, "role": "economy"},
+		"team": {"resources": {"gold": 10}},
+		"resources": {"gold": {"uid": "gold", "name": "gold"}},
+		"players": {"me": {"uid": "me", "team": 1, "role": "economy"}},
+		"smart_objects": {"v1": {"uid": "v1", "unit_type": "villager", "controlling_team": 1, "controlling_role": "economy"}}}`,
+	`[{"type": "team-resource-changed", "game_time": 3, "resources": {"gold": 25}},
+		{"type": "chat-message", "game_time": 3, "author_uid": "me", "text": "hello"}]`,
+	`{"type": "game-object-removed", "game_time": 4, "uid": "v1"}`,
+	`{"type": "not-a-real-packet"}`,
+}
+
+func writeTestCapture(t *testing.T) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := newCaptureWriter(&buf)
+	for idx, frame := range testFrames {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, []byte(frame)); err != nil {
+			t.Fatal(err)
+		}
+		err := writer.Write(&captureRecord{At: float64(idx) / 2, Frame: compact.Bytes()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplay(t *testing.T) {
+	var out bytes.Buffer
+	err := runReplay([]string{writeTestCapture(t)}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"self loaded: me on team 1 as economy",
+		"controllable villager loaded: v1",
+		"chat from me: hello",
+		"controllable villager lost: v1",
+		"frame 4 could not be parsed",
+		"Replayed 5 frames",
+		"Skipped 1 packets before the first game sync",
+		"resources: {gold: 25}",
+		"smart objects: 0 {}",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected output to contain %q\n%s", line, out.String())
+		}
+	}
+}
+
+func TestDecode_captureFrame(t *testing.T) {
+	var out bytes.Buffer
+	err := runDecode([]string{"-frame", "2", writeTestCapture(t)}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"# frame 2 at 1.000s", "*srvpkts.TeamResourceChangedPacket", "*srvpkts.ChatMessagePacket", `"text": "hello"`} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected output to contain %q\n%s", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "# frame 1") {
+		t.Errorf("expected only frame 2 to be decoded\n%s", out.String())
+	}
+}
+
+func TestDecode_invalidFrame(t *testing.T) {
+	var out bytes.Buffer
+	err := runDecode([]string{"-frame", "-2", writeTestCapture(t)}, &out)
+	if err == nil || !strings.Contains(err.Error(), "invalid frame -2") {
+		t.Errorf("expected an invalid frame error, got %v", err)
+	}
+}
+
+func TestDecode_clientFrame(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frame.json")
+	frame := `[{"type": "move", "uid": "a", "dir": {"x": 1, "y": 0}}, {"type": "bogus"}]`
+	if err := ioutil.WriteFile(path, []byte(frame), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err := runDecode([]string{"-client", path}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "*clipkts.MovePacket") || !strings.Contains(out.String(), "error:") {
+		t.Errorf("expected the move packet and an error for the bogus packet\n%s", out.String())
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	cos "github.com/calamity-of-subterfuge/cos/pkg"
+)
+
+func runValidateConfig(args []string, stdout io.Writer) error {
+	fs := newFlagSet("validate-config")
+	cos.RegisterConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	cfg, err := cos.LoadConfig(&cos.ConfigSources{Flags: fs})
+	var cfgErr *cos.ConfigError
+	if errors.As(err, &cfgErr) {
+		fmt.Fprintln(stdout, "The config is invalid:")
+		for _, problem := range cfgErr.Problems {
+			fmt.Fprintf(stdout, "  - %s\n", problem)
+		}
+		return errors.New("invalid config")
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "The config is valid:")
+	fmt.Fprintf(stdout, "%v\n", cfg)
+	return nil
+}
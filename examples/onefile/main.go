@@ -1,8 +1,10 @@
 package onefile
 
 import (
+	"flag"
 	"log"
 	"math/rand"
+	"os"
 	"time"
 
 	cos "github.com/calamity-of-subterfuge/cos/pkg"
@@ -15,8 +17,8 @@ import (
 // Game typically goes in a different file and is your implementation of
 // cos.Game
 type Game struct {
-	// sendQueue is how you send messages to the server
-	sendQueue chan interface{}
+	// conn is how you send messages to the server
+	conn *cos.Conn
 
 	// state is the state of the world for you as a client. you will
 	// need either this or your own version of it.
@@ -33,10 +35,10 @@ type Game struct {
 }
 
 // NewGame initializes a new Game which can send packets using the
-// given sendQueue
-func NewGame(sendQueue chan interface{}) cos.Game {
+// given conn
+func NewGame(conn *cos.Conn) cos.Game {
 	return &Game{
-		sendQueue:          sendQueue,
+		conn:               conn,
 		state:              client.NewState(),
 		chat:               client.NewChat(100),
 		timeUntilNextHello: time.Second * 5,
@@ -60,13 +62,19 @@ func (g *Game) Tick(delta time.Duration) {
 		return
 	}
 
+	// keeps g.state.EstimatedServerGameTime() accurate
+	g.state.Clock.SetRTT(g.conn.RTT().Smoothed)
+
 	g.timeUntilNextHello -= delta
 
 	if g.timeUntilNextHello <= 0 {
 		g.timeUntilNextHello = time.Second * 5
 		// notice how Type does not need to be filled in
-		g.sendQueue <- clipkts.SendLocalMessagePacket{
+		err := g.conn.Send(&clipkts.SendLocalMessagePacket{
 			Text: "hello world!",
+		})
+		if err != nil {
+			log.Printf("failed to send hello: %v", err)
 		}
 	}
 }
@@ -78,39 +86,33 @@ func RunExample() {
 	// to seed rand!
 	rand.Seed(time.Now().UnixNano())
 
-	// you should load these from somewhere! typically a configuration file,
-	// via environment variables, or via flags (https://golang.org/pkg/flag/)
-	var email string = "example@example.com"
-	var grantIden string = "pa_xyz"
-	var secret = "mysecret"
-
-	// these are fine to keep in the code but depend on your infrastructure
-	// so it can be convenient to load from somewhere.
-	var maxConcurrentInstances int = 2
-	var clientAllowList []string = make([]string, 0)
-
-	// these should be checked in with your repo
-	var aiName string = "ExampleAI"
-	var aiUID string = "example-ai"
-	var version string = "0.0.1"
-	var role utils.Role = utils.RoleEconomyAI
-
-	aiCfg := &cos.AIConfig{
-		AIName:                 aiName,
-		AIUID:                  aiUID,
-		Version:                version,
-		Role:                   role,
-		ClientAllowList:        clientAllowList,
-		MaxConcurrentInstances: maxConcurrentInstances,
+	// the credentials must be loaded from somewhere which isn't checked in:
+	// a config file (-config ai.yaml), environment variables (COS_EMAIL,
+	// COS_GRANT_IDEN, COS_SECRET or COS_SECRET_FILE), or flags (-email etc).
+	// Use -help to see every setting.
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	cos.RegisterConfigFlags(flags)
+	flags.Parse(os.Args[1:])
+
+	cfg, err := cos.LoadConfig(&cos.ConfigSources{
+		Flags: flags,
+
+		// these should be checked in with your repo, though they can still
+		// be overridden like any other setting
+		Defaults: &cos.ConfigFile{
+			AIName:                 "ExampleAI",
+			AIUID:                  "example-ai",
+			Version:                "0.0.1",
+			Role:                   utils.RoleEconomyAI.Name(),
+			MaxConcurrentInstances: 2,
+		},
+	})
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
 	}
 
-	cos.Play(
-		&cos.Config{
-			Email:     email,
-			GrantIden: grantIden,
-			Secret:    secret,
-			AIConfig:  aiCfg,
-		},
-		NewGame,
-	)
+	// the secret is redacted when formatting configs
+	log.Printf("Loaded config %v", cfg)
+
+	cos.PlayWithConn(cfg, NewGame)
 }
@@ -1,10 +1,18 @@
 module github.com/calamity-of-subterfuge/cos
 
-go 1.15
+go 1.18
 
 require (
+	github.com/BurntSushi/toml v0.4.1
+	github.com/fxamacker/cbor/v2 v2.4.0
 	github.com/gorilla/websocket v1.4.2
 	github.com/jakecoffman/cp v1.1.0
 	github.com/mitchellh/mapstructure v1.4.1
 	golang.org/x/net v0.0.0-20210525063256-abc453219eb5
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/text v0.3.6 // indirect
 )
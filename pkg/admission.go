@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// MatchOffer describes a match which the lobby socket server has made
+// available, before we have connected to it.
+type MatchOffer struct {
+	// URL of the game server websocket
+	URL string
+
+	// JWT which authenticates us with the game server
+	JWT string
+
+	// Message is the complete match-available notification
+	Message map[string]interface{}
+}
+
+// AdmissionHook decides whether a match should be played before the hub
+// connects to it. Returning a non-nil error rejects the match, with the
+// error as the reason. Hooks are called from the goroutine managing the hub
+// and should return quickly; e.g., a hook may reject matches while the CPU
+// is saturated.
+type AdmissionHook func(offer *MatchOffer) error
+
+// ErrTooManyGames is the reason a match is rejected when the hub is already
+// playing the maximum number of concurrent games.
+var ErrTooManyGames = errors.New("already playing the maximum number of concurrent games")
+
+// ErrNoGameSlots is the reason a match is rejected when no slot could be
+// acquired from the GameSlots shared with other hubs.
+var ErrNoGameSlots = errors.New("no game slots available")
+
+// DefaultMaxPendingMatches is how many matches a hub holds onto while it has
+// no room for them, unless changed with SetMaxPendingMatches
+const DefaultMaxPendingMatches = 8
+
+// pendingMatchTTL is how long a pending match is held if its JWT doesn't say
+// when it expires
+const pendingMatchTTL = 30 * time.Second
+
+// pendingMatch is a match which was refused for lack of room, and which is
+// retried when room frees up until it expires
+type pendingMatch struct {
+	offer     *MatchOffer
+	expiresAt time.Time
+}
+
+// isCapacityError returns true if the given admission error means that the
+// match was refused for lack of room, so it may be admitted later
+func isCapacityError(err error) bool {
+	return errors.Is(err, ErrTooManyGames) || errors.Is(err, ErrNoGameSlots)
+}
+
+// jwtExpiry returns when the given JWT expires according to its exp claim,
+// without verifying it. The second result is false if the JWT doesn't have
+// an exp claim which can be read.
+func jwtExpiry(jwt string) (time.Time, bool) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp *float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+
+	seconds := int64(*claims.Exp)
+	return time.Unix(seconds, 0), true
+}
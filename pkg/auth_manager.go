@@ -0,0 +1,337 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrAuthClosed is returned from AuthManager.Token after the manager has been
+// closed or logged out.
+var ErrAuthClosed = errors.New("auth manager closed")
+
+// AuthManagerOptions configures an AuthManager. The zero value for any field
+// uses the default.
+type AuthManagerOptions struct {
+	// RefreshBefore is how long before the token expires that it is
+	// refreshed. Defaults to 10 minutes. Tokens are never refreshed before
+	// half their lifetime has passed, so short lived tokens are not
+	// refreshed continuously.
+	RefreshBefore time.Duration
+
+	// RetryInterval is how long to wait after the first failed refresh,
+	// doubling after each consecutive failure up to 16 times this value.
+	// Defaults to 1 minute.
+	RetryInterval time.Duration
+
+	// Login is used to get new tokens. Defaults to Login.
+	Login func(email, grantIden, secret string) (*AuthToken, error)
+
+	// Logout, if not nil, is used to invalidate the current token when
+	// logging out, e.g., Logout. If nil, tokens are left to expire. Tokens
+	// which are replaced by a refresh are always left to expire, since they
+	// may still be in use.
+	Logout func(auth *AuthToken) error
+}
+
+// AuthManager keeps a current AuthToken for an account, logging in again
+// ahead of the token expiring. It is safe to use from multiple goroutines,
+// so a single AuthManager may be shared by everything using the account.
+type AuthManager struct {
+	email     string
+	grantIden string
+	secret    string
+	opts      AuthManagerOptions
+
+	// loginMutex is held while logging in so concurrent callers of Token
+	// result in a single login
+	loginMutex sync.Mutex
+
+	mutex       sync.Mutex
+	token       *AuthToken
+	obtainedAt  time.Time
+	started     bool
+	closed      bool
+	closeChan   chan struct{}
+	changedChan chan struct{}
+	subscribers []chan *AuthToken
+}
+
+// NewAuthManager initializes an AuthManager for the account with the given
+// email, using the given grant and secret as in Login. This does not login;
+// that happens on the first call to Token, or immediately after Start.
+func NewAuthManager(email, grantIden, secret string) *AuthManager {
+	return NewAuthManagerWithOptions(email, grantIden, secret, nil)
+}
+
+// NewAuthManagerWithOptions is equivalent to NewAuthManager with the given
+// options. If opts is nil the defaults are used.
+func NewAuthManagerWithOptions(email, grantIden, secret string, opts *AuthManagerOptions) *AuthManager {
+	var resolved AuthManagerOptions
+	if opts != nil {
+		resolved = *opts
+	}
+	if resolved.RefreshBefore <= 0 {
+		resolved.RefreshBefore = 10 * time.Minute
+	}
+	if resolved.RetryInterval <= 0 {
+		resolved.RetryInterval = time.Minute
+	}
+	if resolved.Login == nil {
+		resolved.Login = Login
+	}
+
+	return &AuthManager{
+		email:       email,
+		grantIden:   grantIden,
+		secret:      secret,
+		opts:        resolved,
+		closeChan:   make(chan struct{}),
+		changedChan: make(chan struct{}, 1),
+	}
+}
+
+// String describes the manager without its secret or token
+func (m *AuthManager) String() string {
+	return fmt.Sprintf("AuthManager{Email:%s GrantIden:%s}", m.email, m.grantIden)
+}
+
+// GoString is equivalent to String, so the secret is also hidden with %#v
+func (m *AuthManager) GoString() string {
+	return m.String()
+}
+
+// Start refreshing the token in the background ahead of its expiry, until
+// Close or Logout is called. Calling Start more than once has no effect.
+func (m *AuthManager) Start() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.started || m.closed {
+		return
+	}
+	m.started = true
+	go m.manageRefresh()
+}
+
+// Token returns the current token, logging in if there is no token or it
+// has expired. Returns ErrAuthClosed if the manager was closed.
+func (m *AuthManager) Token() (*AuthToken, error) {
+	token, err := m.currentToken()
+	if token != nil || err != nil {
+		return token, err
+	}
+
+	m.loginMutex.Lock()
+	defer m.loginMutex.Unlock()
+
+	// another goroutine may have logged in while we were waiting
+	token, err = m.currentToken()
+	if token != nil || err != nil {
+		return token, err
+	}
+	return m.login()
+}
+
+// Refresh logs in again regardless of whether the current token has
+// expired, returning the new token.
+func (m *AuthManager) Refresh() (*AuthToken, error) {
+	m.loginMutex.Lock()
+	defer m.loginMutex.Unlock()
+
+	return m.login()
+}
+
+// Subscribe returns a channel which receives each new token after it
+// replaces the previous one. If the subscriber falls behind only the most
+// recent token is kept. The channel is never closed; use Unsubscribe to stop
+// receiving tokens.
+func (m *AuthManager) Subscribe() chan *AuthToken {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	res := make(chan *AuthToken, 1)
+	m.subscribers = append(m.subscribers, res)
+	return res
+}
+
+// Unsubscribe stops sending tokens to a channel returned from Subscribe
+func (m *AuthManager) Unsubscribe(subscription chan *AuthToken) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for idx, sub := range m.subscribers {
+		if sub == subscription {
+			m.subscribers = append(m.subscribers[:idx], m.subscribers[idx+1:]...)
+			return
+		}
+	}
+}
+
+// Close stops refreshing the token without logging out. Afterward Token
+// returns ErrAuthClosed. Calling Close more than once has no effect.
+func (m *AuthManager) Close() {
+	m.close()
+}
+
+// Logout closes the manager and invalidates the current token, if there is
+// one and the Logout option is set. Otherwise this is the same as Close.
+func (m *AuthManager) Logout() error {
+	token := m.close()
+	if token == nil || m.opts.Logout == nil {
+		return nil
+	}
+
+	err := m.opts.Logout(token)
+	if err != nil {
+		return fmt.Errorf("logging out: %w", err)
+	}
+	return nil
+}
+
+// close the manager, returning the token which was current, or nil if
+// there wasn't one or the manager was already closed
+func (m *AuthManager) close() *AuthToken {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	close(m.closeChan)
+
+	token := m.token
+	m.token = nil
+	return token
+}
+
+// currentToken returns the current token if it has not expired, nil if
+// there is no such token, or ErrAuthClosed if the manager is closed
+func (m *AuthManager) currentToken() (*AuthToken, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return nil, ErrAuthClosed
+	}
+	if m.token != nil && time.Now().Before(m.token.ExpiresAt) {
+		return m.token, nil
+	}
+	return nil, nil
+}
+
+// login to get a new token and notify subscribers. The loginMutex must be
+// held.
+func (m *AuthManager) login() (*AuthToken, error) {
+	token, err := m.opts.Login(m.email, m.grantIden, m.secret)
+	if err != nil {
+		return nil, fmt.Errorf("logging in: %w", err)
+	}
+
+	m.mutex.Lock()
+	if m.closed {
+		m.mutex.Unlock()
+
+		// we were closed while logging in, so nothing else will ever see
+		// this token to log it out. Failing to log out changes nothing for
+		// the caller, who can't use the token either way.
+		if m.opts.Logout != nil {
+			m.opts.Logout(token)
+		}
+		return nil, ErrAuthClosed
+	}
+	defer m.mutex.Unlock()
+
+	m.token = token
+	m.obtainedAt = time.Now()
+
+	select {
+	case m.changedChan <- struct{}{}:
+	default:
+	}
+
+	for _, sub := range m.subscribers {
+		// replace any token the subscriber hasn't received yet
+		select {
+		case <-sub:
+		default:
+		}
+		sub <- token
+	}
+	return token, nil
+}
+
+// refreshAt returns when the current token should be refreshed, which is
+// the zero time if there isn't one
+func (m *AuthManager) refreshAt() time.Time {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.token == nil {
+		return time.Time{}
+	}
+
+	refreshBefore := m.opts.RefreshBefore
+	if halfLife := m.token.ExpiresAt.Sub(m.obtainedAt) / 2; halfLife < refreshBefore {
+		refreshBefore = halfLife
+	}
+	return m.token.ExpiresAt.Add(-refreshBefore)
+}
+
+// refreshIfDue logs in again if the current token should be refreshed,
+// which may no longer be the case if another goroutine logged in while we
+// were waiting for the loginMutex
+func (m *AuthManager) refreshIfDue() error {
+	m.loginMutex.Lock()
+	defer m.loginMutex.Unlock()
+
+	if time.Now().Before(m.refreshAt()) {
+		return nil
+	}
+	_, err := m.login()
+	return err
+}
+
+// manageRefresh refreshes the token ahead of its expiry until closed
+func (m *AuthManager) manageRefresh() {
+	retryCounter := 0
+	for {
+		timer := time.NewTimer(time.Until(m.refreshAt()))
+		select {
+		case <-timer.C:
+		case <-m.changedChan:
+			// the token was refreshed elsewhere; recompute when to refresh
+			timer.Stop()
+			continue
+		case <-m.closeChan:
+			timer.Stop()
+			return
+		}
+
+		err := m.refreshIfDue()
+		if err == nil {
+			retryCounter = 0
+			continue
+		}
+		if errors.Is(err, ErrAuthClosed) {
+			return
+		}
+
+		retryIn := m.opts.RetryInterval << uint(retryCounter)
+		log.Printf("Error refreshing auth token, retrying in %v: %v", retryIn, err)
+		if retryCounter < 4 {
+			retryCounter++
+		}
+
+		timer = time.NewTimer(retryIn)
+		select {
+		case <-timer.C:
+		case <-m.closeChan:
+			timer.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package pkg_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cos "github.com/calamity-of-subterfuge/cos/pkg"
+)
+
+// fakeLogin returns a login function which issues sequentially numbered
+// tokens that expire after the given lifetime
+func fakeLogin(lifetime time.Duration, logins *int64) func(email, grantIden, secret string) (*cos.AuthToken, error) {
+	return func(email, grantIden, secret string) (*cos.AuthToken, error) {
+		if email != "a@example.com" || grantIden != "grant" || secret != "secret" {
+			return nil, fmt.Errorf("unexpected credentials %s %s %s", email, grantIden, secret)
+		}
+		num := atomic.AddInt64(logins, 1)
+		return &cos.AuthToken{
+			Token:     fmt.Sprintf("token-%d", num),
+			ExpiresAt: time.Now().Add(lifetime),
+		}, nil
+	}
+}
+
+func TestAuthManager_Token_concurrent(t *testing.T) {
+	var logins int64
+	manager := cos.NewAuthManagerWithOptions("a@example.com", "grant", "secret", &cos.AuthManagerOptions{
+		Login: fakeLogin(time.Hour, &logins),
+	})
+	defer manager.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := manager.Token()
+			if err != nil || token.Token != "token-1" {
+				t.Errorf("expected token-1, got %v (%v)", token, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if logins != 1 {
+		t.Errorf("expected a single login, got %d", logins)
+	}
+}
+
+func TestAuthManager_refreshesBeforeExpiry(t *testing.T) {
+	var logins int64
+	manager := cos.NewAuthManagerWithOptions("a@example.com", "grant", "secret", &cos.AuthManagerOptions{
+		RefreshBefore: time.Hour,
+		Login:         fakeLogin(200*time.Millisecond, &logins),
+	})
+	defer manager.Close()
+
+	refreshed := manager.Subscribe()
+	manager.Start()
+
+	var last *cos.AuthToken
+	for i := 1; i <= 3; i++ {
+		select {
+		case last = <-refreshed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for token %d", i)
+		}
+
+		if expected := fmt.Sprintf("token-%d", i); last.Token != expected {
+			t.Fatalf("expected %s, got %s", expected, last.Token)
+		}
+		if remaining := time.Until(last.ExpiresAt); remaining < 50*time.Millisecond {
+			t.Errorf("token %d received too close to expiry (%v remaining)", i, remaining)
+		}
+	}
+
+	token, err := manager.Token()
+	if err != nil || token.ExpiresAt.Before(last.ExpiresAt) {
+		t.Errorf("expected a current token, got %v (%v)", token, err)
+	}
+}
+
+func TestAuthManager_Logout(t *testing.T) {
+	var logins int64
+	var loggedOut []string
+	manager := cos.NewAuthManagerWithOptions("a@example.com", "grant", "secret", &cos.AuthManagerOptions{
+		Login: fakeLogin(time.Hour, &logins),
+		Logout: func(auth *cos.AuthToken) error {
+			loggedOut = append(loggedOut, auth.Token)
+			return nil
+		},
+	})
+
+	if _, err := manager.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	if len(loggedOut) != 1 || loggedOut[0] != "token-2" {
+		t.Errorf("expected only the current token to be logged out, got %v", loggedOut)
+	}
+
+	if _, err := manager.Token(); !errors.Is(err, cos.ErrAuthClosed) {
+		t.Errorf("expected ErrAuthClosed after logout, got %v", err)
+	}
+}
+
+func TestAuthManager_Logout_duringLogin(t *testing.T) {
+	var logins int64
+	loggingIn := make(chan struct{})
+	finishLogin := make(chan struct{})
+	var loggedOut []string
+	manager := cos.NewAuthManagerWithOptions("a@example.com", "grant", "secret", &cos.AuthManagerOptions{
+		Login: func(email, grantIden, secret string) (*cos.AuthToken, error) {
+			close(loggingIn)
+			<-finishLogin
+			return fakeLogin(time.Hour, &logins)(email, grantIden, secret)
+		},
+		Logout: func(auth *cos.AuthToken) error {
+			loggedOut = append(loggedOut, auth.Token)
+			return nil
+		},
+	})
+
+	errChan := make(chan error)
+	go func() {
+		_, err := manager.Token()
+		errChan <- err
+	}()
+
+	<-loggingIn
+	if err := manager.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	close(finishLogin)
+	if err := <-errChan; !errors.Is(err, cos.ErrAuthClosed) {
+		t.Fatalf("expected ErrAuthClosed, got %v", err)
+	}
+	if len(loggedOut) != 1 || loggedOut[0] != "token-1" {
+		t.Errorf("expected the token from the interrupted login to be logged out, got %v", loggedOut)
+	}
+}
+
+func TestAuthManager_Logout_optIn(t *testing.T) {
+	var logins int64
+	manager := cos.NewAuthManagerWithOptions("a@example.com", "grant", "secret", &cos.AuthManagerOptions{
+		Login: fakeLogin(time.Hour, &logins),
+	})
+	if _, err := manager.Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	// without the Logout option the token is left to expire, so logging out
+	// only closes the manager
+	if err := manager.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Token(); !errors.Is(err, cos.ErrAuthClosed) {
+		t.Errorf("expected ErrAuthClosed after logout, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"math"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+)
+
+// ClockEstimator estimates the relationship between the server game time and
+// the local clock using the GameTime on every packet received from the
+// server. This allows predicting what the game time is right now, rather than
+// what it was when the latest packet was sent, which is important for
+// prediction and for cooldowns which are measured in game time, such as
+// MINE_COOLDOWN.
+//
+// Packets can only be delayed, never early, so each packet provides a lower
+// bound for the current game time. The estimator moves quickly towards
+// samples which suggest less delay and slowly towards samples which suggest
+// more delay, which rejects jitter while still tracking real changes.
+//
+// A ClockEstimator is not safe for concurrent use.
+type ClockEstimator struct {
+	// FastSmoothing is the weight of a sample which suggests the game time is
+	// later than the current estimate. Defaults to 0.5
+	FastSmoothing float64
+
+	// SlowSmoothing is the weight of a sample which suggests the game time is
+	// earlier than the current estimate. Defaults to 0.02
+	SlowSmoothing float64
+
+	// ResetThreshold is the difference in seconds between a sample and the
+	// current estimate beyond which the estimate is discarded and replaced
+	// with the sample, e.g., because the game was restarted. Defaults to 5
+	ResetThreshold float64
+
+	// epoch is the local time that offsets are relative to
+	epoch time.Time
+
+	// offset is the estimated game time at epoch, not counting the one-way
+	// delay, i.e., gameTime = offset + seconds since epoch
+	offset float64
+
+	// oneWayDelay is half the round trip time in seconds
+	oneWayDelay float64
+
+	samples int
+}
+
+// NewClockEstimator initializes a clock estimator with no samples.
+func NewClockEstimator() *ClockEstimator {
+	return &ClockEstimator{
+		FastSmoothing:  0.5,
+		SlowSmoothing:  0.02,
+		ResetThreshold: 5,
+		epoch:          time.Now(),
+	}
+}
+
+// HandleMessage should be called whenever a new server packet is received in
+// order to update the estimate. Packets which don't implement
+// srvpkts.TimedPacket are ignored.
+func (e *ClockEstimator) HandleMessage(packet srvpkts.Packet) {
+	if timed, ok := packet.(srvpkts.TimedPacket); ok {
+		e.Observe(timed.GetGameTime(), time.Now())
+	}
+}
+
+// Observe updates the estimate using a packet which was sent at the given
+// game time and received at the given local time.
+func (e *ClockEstimator) Observe(gameTime float64, receivedAt time.Time) {
+	sample := gameTime - e.localSeconds(receivedAt)
+
+	if e.samples == 0 || math.Abs(sample-e.offset) > e.ResetThreshold {
+		e.offset = sample
+		e.samples = 1
+		return
+	}
+
+	if sample > e.offset {
+		e.offset += (sample - e.offset) * e.FastSmoothing
+	} else {
+		e.offset += (sample - e.offset) * e.SlowSmoothing
+	}
+	e.samples++
+}
+
+// SetRTT updates the round trip time to the server, typically from the RTT
+// of the game connection. Half of this is added to the estimated game time
+// to account for the delay between the server sending a packet and it being
+// received.
+func (e *ClockEstimator) SetRTT(rtt time.Duration) {
+	e.oneWayDelay = rtt.Seconds() / 2
+}
+
+// Ready returns true if at least one packet has been observed. Until then
+// all game times are 0.
+func (e *ClockEstimator) Ready() bool {
+	return e.samples > 0
+}
+
+// GameTimeAt estimates the game time on the server at the given local time.
+func (e *ClockEstimator) GameTimeAt(local time.Time) float64 {
+	if e.samples == 0 {
+		return 0
+	}
+	return e.offset + e.oneWayDelay + e.localSeconds(local)
+}
+
+// LocalTimeAt estimates the local time at which the server game time will be
+// the given game time. This is convenient for scheduling actions which are
+// waiting on a cooldown. The result is meaningless until Ready.
+func (e *ClockEstimator) LocalTimeAt(gameTime float64) time.Time {
+	seconds := gameTime - e.offset - e.oneWayDelay
+	return e.epoch.Add(time.Duration(seconds * float64(time.Second)))
+}
+
+// EstimatedServerGameTime estimates the game time on the server right now.
+func (e *ClockEstimator) EstimatedServerGameTime() float64 {
+	return e.GameTimeAt(time.Now())
+}
+
+func (e *ClockEstimator) localSeconds(local time.Time) float64 {
+	return local.Sub(e.epoch).Seconds()
+}
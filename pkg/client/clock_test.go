@@ -0,0 +1,69 @@
+package client_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+)
+
+// simulateClock feeds the estimator packets sent every 16ms whose delivery
+// is delayed by minDelay plus exponentially distributed jitter, returning
+// the local time the last packet was sent.
+func simulateClock(clock *client.ClockEstimator, start time.Time, startGameTime float64, minDelay, meanJitter time.Duration, numPackets int) time.Time {
+	sentAt := start
+	for i := 0; i < numPackets; i++ {
+		sentAt = start.Add(time.Duration(i) * 16 * time.Millisecond)
+		jitter := time.Duration(rand.ExpFloat64() * float64(meanJitter))
+		gameTime := startGameTime + sentAt.Sub(start).Seconds()
+		clock.Observe(gameTime, sentAt.Add(minDelay+jitter))
+	}
+	return sentAt
+}
+
+func TestClockEstimator_rand(t *testing.T) {
+	var seed int64
+	for seed = 0; seed < 100; seed++ {
+		rand.Seed(seed)
+
+		clock := client.NewClockEstimator()
+		start := time.Now()
+		startGameTime := rand.Float64() * 1000
+		minDelay := time.Duration(rand.Intn(100)) * time.Millisecond
+		clock.SetRTT(2 * minDelay)
+
+		last := simulateClock(clock, start, startGameTime, minDelay, 20*time.Millisecond, 1000)
+
+		expected := startGameTime + last.Sub(start).Seconds()
+		got := clock.GameTimeAt(last)
+		if math.Abs(got-expected) > 0.01 {
+			t.Fatalf("seed %d: expected game time %v, got %v (error %v)", seed, expected, got, got-expected)
+		}
+
+		roundTrip := clock.LocalTimeAt(got)
+		if diff := roundTrip.Sub(last); diff > time.Microsecond || diff < -time.Microsecond {
+			t.Fatalf("seed %d: LocalTimeAt(GameTimeAt(t)) is %v from t", seed, diff)
+		}
+	}
+}
+
+func TestClockEstimator_reset(t *testing.T) {
+	rand.Seed(1)
+
+	clock := client.NewClockEstimator()
+	if clock.Ready() || clock.GameTimeAt(time.Now()) != 0 {
+		t.Fatalf("expected clock without samples to not be ready")
+	}
+
+	start := time.Now()
+	last := simulateClock(clock, start, 500, 0, time.Millisecond, 100)
+
+	// the game restarts at game time 0
+	restart := last.Add(time.Second)
+	clock.Observe(0, restart)
+	if got := clock.GameTimeAt(restart); math.Abs(got) > 1e-9 {
+		t.Errorf("expected estimate to reset to 0, got %v", got)
+	}
+}
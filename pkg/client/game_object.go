@@ -49,6 +49,13 @@ type GameObject struct {
 	// finished and false if it should not.
 	AnimationLooping bool
 
+	// AnimationStartedAt is the game time at which the current animation was
+	// first seen playing. The server doesn't say when animations start, so for
+	// objects which were already animating when they were synced this is the
+	// game time of the sync, and an animation which is restarted without
+	// changing or pausing is not noticed.
+	AnimationStartedAt float64
+
 	// Body is the physics body for this game object, with the appropriate
 	// shapes, position, velocity, angle, and angular velocity, but not
 	// attached to any space and hence not simulated.
@@ -88,6 +95,10 @@ func (o *GameObject) Sync(packet *srvpkts.GameObjectSync) *GameObject {
 // Update this game object with the given information, which only affects
 // the relatively frequently changing fields
 func (o *GameObject) Update(packet *srvpkts.GameObjectUpdatePacket) *GameObject {
+	if packet.Animation != o.Animation || (packet.AnimationPlaying && !o.AnimationPlaying) {
+		o.AnimationStartedAt = packet.GameTime
+	}
+
 	o.Body.SetPosition(cp.Vector{X: packet.Position.X, Y: packet.Position.Y})
 	o.Body.SetVelocity(packet.Velocity.X, packet.Velocity.Y)
 	o.Body.SetAngle(packet.Rotation)
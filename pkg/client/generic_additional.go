@@ -0,0 +1,73 @@
+package client
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+)
+
+// GenericSmartObjectAdditional is the additional information for smart
+// objects whose unit type has no registered parser. It keeps the raw
+// Additional map so unknown unit types can still be inspected.
+type GenericSmartObjectAdditional struct {
+	// Raw is the Additional map from the sync, with every update applied
+	Raw map[string]interface{}
+}
+
+// Update applies the packet's Additional map as a JSON merge patch (RFC 7386):
+// nested maps are merged, nil values remove keys and anything else replaces
+// the existing value.
+func (a *GenericSmartObjectAdditional) Update(packet *srvpkts.SmartObjectUpdatePacket) error {
+	if packet.Additional == nil {
+		return nil
+	}
+
+	patch, ok := packet.Additional.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected additional to be a map, got %T", packet.Additional)
+	}
+	if a.Raw == nil {
+		a.Raw = make(map[string]interface{}, len(patch))
+	}
+	mergePatch(a.Raw, patch)
+	return nil
+}
+
+// mergePatch applies the given JSON merge patch to target in place
+func mergePatch(target, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+
+		patchMap, isMap := value.(map[string]interface{})
+		if !isMap {
+			target[key] = value
+			continue
+		}
+
+		targetMap, isMap := target[key].(map[string]interface{})
+		if !isMap {
+			targetMap = make(map[string]interface{}, len(patchMap))
+			target[key] = targetMap
+		}
+		mergePatch(targetMap, patchMap)
+	}
+}
+
+// parseGenericSmartObjectAdditional is the parser used for unit types
+// without a registered parser. This never fails since unknown unit types
+// shouldn't stop the client; additional values which aren't maps are logged
+// and dropped.
+func parseGenericSmartObjectAdditional(sync *srvpkts.SmartObjectSync) (SmartObjectAdditional, error) {
+	raw, ok := sync.Additional.(map[string]interface{})
+	if !ok {
+		if sync.Additional != nil {
+			log.Printf("ignoring additional for %s %s which is not a map: %T", sync.UnitType, sync.UID, sync.Additional)
+		}
+		raw = make(map[string]interface{})
+	}
+	return &GenericSmartObjectAdditional{Raw: raw}, nil
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+	"github.com/calamity-of-subterfuge/cos/pkg/unitdets"
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+)
+
+// LaboratoryResearch describes a single research project in a laboratory
+type LaboratoryResearch struct {
+	// UID of this research project, which is distinct from the technology
+	// since the same technology may be queued in multiple laboratories
+	UID string
+
+	// Technology is the name of the technology being researched
+	Technology string
+
+	// Duration is the game time in seconds the research takes to complete
+	// once it reaches the front of the queue
+	Duration float64
+}
+
+// LaboratoryAdditional is the additional information for a laboratory
+type LaboratoryAdditional struct {
+	// Owner is the uid of the player which can queue research in this
+	// laboratory, or blank if no player owns it
+	Owner string
+
+	// Queue is the research queued in this laboratory, in order. The first
+	// research is the one in progress.
+	Queue []LaboratoryResearch
+
+	// Progress is how much of the first research in the queue is complete,
+	// from 0 to 1
+	Progress float64
+}
+
+// Current returns the research in progress, or nil if the queue is empty
+func (a *LaboratoryAdditional) Current() *LaboratoryResearch {
+	if len(a.Queue) == 0 {
+		return nil
+	}
+	return &a.Queue[0]
+}
+
+// RemainingTime returns the game time in seconds until the whole queue is
+// complete, assuming nothing else is queued
+func (a *LaboratoryAdditional) RemainingTime() float64 {
+	var res float64
+	for idx, research := range a.Queue {
+		if idx == 0 {
+			res += (1 - a.Progress) * research.Duration
+		} else {
+			res += research.Duration
+		}
+	}
+	return res
+}
+
+func (a *LaboratoryAdditional) Update(packet *srvpkts.SmartObjectUpdatePacket) error {
+	if packet.Additional == nil {
+		return nil
+	}
+
+	additional, ok := packet.Additional.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected additional to be a map, got %T", packet.Additional)
+	}
+
+	var updateDetails unitdets.LaboratoryUpdateDetails
+	_, err := utils.DecodeWithType(additional, &updateDetails)
+	if err != nil {
+		return err
+	}
+
+	if len(updateDetails.RemovedResearch) > 0 {
+		removed := make(map[string]bool, len(updateDetails.RemovedResearch))
+		for _, uid := range updateDetails.RemovedResearch {
+			removed[uid] = true
+		}
+
+		queue := a.Queue[:0]
+		for _, research := range a.Queue {
+			if !removed[research.UID] {
+				queue = append(queue, research)
+			}
+		}
+		a.Queue = queue
+	}
+	for _, research := range updateDetails.AddedResearch {
+		a.Queue = append(a.Queue, LaboratoryResearch(research))
+	}
+	a.Owner = updateDetails.Owner
+	a.Progress = updateDetails.Progress
+	return nil
+}
+
+func init() {
+	RegisterSmartObjectAdditional("laboratory", func(sync *srvpkts.SmartObjectSync) (SmartObjectAdditional, error) {
+		additional, ok := sync.Additional.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected additional to be a map, got %T", sync.Additional)
+		}
+
+		var syncDetails unitdets.LaboratorySyncDetails
+		_, err := utils.DecodeWithType(additional, &syncDetails)
+		if err != nil {
+			return nil, err
+		}
+
+		queue := make([]LaboratoryResearch, 0, len(syncDetails.Queue))
+		for _, research := range syncDetails.Queue {
+			queue = append(queue, LaboratoryResearch(research))
+		}
+
+		return &LaboratoryAdditional{
+			Owner:    syncDetails.Owner,
+			Queue:    queue,
+			Progress: syncDetails.Progress,
+		}, nil
+	})
+}
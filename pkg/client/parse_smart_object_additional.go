@@ -1,6 +1,9 @@
 package client
 
 import (
+	"sort"
+	"sync"
+
 	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
 )
 
@@ -10,7 +13,9 @@ type SmartObjectAdditional interface {
 	Update(update *srvpkts.SmartObjectUpdatePacket) error
 }
 
-// BlankSmartObjectAdditional is for objects with no additional information
+// BlankSmartObjectAdditional is for objects with no additional information.
+// Unit types without a registered parser get a GenericSmartObjectAdditional
+// instead, so this is only used by parsers which know there's nothing to keep.
 type BlankSmartObjectAdditional struct{}
 
 // Update is no-op
@@ -22,18 +27,42 @@ func (a *BlankSmartObjectAdditional) Update(update *srvpkts.SmartObjectUpdatePac
 // a SmartObjectSync
 type SmartObjectAdditionalParser func(*srvpkts.SmartObjectSync) (SmartObjectAdditional, error)
 
+var smartObjectsLock sync.RWMutex
 var smartObjectsByUnitType map[string]SmartObjectAdditionalParser = make(map[string]SmartObjectAdditionalParser)
 
-func registerSmartObjectUnitAdditional(unitType string, parser SmartObjectAdditionalParser) {
+// RegisterSmartObjectAdditional registers the parser to use for the
+// Additional information on smart objects with the given UnitType, replacing
+// the existing parser for that unit type if there is one. Smart objects whose
+// unit type has no parser get a GenericSmartObjectAdditional. This is
+// typically called from init().
+func RegisterSmartObjectAdditional(unitType string, parser SmartObjectAdditionalParser) {
+	smartObjectsLock.Lock()
+	defer smartObjectsLock.Unlock()
 	smartObjectsByUnitType[unitType] = parser
 }
 
+// KnownSmartObjectUnitTypes returns the unit types which have a registered
+// SmartObjectAdditionalParser, in sorted order.
+func KnownSmartObjectUnitTypes() []string {
+	smartObjectsLock.RLock()
+	defer smartObjectsLock.RUnlock()
+
+	res := make([]string, 0, len(smartObjectsByUnitType))
+	for unitType := range smartObjectsByUnitType {
+		res = append(res, unitType)
+	}
+	sort.Strings(res)
+	return res
+}
+
 // ParseSmartObjectAdditional parses a SmartObjectAdditional from the given
 // SmartObjectSync based on its UnitType
 func ParseSmartObjectAdditional(sync *srvpkts.SmartObjectSync) (SmartObjectAdditional, error) {
+	smartObjectsLock.RLock()
 	parser, found := smartObjectsByUnitType[sync.UnitType]
+	smartObjectsLock.RUnlock()
 	if !found {
-		return &BlankSmartObjectAdditional{}, nil
+		return parseGenericSmartObjectAdditional(sync)
 	}
 
 	return parser(sync)
@@ -0,0 +1,132 @@
+package client_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+)
+
+func smartObjectSync(unitType string, additional interface{}) *srvpkts.SmartObjectSync {
+	return &srvpkts.SmartObjectSync{
+		GameObjectSync: srvpkts.GameObjectSync{UID: "so"},
+		UnitType:       unitType,
+		Additional:     additional,
+	}
+}
+
+func smartObjectUpdate(additional interface{}) *srvpkts.SmartObjectUpdatePacket {
+	return &srvpkts.SmartObjectUpdatePacket{
+		GameObjectUpdatePacket: srvpkts.GameObjectUpdatePacket{UID: "so"},
+		Additional:             additional,
+	}
+}
+
+func TestLaboratoryAdditional(t *testing.T) {
+	so := (&client.SmartObject{}).Sync(smartObjectSync("laboratory", map[string]interface{}{
+		"owner": "player1",
+		"queue": []interface{}{
+			map[string]interface{}{"uid": "r1", "technology": "bronze", "duration": 10.0},
+			map[string]interface{}{"uid": "r2", "technology": "iron", "duration": 20.0},
+		},
+		"progress": 0.5,
+	}))
+
+	lab, ok := so.Additional.(*client.LaboratoryAdditional)
+	if !ok {
+		t.Fatalf("expected a laboratory additional, got %T", so.Additional)
+	}
+	if lab.Owner != "player1" || lab.Current().Technology != "bronze" || lab.RemainingTime() != 25 {
+		t.Fatalf("unexpected laboratory: %+v", lab)
+	}
+
+	so.Update(smartObjectUpdate(map[string]interface{}{
+		"owner":            "player1",
+		"progress":         0.25,
+		"removed_research": []interface{}{"r1"},
+		"added_research": []interface{}{
+			map[string]interface{}{"uid": "r3", "technology": "steel", "duration": 30.0},
+		},
+	}))
+
+	expected := []client.LaboratoryResearch{
+		{UID: "r2", Technology: "iron", Duration: 20},
+		{UID: "r3", Technology: "steel", Duration: 30},
+	}
+	if !reflect.DeepEqual(lab.Queue, expected) {
+		t.Errorf("expected queue %+v, got %+v", expected, lab.Queue)
+	}
+	if lab.Progress != 0.25 || lab.RemainingTime() != 45 {
+		t.Errorf("unexpected progress %v, remaining %v", lab.Progress, lab.RemainingTime())
+	}
+}
+
+func TestLaboratoryAdditional_notMap(t *testing.T) {
+	_, err := client.ParseSmartObjectAdditional(smartObjectSync("laboratory", "unexpected"))
+	if err == nil {
+		t.Errorf("expected an error parsing a value which is not a map")
+	}
+
+	lab := &client.LaboratoryAdditional{Owner: "player1", Progress: 0.5}
+	if err = lab.Update(smartObjectUpdate("unexpected")); err == nil {
+		t.Errorf("expected an error updating with a value which is not a map")
+	}
+	if err = lab.Update(smartObjectUpdate(nil)); err != nil || lab.Owner != "player1" || lab.Progress != 0.5 {
+		t.Errorf("expected updating without additional to change nothing, got %+v (%v)", lab, err)
+	}
+}
+
+func TestGenericSmartObjectAdditional(t *testing.T) {
+	so := (&client.SmartObject{}).Sync(smartObjectSync("unknown-unit", map[string]interface{}{
+		"level": 1.0,
+		"stats": map[string]interface{}{"armor": 2.0, "speed": 3.0},
+		"tags":  []interface{}{"a"},
+	}))
+
+	generic, ok := so.Additional.(*client.GenericSmartObjectAdditional)
+	if !ok {
+		t.Fatalf("expected a generic additional, got %T", so.Additional)
+	}
+
+	so.Update(smartObjectUpdate(map[string]interface{}{
+		"level": 2.0,
+		"stats": map[string]interface{}{"speed": nil, "range": 4.0},
+		"tags":  []interface{}{"b", "c"},
+		"new":   map[string]interface{}{"nested": true},
+	}))
+
+	expected := map[string]interface{}{
+		"level": 2.0,
+		"stats": map[string]interface{}{"armor": 2.0, "range": 4.0},
+		"tags":  []interface{}{"b", "c"},
+		"new":   map[string]interface{}{"nested": true},
+	}
+	if !reflect.DeepEqual(generic.Raw, expected) {
+		t.Errorf("expected %v, got %v", expected, generic.Raw)
+	}
+}
+
+func TestGenericSmartObjectAdditional_notMap(t *testing.T) {
+	so := (&client.SmartObject{}).Sync(smartObjectSync("unknown-unit", "unexpected"))
+	generic, ok := so.Additional.(*client.GenericSmartObjectAdditional)
+	if !ok || generic.Raw == nil || len(generic.Raw) != 0 {
+		t.Fatalf("expected an empty generic additional, got %#v", so.Additional)
+	}
+
+	err := generic.Update(smartObjectUpdate("still unexpected"))
+	if err == nil {
+		t.Errorf("expected an error updating with a value which is not a map")
+	}
+}
+
+func TestKnownSmartObjectUnitTypes(t *testing.T) {
+	known := client.KnownSmartObjectUnitTypes()
+	found := make(map[string]bool)
+	for _, unitType := range known {
+		found[unitType] = true
+	}
+	if !found["tent"] || !found["laboratory"] {
+		t.Errorf("expected tent and laboratory to be known, got %v", known)
+	}
+}
@@ -21,9 +21,16 @@ type State struct {
 	// MyRole is the role of the player for this client
 	MyRole utils.Role
 
-	// GameTime is the current game time
+	// GameTime is the latest game time from a packet, i.e., the game time
+	// at which the latest packet was sent. See EstimatedServerGameTime for
+	// an estimate of the game time right now.
 	GameTime float64
 
+	// Clock estimates the game time on the server from the packets received.
+	// For best results, regularly call Clock.SetRTT with the round trip time
+	// of the game connection.
+	Clock *ClockEstimator
+
 	// PlayersByUID contains all the currently visible players mapped from
 	// their uid.
 	PlayersByUID map[string]*Player
@@ -62,7 +69,17 @@ type State struct {
 // in order to fill into normal representation. Typically the state can
 // be considered invalid if the GameTime is 0.
 func NewState() *State {
-	return &State{}
+	return &State{Clock: NewClockEstimator()}
+}
+
+// EstimatedServerGameTime estimates the game time on the server right now,
+// accounting for the time since the latest packet was received. This falls
+// back to GameTime if there is no Clock.
+func (s *State) EstimatedServerGameTime() float64 {
+	if s.Clock == nil || !s.Clock.Ready() {
+		return s.GameTime
+	}
+	return s.Clock.EstimatedServerGameTime()
 }
 
 // OnSelfLoaded will register the given listener to be called whenever
@@ -114,10 +131,16 @@ func (s *State) OnControllableSmartObjectLost(listener func(*SmartObject)) {
 // the packet is relevant to the client state, this updates the client state
 // appropriately.
 func (s *State) HandleMessage(packet srvpkts.Packet) {
+	if s.Clock != nil {
+		s.Clock.HandleMessage(packet)
+	}
+
 	switch v := packet.(type) {
 	case *srvpkts.GameObjectAddedPacket:
 		s.updateGameTime(v.GameTime)
-		s.GenericObjectsByUID[v.Object.UID] = (&GameObject{}).Sync(&v.Object)
+		genObj := (&GameObject{}).Sync(&v.Object)
+		genObj.AnimationStartedAt = v.GameTime
+		s.GenericObjectsByUID[v.Object.UID] = genObj
 	case *srvpkts.GameObjectRemovedPacket:
 		s.updateGameTime(v.GameTime)
 		if ov, found := s.PlayersByUID[v.UID]; found {
@@ -155,6 +178,7 @@ func (s *State) HandleMessage(packet srvpkts.Packet) {
 	case *srvpkts.PlayerAddedPacket:
 		s.updateGameTime(v.GameTime)
 		newPlayer := (&Player{}).Sync(&v.Object)
+		newPlayer.GameObject.AnimationStartedAt = v.GameTime
 		s.PlayersByUID[v.Object.UID] = newPlayer
 		s.PlayerUIDsByTeamAndRole.Add(newPlayer.Team, newPlayer.Role, newPlayer.GameObject.UID)
 
@@ -166,6 +190,7 @@ func (s *State) HandleMessage(packet srvpkts.Packet) {
 	case *srvpkts.SmartObjectAddedPacket:
 		s.updateGameTime(v.GameTime)
 		newSO := (&SmartObject{}).Sync(&v.Object)
+		newSO.GameObject.AnimationStartedAt = v.GameTime
 		s.SmartObjectsByUID[v.Object.UID] = newSO
 		s.SmartObjectsByUnitType.Add(newSO)
 
@@ -213,19 +238,24 @@ func (s *State) handleGameSync(packet *srvpkts.GameSyncPacket) {
 	s.PlayersByUID = make(map[string]*Player, len(packet.Players))
 	s.PlayerUIDsByTeamAndRole = make(TeamRoleUIDLookup)
 	for _, plyr := range packet.Players {
-		s.PlayersByUID[plyr.UID] = (&Player{}).Sync(&plyr)
+		newPlayer := (&Player{}).Sync(&plyr)
+		newPlayer.GameObject.AnimationStartedAt = packet.GameTime
+		s.PlayersByUID[plyr.UID] = newPlayer
 		s.PlayerUIDsByTeamAndRole.Add(plyr.Team, utils.RoleFromName(plyr.Role), plyr.UID)
 	}
 
 	s.StaticObjects = make([]GameObject, 0, len(packet.DumbObjects))
 	for _, obj := range packet.DumbObjects {
-		s.StaticObjects = append(s.StaticObjects, *(&GameObject{}).Sync(&obj))
+		staticObj := (&GameObject{}).Sync(&obj)
+		staticObj.AnimationStartedAt = packet.GameTime
+		s.StaticObjects = append(s.StaticObjects, *staticObj)
 	}
 
 	s.SmartObjectsByUID = make(map[string]*SmartObject, len(packet.SmartObjects))
 	s.SmartObjectsByUnitType = make(UnitTypeLookup)
 	for _, obj := range packet.SmartObjects {
 		newSO := (&SmartObject{}).Sync(&obj)
+		newSO.GameObject.AnimationStartedAt = packet.GameTime
 		s.SmartObjectsByUID[obj.UID] = newSO
 		s.SmartObjectsByUnitType.Add(newSO)
 	}
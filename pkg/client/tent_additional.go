@@ -59,7 +59,7 @@ func (a *TentAdditional) Update(packet *srvpkts.SmartObjectUpdatePacket) error {
 }
 
 func init() {
-	registerSmartObjectUnitAdditional("tent", func(sync *srvpkts.SmartObjectSync) (SmartObjectAdditional, error) {
+	RegisterSmartObjectAdditional("tent", func(sync *srvpkts.SmartObjectSync) (SmartObjectAdditional, error) {
 		var syncDetails unitdets.TentSyncDetails
 		_, err := utils.DecodeWithType(sync.Additional.(map[string]interface{}), &syncDetails)
 		if err != nil {
@@ -20,8 +20,12 @@ func (p *CreateLaboratoryPacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *CreateLaboratoryPacket) validate() error {
+	return validateFinite("location", p.Location.X, p.Location.Y)
+}
+
 func init() {
-	registerPacketParser("create-laboratory", func(parsed map[string]interface{}) (Packet, error) {
+	RegisterPacketParser("create-laboratory", func(parsed map[string]interface{}) (Packet, error) {
 		return parseSinglePacketOfType(parsed, &CreateLaboratoryPacket{})
 	})
 }
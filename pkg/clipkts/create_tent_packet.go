@@ -20,8 +20,12 @@ func (p *CreateTentPacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *CreateTentPacket) validate() error {
+	return validateFinite("location", p.Location.X, p.Location.Y)
+}
+
 func init() {
-	registerPacketParser("create-tent", func(parsed map[string]interface{}) (Packet, error) {
+	RegisterPacketParser("create-tent", func(parsed map[string]interface{}) (Packet, error) {
 		return parseSinglePacketOfType(parsed, &CreateTentPacket{})
 	})
 }
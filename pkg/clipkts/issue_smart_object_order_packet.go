@@ -1,6 +1,11 @@
 package clipkts
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/orders"
+)
 
 // IssueSmartObjectOrderPacket describes the client trying to issue an order
 // to a smart object by the given UID.
@@ -12,7 +17,9 @@ type IssueSmartObjectOrderPacket struct {
 	UID string `json:"uid" mapstructure:"uid"`
 
 	// Order contains the order being issued and should be processed based
-	// on the UnitType of the smart object.
+	// on the UnitType of the smart object. When sending this should be a
+	// pointer to one of the orders in the orders package; when parsed this
+	// is the map representation of the order.
 	Order interface{} `json:"order" mapstructure:"order"`
 }
 
@@ -28,18 +35,34 @@ func (p *IssueSmartObjectOrderPacket) PrepareForMarshal() {
 	}
 }
 
+func (p *IssueSmartObjectOrderPacket) validate() error {
+	if p.UID == "" {
+		return errors.New("uid must not be blank")
+	}
+
+	switch order := p.Order.(type) {
+	case nil:
+		return errors.New("order cannot be empty")
+	case orders.Order:
+		if err := orders.ValidateOrder(order); err != nil {
+			return fmt.Errorf("invalid order: %w", err)
+		}
+	case map[string]interface{}:
+	default:
+		return fmt.Errorf("order must be an orders.Order or a map, got %T", p.Order)
+	}
+	return nil
+}
+
 func init() {
-	registerPacketParser("issue-smart-object-order", func(parsed map[string]interface{}) (Packet, error) {
+	RegisterPacketParser("issue-smart-object-order", func(parsed map[string]interface{}) (Packet, error) {
 		var issSOOrder IssueSmartObjectOrderPacket
 		_, err := parseSinglePacketOfType(parsed, &issSOOrder)
 		if err != nil {
 			return nil, err
 		}
-		if issSOOrder.UID == "" {
-			return nil, fmt.Errorf("uid must not be blank")
-		}
-		if issSOOrder.Order == nil {
-			return nil, fmt.Errorf("order cannot be empty")
+		if err = issSOOrder.validate(); err != nil {
+			return nil, err
 		}
 		return &issSOOrder, nil
 	})
@@ -1,5 +1,7 @@
 package clipkts
 
+import "errors"
+
 // MinePacket is used for AI to mine resources which are near a unit they
 // control.
 type MinePacket struct {
@@ -21,8 +23,18 @@ func (p *MinePacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *MinePacket) validate() error {
+	if p.MiningUID == "" {
+		return errors.New("mining_uid cannot be blank")
+	}
+	if p.MinedUID == "" {
+		return errors.New("mined_uid cannot be blank")
+	}
+	return nil
+}
+
 func init() {
-	registerPacketParser("mine", func(parsed map[string]interface{}) (Packet, error) {
+	RegisterPacketParser("mine", func(parsed map[string]interface{}) (Packet, error) {
 		return parseSinglePacketOfType(parsed, &MinePacket{})
 	})
 }
@@ -1,6 +1,6 @@
 package clipkts
 
-import "fmt"
+import "errors"
 
 // MovePacket describes the client trying to move the game object with
 // the given uid in the given direction.
@@ -25,15 +25,22 @@ func (p *MovePacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *MovePacket) validate() error {
+	if p.UID == "" {
+		return errors.New("uid cannot be blank")
+	}
+	return validateFinite("dir", p.Direction.X, p.Direction.Y)
+}
+
 func init() {
-	registerPacketParser("move", func(parsed map[string]interface{}) (Packet, error) {
+	RegisterPacketParser("move", func(parsed map[string]interface{}) (Packet, error) {
 		var movePacket MovePacket
 		_, err := parseSinglePacketOfType(parsed, &movePacket)
 		if err != nil {
 			return nil, err
 		}
 		if movePacket.UID == "" {
-			return nil, fmt.Errorf("uid cannot be blank")
+			return nil, errors.New("uid cannot be blank")
 		}
 		return &movePacket, nil
 	})
@@ -4,18 +4,47 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/calamity-of-subterfuge/cos/pkg/utils"
 )
 
-type packetParser func(map[string]interface{}) (Packet, error)
+// PacketParser parses a single packet of a particular type from its map
+// representation.
+type PacketParser func(map[string]interface{}) (Packet, error)
 
-var packetParsersByType map[string]packetParser = make(map[string]packetParser)
+// ErrUnknownPacketType is wrapped by the error from ParseSinglePacket when
+// there is no parser registered for the type of the packet.
+var ErrUnknownPacketType = errors.New("unknown packet type")
 
-func registerPacketParser(typ string, parser packetParser) {
+var packetParsersLock sync.RWMutex
+var packetParsersByType map[string]PacketParser = make(map[string]PacketParser)
+
+// RegisterPacketParser registers the parser to use for packets of the given
+// type, replacing the existing parser for that type if there is one. This
+// allows applications to handle packets which this library does not know
+// about yet without forking it. This is typically called from init().
+func RegisterPacketParser(typ string, parser PacketParser) {
+	packetParsersLock.Lock()
+	defer packetParsersLock.Unlock()
 	packetParsersByType[typ] = parser
 }
 
+// KnownPacketTypes returns the packet types which have a registered parser,
+// in sorted order.
+func KnownPacketTypes() []string {
+	packetParsersLock.RLock()
+	defer packetParsersLock.RUnlock()
+
+	res := make([]string, 0, len(packetParsersByType))
+	for typ := range packetParsersByType {
+		res = append(res, typ)
+	}
+	sort.Strings(res)
+	return res
+}
+
 // ParsePacket attempts to parse the packet described by the given bytes as one
 // of the packets in this package. The resulting interface is nil if error is
 // not nil, otherwise its a pointer to one of the Packet structs and should be
@@ -78,10 +107,11 @@ func ParseSinglePacket(parsed map[string]interface{}) (Packet, error) {
 		return nil, errors.New("packet has type but it's not a string")
 	}
 
-	var parser packetParser
-	parser, found = packetParsersByType[packetType]
+	packetParsersLock.RLock()
+	parser, found := packetParsersByType[packetType]
+	packetParsersLock.RUnlock()
 	if !found {
-		return nil, fmt.Errorf("unknown packet type: %s", packetType)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPacketType, packetType)
 	}
 
 	return parser(parsed)
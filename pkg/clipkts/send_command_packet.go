@@ -23,15 +23,19 @@ func (p *SendCommandPacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *SendCommandPacket) validate() error {
+	return validateText(p.Text)
+}
+
 func init() {
-	registerPacketParser("send-command", func(parsed map[string]interface{}) (Packet, error) {
+	RegisterPacketParser("send-command", func(parsed map[string]interface{}) (Packet, error) {
 		var scPacket SendCommandPacket
 		_, err := parseSinglePacketOfType(parsed, &scPacket)
 		if err != nil {
 			return nil, err
 		}
-		if len(scPacket.Text) > 4096 {
-			scPacket.Text = scPacket.Text[:4096]
+		if len(scPacket.Text) > MaxTextLength {
+			scPacket.Text = scPacket.Text[:MaxTextLength]
 		}
 		return &scPacket, nil
 	})
@@ -19,15 +19,19 @@ func (p *SendLocalMessagePacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *SendLocalMessagePacket) validate() error {
+	return validateText(p.Text)
+}
+
 func init() {
-	registerPacketParser("send-local-message", func(parsed map[string]interface{}) (Packet, error) {
+	RegisterPacketParser("send-local-message", func(parsed map[string]interface{}) (Packet, error) {
 		var scPacket SendLocalMessagePacket
 		_, err := parseSinglePacketOfType(parsed, &scPacket)
 		if err != nil {
 			return nil, err
 		}
-		if len(scPacket.Text) > 4096 {
-			scPacket.Text = scPacket.Text[:4096]
+		if len(scPacket.Text) > MaxTextLength {
+			scPacket.Text = scPacket.Text[:MaxTextLength]
 		}
 		return &scPacket, nil
 	})
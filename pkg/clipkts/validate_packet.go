@@ -0,0 +1,54 @@
+package clipkts
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// MaxTextLength is the maximum length of the text on a SendCommandPacket or
+// SendLocalMessagePacket. The server ignores anything after this length.
+const MaxTextLength = 4096
+
+// validatablePacket is implemented by packets which have rules beyond what
+// can be described by their types.
+type validatablePacket interface {
+	validate() error
+}
+
+// ValidatePacket checks that the given packet is fit to send, so that it's
+// rejected before being sent rather than by the server. This enforces the
+// rules of the parsers in this package, plus stricter rules which the
+// parsers don't enforce: text can't be longer than MaxTextLength, which the
+// parsers truncate instead, the uids on a MinePacket can't be blank, and
+// directions and locations must be finite. The packet must be a non-nil
+// pointer to one of the packets in this package.
+func ValidatePacket(packet Packet) error {
+	if packet == nil {
+		return errors.New("packet is nil")
+	}
+
+	if v := reflect.ValueOf(packet); v.Kind() == reflect.Ptr && v.IsNil() {
+		return fmt.Errorf("packet is a nil %T", packet)
+	}
+
+	if validatable, ok := packet.(validatablePacket); ok {
+		return validatable.validate()
+	}
+	return nil
+}
+
+func validateFinite(name string, x, y float64) error {
+	if math.IsNaN(x) || math.IsInf(x, 0) || math.IsNaN(y) || math.IsInf(y, 0) {
+		return fmt.Errorf("%s must be finite, got (%v, %v)", name, x, y)
+	}
+	return nil
+}
+
+func validateText(text string) error {
+	if len(text) > MaxTextLength {
+		return fmt.Errorf("text cannot be longer than %d characters, got %d", MaxTextLength, len(text))
+	}
+	return nil
+}
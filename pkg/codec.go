@@ -0,0 +1,203 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gorilla/websocket"
+)
+
+// Codec describes how packets are encoded within websocket message frames.
+// Every frame contains one or more packets. Codecs are negotiated using
+// websocket subprotocols when connecting; see ConnOptions.Codecs.
+type Codec interface {
+	// Subprotocol is the websocket subprotocol which identifies this codec
+	// during the websocket handshake.
+	Subprotocol() string
+
+	// MessageType is the websocket message type used for frames in this
+	// codec, i.e., websocket.TextMessage or websocket.BinaryMessage
+	MessageType() int
+
+	// EncodeFrame encodes the given packets into a single frame. The packets
+	// have already been prepared for marshalling.
+	EncodeFrame(packets []interface{}) ([]byte, error)
+
+	// DecodeFrame decodes the packets within the given frame into their map
+	// representation.
+	DecodeFrame(frame []byte) ([]map[string]interface{}, error)
+}
+
+// rawFrameSplitter is implemented by codecs which can split a frame into the
+// JSON objects for each packet without decoding them, which is required for
+// ConnOptions.RawMessages
+type rawFrameSplitter interface {
+	SplitFrame(frame []byte) ([]json.RawMessage, error)
+}
+
+// JSONCodec is the default codec, where each frame is a text message
+// containing either a JSON object or a JSON array of objects. Numbers are
+// decoded as json.Number.
+type JSONCodec struct{}
+
+// CBORCodec encodes each frame as a binary message containing a CBOR array of
+// maps (RFC 8949), which is typically smaller and faster to decode than JSON.
+// Packets are encoded using their json tags. Numbers are decoded as uint64,
+// int64 or float64 depending on how they were encoded.
+type CBORCodec struct{}
+
+var cborEncMode cbor.EncMode
+var cborDecMode cbor.DecMode
+
+func init() {
+	var err error
+	cborEncMode, err = cbor.EncOptions{}.EncMode()
+	if err != nil {
+		panic(fmt.Sprintf("creating cbor enc mode: %v", err))
+	}
+
+	cborDecMode, err = cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+	}.DecMode()
+	if err != nil {
+		panic(fmt.Sprintf("creating cbor dec mode: %v", err))
+	}
+}
+
+// Subprotocol returns cos-json
+func (c JSONCodec) Subprotocol() string {
+	return "cos-json"
+}
+
+// MessageType returns websocket.TextMessage
+func (c JSONCodec) MessageType() int {
+	return websocket.TextMessage
+}
+
+// EncodeFrame encodes the packets as a JSON array
+func (c JSONCodec) EncodeFrame(packets []interface{}) ([]byte, error) {
+	marshalledPackets, err := json.Marshal(packets)
+	if err != nil {
+		for _, pkt := range packets {
+			_, subErr := json.Marshal(pkt)
+			if subErr != nil {
+				return nil, fmt.Errorf("failed to marshal packet %v: %w", pkt, err)
+			}
+		}
+		return nil, fmt.Errorf("failed to marshal packets despite each individual packet marshalling fine! packets: %v, err: %w", packets, err)
+	}
+	return marshalledPackets, nil
+}
+
+// DecodeFrame decodes a JSON object or array of objects using json.Number for
+// numbers
+func (c JSONCodec) DecodeFrame(frame []byte) ([]map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewBuffer(frame))
+	decoder.UseNumber()
+
+	var decodedMessage interface{}
+	err := decoder.Decode(&decodedMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode: %w", err)
+	}
+
+	return framePacketsToMaps(decodedMessage)
+}
+
+// SplitFrame splits a JSON object or array of objects into the individual
+// objects without decoding them
+func (c JSONCodec) SplitFrame(frame []byte) ([]json.RawMessage, error) {
+	trimmed := bytes.TrimLeft(frame, " \t\r\n")
+	if len(trimmed) == 0 {
+		return nil, errors.New("empty message")
+	}
+
+	if trimmed[0] == '{' {
+		return []json.RawMessage{trimmed}, nil
+	}
+
+	if trimmed[0] != '[' {
+		return nil, fmt.Errorf("unknown format: %s", string(frame))
+	}
+
+	var packets []json.RawMessage
+	err := json.Unmarshal(trimmed, &packets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode: %w", err)
+	}
+
+	for _, packet := range packets {
+		if len(packet) == 0 || packet[0] != '{' {
+			return nil, fmt.Errorf("element in array is not a JSON object: %s", string(packet))
+		}
+	}
+	return packets, nil
+}
+
+// Subprotocol returns cos-cbor
+func (c CBORCodec) Subprotocol() string {
+	return "cos-cbor"
+}
+
+// MessageType returns websocket.BinaryMessage
+func (c CBORCodec) MessageType() int {
+	return websocket.BinaryMessage
+}
+
+// EncodeFrame encodes the packets as a CBOR array
+func (c CBORCodec) EncodeFrame(packets []interface{}) ([]byte, error) {
+	res, err := cborEncMode.Marshal(packets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal packets %v: %w", packets, err)
+	}
+	return res, nil
+}
+
+// DecodeFrame decodes a CBOR map or array of maps with string keys
+func (c CBORCodec) DecodeFrame(frame []byte) ([]map[string]interface{}, error) {
+	var decodedMessage interface{}
+	err := cborDecMode.Unmarshal(frame, &decodedMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode: %w", err)
+	}
+
+	return framePacketsToMaps(decodedMessage)
+}
+
+// framePacketsToMaps converts a decoded frame, which is either a single
+// packet or an array of packets, into the packets
+func framePacketsToMaps(decoded interface{}) ([]map[string]interface{}, error) {
+	switch v := decoded.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}, nil
+	case []interface{}:
+		res := make([]map[string]interface{}, len(v))
+		for idx, ele := range v {
+			packet, ok := ele.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("element in array is not an object: %v", ele)
+			}
+			res[idx] = packet
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %v", decoded)
+	}
+}
+
+// codecForSubprotocol returns the codec from the given codecs which matches
+// the negotiated subprotocol, or JSONCodec if none match
+func codecForSubprotocol(codecs []Codec, subprotocol string) Codec {
+	if subprotocol != "" {
+		for _, codec := range codecs {
+			if codec.Subprotocol() == subprotocol {
+				return codec
+			}
+		}
+	}
+	return JSONCodec{}
+}
@@ -0,0 +1,189 @@
+package pkg_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	cos "github.com/calamity-of-subterfuge/cos/pkg"
+	"github.com/calamity-of-subterfuge/cos/pkg/clipkts"
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+	"github.com/gorilla/websocket"
+)
+
+// startMirrorServer starts a websocket server using the given upgrader which
+// reads the JWT and then writes back every frame it receives unchanged. The
+// returned channel receives the request headers of each connection.
+func startMirrorServer(t *testing.T, upgrader websocket.Upgrader) (string, chan http.Header) {
+	t.Helper()
+
+	// ConnectGameWithOptions sends the production origin
+	upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+
+	headers := make(chan http.Header, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrading: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		msgType, jwt, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if msgType != websocket.TextMessage || string(jwt) != "test-jwt" {
+			t.Errorf("expected JWT as text first, got %d %q", msgType, string(jwt))
+			return
+		}
+
+		for {
+			msgType, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err = conn.WriteMessage(msgType, message); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), headers
+}
+
+func connectMirror(t *testing.T, url string, opts *cos.ConnOptions) (*cos.Conn, chan cos.ReceivedMessage) {
+	t.Helper()
+
+	wsConn, err := cos.ConnectGameWithOptions(url, "test-jwt", opts)
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+
+	recvQueue := make(chan cos.ReceivedMessage, 16)
+	closedQueue := make(chan string, 1)
+	conn := cos.NewConnWithOptions(wsConn, "test", recvQueue, closedQueue, opts)
+	t.Cleanup(func() {
+		conn.Close()
+		<-closedQueue
+	})
+	return conn, recvQueue
+}
+
+func TestConn_negotiatesCodec(t *testing.T) {
+	cases := []struct {
+		serverSubprotocols []string
+		expected           cos.Codec
+	}{
+		{[]string{"cos-cbor", "cos-json"}, cos.CBORCodec{}},
+		{[]string{"cos-json"}, cos.JSONCodec{}},
+		{nil, cos.JSONCodec{}},
+	}
+
+	for _, c := range cases {
+		url, _ := startMirrorServer(t, websocket.Upgrader{Subprotocols: c.serverSubprotocols})
+		conn, recvQueue := connectMirror(t, url, &cos.ConnOptions{
+			Codecs: []cos.Codec{cos.CBORCodec{}, cos.JSONCodec{}},
+		})
+
+		if conn.Codec() != c.expected {
+			t.Errorf("server %v: expected codec %T, got %T", c.serverSubprotocols, c.expected, conn.Codec())
+			continue
+		}
+
+		err := conn.Send(&clipkts.MovePacket{UID: "a", Direction: clipkts.Vector{X: 0.5, Y: -1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := <-recvQueue
+		if msg.Message["type"] != "move" || msg.Message["uid"] != "a" {
+			t.Errorf("server %v: bad round trip: %v", c.serverSubprotocols, msg.Message)
+		}
+	}
+}
+
+func TestConn_compression(t *testing.T) {
+	url, headers := startMirrorServer(t, websocket.Upgrader{EnableCompression: true})
+	conn, recvQueue := connectMirror(t, url, &cos.ConnOptions{
+		EnableCompression: true,
+		CompressionLevel:  9,
+	})
+
+	if ext := (<-headers).Get("Sec-Websocket-Extensions"); !strings.Contains(ext, "permessage-deflate") {
+		t.Errorf("expected per-message deflate to be offered, got %q", ext)
+	}
+
+	text := strings.Repeat("compressible ", 200)
+	err := conn.Send(&clipkts.SendLocalMessagePacket{Text: text})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-recvQueue
+	if msg.Message["text"] != text {
+		t.Errorf("bad round trip: %v", msg.Message)
+	}
+}
+
+func TestCBORCodec_matchesJSON(t *testing.T) {
+	packets := []interface{}{
+		&srvpkts.GameObjectUpdatePacket{
+			UID:              "a",
+			GameTime:         12.25,
+			Position:         srvpkts.Vector{X: 1.5, Y: -3},
+			Velocity:         srvpkts.Vector{X: 1e-9, Y: 1e9},
+			AnimationPlaying: true,
+		},
+		&srvpkts.TeamResourceChangedPacket{
+			GameTime:  3,
+			Resources: map[string]int{"gold": 12, "wood": -4},
+		},
+		&srvpkts.ChatMessagePacket{
+			GameTime:  1,
+			Time:      1.6e9,
+			AuthorUID: "b",
+			Text:      "hello",
+		},
+	}
+	for _, packet := range packets {
+		packet.(srvpkts.Packet).PrepareForMarshal()
+	}
+
+	parse := func(codec cos.Codec) []srvpkts.Packet {
+		frame, err := codec.EncodeFrame(packets)
+		if err != nil {
+			t.Fatalf("%T: encoding: %v", codec, err)
+		}
+
+		maps, err := codec.DecodeFrame(frame)
+		if err != nil {
+			t.Fatalf("%T: decoding: %v", codec, err)
+		}
+
+		res := make([]srvpkts.Packet, len(maps))
+		for idx, m := range maps {
+			res[idx], err = srvpkts.ParseSinglePacket(m)
+			if err != nil {
+				t.Fatalf("%T: parsing %v: %v", codec, m, err)
+			}
+		}
+		return res
+	}
+
+	fromJSON := parse(cos.JSONCodec{})
+	fromCBOR := parse(cos.CBORCodec{})
+	if !reflect.DeepEqual(fromJSON, fromCBOR) {
+		t.Errorf("codecs disagree\njson: %+v\ncbor: %+v", fromJSON, fromCBOR)
+	}
+	for idx, packet := range fromCBOR {
+		if !reflect.DeepEqual(packet, packets[idx]) {
+			t.Errorf("packet %d did not round trip: %+v vs %+v", idx, packet, packets[idx])
+		}
+	}
+}
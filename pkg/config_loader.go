@@ -0,0 +1,355 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigEnvPrefix is the prefix of the environment variables read by
+// LoadConfig unless otherwise specified
+const DefaultConfigEnvPrefix = "COS_"
+
+// ConfigSources describes where LoadConfig reads settings from. Each setting
+// is taken from the first of the following which provides it: flags, then
+// environment variables, then the config file, then the defaults.
+//
+// Settings are identified by the keys of ConfigFile; the environment
+// variable for a setting is the prefix followed by the key in upper case,
+// e.g., COS_GRANT_IDEN, and the flag is the key with dashes instead of
+// underscores, e.g., -grant-iden. Lists are comma separated in environment
+// variables and flags.
+type ConfigSources struct {
+	// File is the path to a YAML, JSON or TOML config file, where the format
+	// is chosen from the extension. If empty, the file is taken from the
+	// config flag or the CONFIG environment variable with the EnvPrefix,
+	// e.g., COS_CONFIG, and if those are empty too no file is read.
+	File string
+
+	// EnvPrefix is the prefix of the environment variables to read. If
+	// empty, DefaultConfigEnvPrefix is used.
+	EnvPrefix string
+
+	// Environ is the environment in the form "key=value". If nil,
+	// os.Environ() is used.
+	Environ []string
+
+	// Flags, if not nil, is a parsed FlagSet which was passed to
+	// RegisterConfigFlags. Only flags which were set are used.
+	Flags *flag.FlagSet
+
+	// Defaults, if not nil, are used for settings which no other source
+	// provides. This is convenient for settings which are checked in with
+	// the AI, such as its name and role, but must not contain credentials
+	// which shouldn't be.
+	Defaults *ConfigFile
+}
+
+// ConfigFile is the format of a config file for LoadConfig. Every field is
+// optional within the file, but the loaded Config must be valid.
+type ConfigFile struct {
+	// Email of the account to login with
+	Email string `mapstructure:"email" json:"email"`
+
+	// GrantIden identifies the grant used to login
+	GrantIden string `mapstructure:"grant_iden" json:"grant_iden"`
+
+	// Secret for the grant. It's usually better to use SecretFile or an
+	// environment variable than to store this directly in a config file.
+	Secret string `mapstructure:"secret" json:"secret"`
+
+	// SecretFile is the path to a file containing the secret, used when
+	// Secret is empty. Surrounding whitespace in the file is ignored.
+	SecretFile string `mapstructure:"secret_file" json:"secret_file"`
+
+	// AIName is the name of the AI personality
+	AIName string `mapstructure:"ai_name" json:"ai_name"`
+
+	// AIUID is the unique identifier of the AI personality
+	AIUID string `mapstructure:"ai_uid" json:"ai_uid"`
+
+	// Version is the semantic version of the AI personality
+	Version string `mapstructure:"version" json:"version"`
+
+	// Role is the name of the role, i.e., economy, military or science
+	Role string `mapstructure:"role" json:"role"`
+
+	// ClientAllowList are the UIDs of the users which may select the AI
+	ClientAllowList []string `mapstructure:"client_allow_list" json:"client_allow_list"`
+
+	// MaxConcurrentInstances is the maximum number of games to play at
+	// once. Defaults to 1.
+	MaxConcurrentInstances int `mapstructure:"max_concurrent_instances" json:"max_concurrent_instances"`
+}
+
+// String formats the file with the secret redacted
+func (f ConfigFile) String() string {
+	redacted := f
+	redacted.Secret = redact(f.Secret)
+	type plain ConfigFile
+	return fmt.Sprintf("%+v", plain(redacted))
+}
+
+// GoString is equivalent to String, so the secret is also redacted with %#v
+func (f ConfigFile) GoString() string {
+	return f.String()
+}
+
+// configKeys are the keys of every setting in ConfigFile, in the order they
+// are registered as flags
+var configKeys = []string{
+	"email",
+	"grant_iden",
+	"secret",
+	"secret_file",
+	"ai_name",
+	"ai_uid",
+	"version",
+	"role",
+	"client_allow_list",
+	"max_concurrent_instances",
+}
+
+// configFlagUsage describes each setting for RegisterConfigFlags
+var configFlagUsage = map[string]string{
+	"email":                    "email of the account to login with",
+	"grant_iden":               "identifier of the grant to login with",
+	"secret":                   "secret for the grant; prefer -secret-file or the environment",
+	"secret_file":              "path to a file containing the secret for the grant",
+	"ai_name":                  "name of the AI personality",
+	"ai_uid":                   "unique identifier of the AI personality",
+	"version":                  "semantic version of the AI personality",
+	"role":                     "role of the AI personality: economy, military or science",
+	"client_allow_list":        "comma separated UIDs of the users which may select the AI",
+	"max_concurrent_instances": "maximum number of games to play at once",
+}
+
+// RegisterConfigFlags registers a flag on the given FlagSet for every
+// setting LoadConfig reads, plus the config flag for the path to the config
+// file. The defaults of the flags are always empty; flags are only used by
+// LoadConfig if they are set.
+func RegisterConfigFlags(fs *flag.FlagSet) {
+	fs.String("config", "", "path to a YAML, JSON or TOML config file")
+	for _, key := range configKeys {
+		fs.String(configFlagName(key), "", configFlagUsage[key])
+	}
+}
+
+// LoadConfig loads and validates the configuration for Play from the given
+// sources. If sources is nil only the environment is used. The returned
+// error describes every problem with the configuration, not just the first.
+func LoadConfig(sources *ConfigSources) (*Config, error) {
+	file, err := LoadConfigFile(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := file.ToConfig()
+	err = ValidateConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadConfigFile merges the settings from the given sources as in LoadConfig,
+// including reading the secret file, but does not validate them. This is
+// useful when only some settings are needed, e.g., just the credentials.
+func LoadConfigFile(sources *ConfigSources) (*ConfigFile, error) {
+	if sources == nil {
+		sources = &ConfigSources{}
+	}
+
+	envPrefix := sources.EnvPrefix
+	if envPrefix == "" {
+		envPrefix = DefaultConfigEnvPrefix
+	}
+	environ := sources.Environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+
+	fromEnv := configFromEnv(environ, envPrefix)
+	fromFlags := configFromFlags(sources.Flags)
+
+	path := sources.File
+	if path == "" {
+		path = fromFlags["config"]
+	}
+	if path == "" {
+		path = fromEnv["config"]
+	}
+	delete(fromEnv, "config")
+	delete(fromFlags, "config")
+
+	merged := make(map[string]interface{})
+	if path != "" {
+		fromFile, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		mergeConfigLayer(merged, fromFile)
+	}
+	mergeConfigLayer(merged, stringMapToInterfaces(fromEnv))
+	mergeConfigLayer(merged, stringMapToInterfaces(fromFlags))
+
+	file := ConfigFile{}
+	if sources.Defaults != nil {
+		file = *sources.Defaults
+		file.ClientAllowList = append([]string(nil), file.ClientAllowList...)
+	}
+	if file.MaxConcurrentInstances == 0 {
+		file.MaxConcurrentInstances = 1
+	}
+	if _, found := merged["secret_file"]; found {
+		if _, found = merged["secret"]; !found {
+			file.Secret = ""
+		}
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToSliceHookFunc(","),
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+		ZeroFields:       true,
+		Result:           &file,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("constructing decoder: %w", err)
+	}
+	err = decoder.Decode(merged)
+	if err != nil {
+		return nil, fmt.Errorf("decoding config: %w", err)
+	}
+
+	if file.Secret == "" && file.SecretFile != "" {
+		secret, err := ioutil.ReadFile(file.SecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading secret file: %w", err)
+		}
+		file.Secret = strings.TrimSpace(string(secret))
+	}
+	return &file, nil
+}
+
+// ToConfig converts the settings to a Config for Play, which is not
+// validated
+func (f *ConfigFile) ToConfig() *Config {
+	cfg := &Config{
+		Email:     f.Email,
+		GrantIden: f.GrantIden,
+		Secret:    f.Secret,
+		AIConfig: &AIConfig{
+			AIName:                 f.AIName,
+			AIUID:                  f.AIUID,
+			Version:                f.Version,
+			Role:                   utils.RoleFromName(f.Role),
+			ClientAllowList:        append([]string(nil), f.ClientAllowList...),
+			MaxConcurrentInstances: f.MaxConcurrentInstances,
+		},
+	}
+	if cfg.AIConfig.ClientAllowList == nil {
+		cfg.AIConfig.ClientAllowList = make([]string, 0)
+	}
+	return cfg
+}
+
+// mergeConfigLayer overrides the settings in merged with those in layer. A
+// secret file in the layer overrides a secret from an earlier layer.
+func mergeConfigLayer(merged map[string]interface{}, layer map[string]interface{}) {
+	_, hasSecret := layer["secret"]
+	_, hasSecretFile := layer["secret_file"]
+	if hasSecretFile && !hasSecret {
+		delete(merged, "secret")
+	}
+
+	for key, val := range layer {
+		merged[key] = val
+	}
+}
+
+// stringMapToInterfaces converts the given map for mergeConfigLayer
+func stringMapToInterfaces(m map[string]string) map[string]interface{} {
+	res := make(map[string]interface{}, len(m))
+	for key, val := range m {
+		res[key] = val
+	}
+	return res
+}
+
+// configFlagName returns the name of the flag for the given setting
+func configFlagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// configFromEnv returns the settings in the given environment, including
+// the config file path
+func configFromEnv(environ []string, prefix string) map[string]string {
+	res := make(map[string]string)
+	for _, key := range append([]string{"config"}, configKeys...) {
+		name := prefix + strings.ToUpper(key)
+		for _, kv := range environ {
+			if strings.HasPrefix(kv, name+"=") {
+				res[key] = kv[len(name)+1:]
+			}
+		}
+	}
+	return res
+}
+
+// configFromFlags returns the settings which were set in the given flags,
+// including the config file path
+func configFromFlags(fs *flag.FlagSet) map[string]string {
+	res := make(map[string]string)
+	if fs == nil {
+		return res
+	}
+
+	keysByFlag := map[string]string{"config": "config"}
+	for _, key := range configKeys {
+		keysByFlag[configFlagName(key)] = key
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if key, found := keysByFlag[f.Name]; found {
+			res[key] = f.Value.String()
+		}
+	})
+	return res
+}
+
+// readConfigFile reads the YAML, JSON or TOML file at the given path
+func readConfigFile(path string) (map[string]interface{}, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	res := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &res)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		err = decoder.Decode(&res)
+	case ".toml":
+		err = toml.Unmarshal(raw, &res)
+	default:
+		return nil, fmt.Errorf("unknown config file format %q (expected .yaml, .yml, .json or .toml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return res, nil
+}
@@ -0,0 +1,217 @@
+package pkg_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	cos "github.com/calamity-of-subterfuge/cos/pkg"
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+)
+
+var configFiles = map[string]string{
+	"cfg.yaml": `
+email: file@example.com
+grant_iden: pa_file
+secret: file-secret
+ai_name: FileAI
+ai_uid: file-ai
+version: 1.2.3-beta.1
+role: military
+client_allow_list: [a, b]
+max_concurrent_instances: 3
+`,
+	"cfg.json": `{
+	"email": "file@example.com",
+	"grant_iden": "pa_file",
+	"secret": "file-secret",
+	"ai_name": "FileAI",
+	"ai_uid": "file-ai",
+	"version": "1.2.3-beta.1",
+	"role": "military",
+	"client_allow_list": ["a", "b"],
+	"max_concurrent_instances": 3
+}`,
+	"cfg.toml": `
+email = "file@example.com"
+grant_iden = "pa_file"
+secret = "file-secret"
+ai_name = "FileAI"
+ai_uid = "file-ai"
+version = "1.2.3-beta.1"
+role = "military"
+client_allow_list = ["a", "b"]
+max_concurrent_instances = 3
+`,
+}
+
+func writeFile(t *testing.T, name, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_fileFormats(t *testing.T) {
+	expected := &cos.Config{
+		Email:     "file@example.com",
+		GrantIden: "pa_file",
+		Secret:    "file-secret",
+		AIConfig: &cos.AIConfig{
+			AIName:                 "FileAI",
+			AIUID:                  "file-ai",
+			Version:                "1.2.3-beta.1",
+			Role:                   utils.RoleMilitaryAI,
+			ClientAllowList:        []string{"a", "b"},
+			MaxConcurrentInstances: 3,
+		},
+	}
+
+	for name, contents := range configFiles {
+		cfg, err := cos.LoadConfig(&cos.ConfigSources{
+			File:    writeFile(t, name, contents),
+			Environ: []string{},
+		})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg, expected) || !reflect.DeepEqual(cfg.AIConfig, expected.AIConfig) {
+			t.Errorf("%s: expected %+v, got %+v", name, expected.AIConfig, cfg.AIConfig)
+		}
+	}
+}
+
+func TestLoadConfig_precedence(t *testing.T) {
+	path := writeFile(t, "cfg.yaml", configFiles["cfg.yaml"])
+	secretPath := writeFile(t, "secret", "  env-secret\n")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cos.RegisterConfigFlags(fs)
+	err := fs.Parse([]string{"-config", path, "-ai-uid", "flag-ai", "-client-allow-list", "x,y,z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := cos.LoadConfig(&cos.ConfigSources{
+		Flags: fs,
+		Environ: []string{
+			"COS_AI_UID=env-ai",
+			"COS_EMAIL=env@example.com",
+			"COS_SECRET_FILE=" + secretPath,
+			"COS_MAX_CONCURRENT_INSTANCES=5",
+			"OTHER_EMAIL=other@example.com",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.AIConfig.AIUID != "flag-ai" {
+		t.Errorf("expected flags to take precedence over the environment, got %s", cfg.AIConfig.AIUID)
+	}
+	if cfg.Email != "env@example.com" || cfg.AIConfig.MaxConcurrentInstances != 5 {
+		t.Errorf("expected the environment to take precedence over the file, got %s %d", cfg.Email, cfg.AIConfig.MaxConcurrentInstances)
+	}
+	if cfg.Secret != "env-secret" {
+		t.Errorf("expected the secret file from the environment to override the file secret, got %q", cfg.Secret)
+	}
+	if cfg.AIConfig.AIName != "FileAI" || cfg.AIConfig.Role != utils.RoleMilitaryAI {
+		t.Errorf("expected remaining settings from the file, got %+v", cfg.AIConfig)
+	}
+	if !reflect.DeepEqual(cfg.AIConfig.ClientAllowList, []string{"x", "y", "z"}) {
+		t.Errorf("expected comma separated list from flags, got %v", cfg.AIConfig.ClientAllowList)
+	}
+}
+
+func TestLoadConfig_defaults(t *testing.T) {
+	defaults := &cos.ConfigFile{
+		AIName:          "DefaultAI",
+		AIUID:           "default-ai",
+		Version:         "0.0.1",
+		Role:            "science",
+		ClientAllowList: []string{"a", "b", "c"},
+	}
+	cfg, err := cos.LoadConfig(&cos.ConfigSources{
+		File:     writeFile(t, "cfg.yaml", "ai_uid: file-ai\nclient_allow_list: [z]"),
+		Defaults: defaults,
+		Environ:  []string{"COS_EMAIL=a@example.com", "COS_GRANT_IDEN=pa_xyz", "COS_SECRET=s"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.AIConfig.AIName != "DefaultAI" || cfg.AIConfig.Role != utils.RoleScienceAI || cfg.AIConfig.MaxConcurrentInstances != 1 {
+		t.Errorf("expected unset settings from the defaults, got %+v", cfg.AIConfig)
+	}
+	if cfg.AIConfig.AIUID != "file-ai" || !reflect.DeepEqual(cfg.AIConfig.ClientAllowList, []string{"z"}) {
+		t.Errorf("expected the file to override the defaults, got %+v", cfg.AIConfig)
+	}
+	if len(defaults.ClientAllowList) != 3 || defaults.ClientAllowList[0] != "a" {
+		t.Errorf("defaults were modified: %v", defaults.ClientAllowList)
+	}
+}
+
+func TestLoadConfig_invalid(t *testing.T) {
+	_, err := cos.LoadConfig(&cos.ConfigSources{
+		Environ: []string{
+			"COS_EMAIL=a@example.com",
+			"COS_SECRET=s",
+			"COS_AI_NAME=AI",
+			"COS_VERSION=1.0",
+			"COS_ROLE=player",
+			"COS_MAX_CONCURRENT_INSTANCES=0",
+		},
+	})
+
+	var cfgErr *cos.ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("expected ConfigError, got %v", err)
+	}
+
+	expected := []string{"grant_iden", "ai_uid", "version", "role", "max_concurrent_instances"}
+	if len(cfgErr.Problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %v", len(expected), cfgErr.Problems)
+	}
+	for idx, field := range expected {
+		if !strings.Contains(cfgErr.Problems[idx], field) {
+			t.Errorf("expected problem %d to mention %s, got %q", idx, field, cfgErr.Problems[idx])
+		}
+	}
+
+	_, err = cos.LoadConfig(&cos.ConfigSources{File: writeFile(t, "cfg.yaml", "emial: typo@example.com"), Environ: []string{}})
+	if err == nil || !strings.Contains(err.Error(), "emial") {
+		t.Errorf("expected unknown keys in the file to be rejected, got %v", err)
+	}
+}
+
+func TestConfig_redactsSecret(t *testing.T) {
+	cfg := &cos.Config{
+		Email:     "a@example.com",
+		GrantIden: "pa_xyz",
+		Secret:    "hunter2",
+		AIConfig:  &cos.AIConfig{AIName: "AI"},
+		Auth:      cos.NewAuthManager("a@example.com", "pa_xyz", "hunter2"),
+	}
+	file := cos.ConfigFile{Email: "a@example.com", Secret: "hunter2"}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		for _, val := range []interface{}{cfg, *cfg, file, cfg.Auth} {
+			formatted := fmt.Sprintf(format, val)
+			if strings.Contains(formatted, "hunter2") {
+				t.Errorf("%s of %T leaked the secret: %s", format, val, formatted)
+			}
+			if !strings.Contains(formatted, "a@example.com") {
+				t.Errorf("%s of %T should still include the email: %s", format, val, formatted)
+			}
+		}
+	}
+}
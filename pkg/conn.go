@@ -1,14 +1,17 @@
 package pkg
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/calamity-of-subterfuge/cos/pkg/clipkts"
 	"github.com/calamity-of-subterfuge/cos/pkg/utils"
 	"github.com/gorilla/websocket"
 )
@@ -22,6 +25,10 @@ type PreparablePacket interface {
 	PrepareForMarshal()
 }
 
+// ErrConnClosed is returned when trying to send on a Conn which has been
+// closed.
+var ErrConnClosed = errors.New("connection closed")
+
 // ReceivedMessage describes a message along with the connection it was
 // received on.
 type ReceivedMessage struct {
@@ -32,8 +39,97 @@ type ReceivedMessage struct {
 	// Message is the parsed message that was received. Note that this may have
 	// been only part of the actual logical message frame, since the calamity of
 	// subterfuge protocol allows multiple messages per message frame to reduce
-	// overhead on small messages.
+	// overhead on small messages. This is nil if the Conn was created with
+	// RawMessages, in which case Raw is set instead.
 	Message map[string]interface{}
+
+	// Raw is the JSON object for the message that was received if the Conn
+	// was created with RawMessages, otherwise nil. This is typically parsed
+	// with srvpkts.ParseSinglePacketJSON.
+	Raw json.RawMessage
+}
+
+// ConnOptions contains the optional settings for a Conn.
+type ConnOptions struct {
+	// SendPolicy decides how outgoing packets are rate limited and coalesced
+	// and what TrySend does when the SendQueue is full. If nil, packets are
+	// sent in order as quickly as possible and TrySend drops the newest
+	// packet.
+	SendPolicy *SendPolicy
+
+	// RawMessages, if true, means that received messages are split into
+	// packets but are not decoded, so ReceivedMessage.Raw is set rather than
+	// ReceivedMessage.Message. This avoids decoding into the map
+	// representation for packets which can be decoded directly. This only
+	// applies to the JSONCodec; with other codecs Message is always set.
+	RawMessages bool
+
+	// Codecs are the codecs that we support in order of preference. They're
+	// offered to the server as websocket subprotocols by
+	// ConnectGameWithOptions and the Conn uses whichever one the server
+	// selected. If the server does not select one, JSONCodec is used.
+	Codecs []Codec
+
+	// EnableCompression, if true, means that ConnectGameWithOptions attempts
+	// to negotiate per-message deflate (RFC 7692) with the server. If the
+	// server agrees, messages in both directions are compressed.
+	EnableCompression bool
+
+	// CompressionLevel is the flate compression level for outgoing messages
+	// if compression was negotiated. Zero means the default level.
+	CompressionLevel int
+
+	// PingInterval is how often pings are sent to keep the connection alive
+	// and measure the round trip time. Zero means slightly less than the
+	// lower of utils.CONN_READ_TIMEOUT and utils.CONN_WRITE_TIMEOUT, which is
+	// the minimum required to keep the connection alive; larger values are
+	// reduced to that.
+	PingInterval time.Duration
+
+	// ProtocolVersion is the protocol version the server reported, if known.
+	// This is not used by the Conn itself but allows games to adapt to the
+	// server. The Hub sets this from the lobby welcome message.
+	ProtocolVersion ProtocolVersion
+}
+
+// SendStats describes the outgoing packets on a Conn.
+type SendStats struct {
+	// Queued is the number of packets in the SendQueue which have not been
+	// read by the Conn yet.
+	Queued int
+
+	// Pending is the number of packets which have been read from the
+	// SendQueue but are waiting on rate limits or the websocket.
+	Pending int
+
+	// Sent is the total number of packets written to the websocket.
+	Sent int64
+
+	// Dropped is the total number of packets discarded by TrySend.
+	Dropped int64
+
+	// Coalesced is the total number of packets which were replaced by a
+	// newer packet before they were sent.
+	Coalesced int64
+}
+
+// RTTStats describes the round trip time of a Conn as measured by the time
+// between sending a ping and receiving the corresponding pong.
+type RTTStats struct {
+	// Last is the most recent round trip time
+	Last time.Duration
+
+	// Smoothed is an exponentially weighted moving average of the round trip
+	// time, weighting each new sample by 1/8 like TCP
+	Smoothed time.Duration
+
+	// Min is the lowest round trip time measured, which is the best estimate
+	// of the network latency without any queueing
+	Min time.Duration
+
+	// Samples is the number of round trip times measured. If zero, the other
+	// fields are zero.
+	Samples int64
 }
 
 // Conn is a convenience wrapper around a basic websocket connection which uses
@@ -42,6 +138,13 @@ type ReceivedMessage struct {
 // manages the required goroutines that read from the send queue and write to
 // the receive queue, which can be canceled using Close.
 type Conn struct {
+	// these are accessed atomically and hence need to be 64-bit aligned,
+	// which is only guaranteed for the first words in the struct
+	sentCount      int64
+	droppedCount   int64
+	coalescedCount int64
+	pendingCount   int64
+
 	// UID is the identifier of this connection which is forwarded alongside all
 	// messages to the receiving channel. This allows multiple connections to
 	// use the same receive channel if it's desirable to do so. It may be left
@@ -50,13 +153,27 @@ type Conn struct {
 	UID string
 
 	// SendQueue is the channel which the Conn reads from in order to write to the
-	// actual websocket.
+	// actual websocket. Writing to this channel only blocks if the Conn has
+	// fallen far behind; see SendPolicy.MaxPending and TrySend. Prefer Send for
+	// client packets, which validates them first.
 	SendQueue chan interface{}
 
 	recvQueue    chan ReceivedMessage
 	closedQueue  chan string
 	cancelSignal chan struct{}
+	closedSignal chan struct{}
 	conn         *websocket.Conn
+	sendPolicy   *SendPolicy
+	protocolVer  ProtocolVersion
+	rawMessages  bool
+	codec        Codec
+	pingInterval time.Duration
+
+	// createdAt is used as the epoch for ping payloads, so that round trip
+	// times use the monotonic clock
+	createdAt time.Time
+	rttLock   sync.Mutex
+	rtt       RTTStats
 }
 
 // NewConn takes over management of the given websocket connection and returns
@@ -72,13 +189,38 @@ type Conn struct {
 // message. Our uid is written to the closedQueue exactly once when the
 // underlying websocket connection is closed.
 func NewConn(conn *websocket.Conn, uid string, recvQueue chan ReceivedMessage, closedQueue chan string) *Conn {
+	return NewConnWithOptions(conn, uid, recvQueue, closedQueue, nil)
+}
+
+// NewConnWithOptions is equivalent to NewConn except it allows specifying
+// the optional settings for the connection. A nil opts is equivalent to
+// the zero value.
+func NewConnWithOptions(conn *websocket.Conn, uid string, recvQueue chan ReceivedMessage, closedQueue chan string, opts *ConnOptions) *Conn {
+	if opts == nil {
+		opts = &ConnOptions{}
+	}
+
 	res := &Conn{
 		UID:          uid,
 		SendQueue:    make(chan interface{}, 128),
 		recvQueue:    recvQueue,
 		closedQueue:  closedQueue,
 		cancelSignal: make(chan struct{}, 1),
+		closedSignal: make(chan struct{}),
 		conn:         conn,
+		sendPolicy:   opts.SendPolicy,
+		protocolVer:  opts.ProtocolVersion,
+		rawMessages:  opts.RawMessages,
+		codec:        codecForSubprotocol(opts.Codecs, conn.Subprotocol()),
+		pingInterval: opts.PingInterval,
+		createdAt:    time.Now(),
+	}
+
+	if opts.CompressionLevel != 0 {
+		err := conn.SetCompressionLevel(opts.CompressionLevel)
+		if err != nil {
+			log.Printf("Invalid compression level for %s: %v", uid, err)
+		}
 	}
 
 	go res.manageSend()
@@ -97,58 +239,183 @@ func (c *Conn) Close() {
 	}
 }
 
-func (c *Conn) manageSend() {
-	lowerTimeout := utils.CONN_READ_TIMEOUT
-	if utils.CONN_WRITE_TIMEOUT < lowerTimeout {
-		lowerTimeout = utils.CONN_WRITE_TIMEOUT
+// Send validates the given client packet, prepares it for marshalling, and
+// queues it to be sent. Unlike writing to the SendQueue directly, invalid
+// packets are rejected here rather than by the server, and this returns
+// ErrConnClosed rather than blocking if the connection is closed.
+func (c *Conn) Send(packet clipkts.Packet) error {
+	err := clipkts.ValidatePacket(packet)
+	if err != nil {
+		return fmt.Errorf("invalid %T: %w", packet, err)
 	}
 
-	pingInterval := (lowerTimeout * 9) / 10
-	pingTicker := time.NewTicker(pingInterval)
+	packet.PrepareForMarshal()
 
-	packets := make([]interface{}, 0, 1)
+	select {
+	case <-c.closedSignal:
+		return ErrConnClosed
+	default:
+	}
+
+	select {
+	case c.SendQueue <- packet:
+		return nil
+	case <-c.closedSignal:
+		return ErrConnClosed
+	}
+}
+
+// RTT returns the round trip time measured so far on this connection. This
+// may be called from any goroutine.
+func (c *Conn) RTT() RTTStats {
+	c.rttLock.Lock()
+	defer c.rttLock.Unlock()
+	return c.rtt
+}
+
+// recordPong updates the round trip time from the payload of a pong, which
+// is the payload of the ping we sent
+func (c *Conn) recordPong(payload string) {
+	sentAt, err := strconv.ParseInt(payload, 36, 64)
+	if err != nil {
+		// not one of our pings
+		return
+	}
+
+	rtt := time.Since(c.createdAt) - time.Duration(sentAt)
+	if rtt < 0 {
+		return
+	}
+
+	c.rttLock.Lock()
+	defer c.rttLock.Unlock()
+
+	c.rtt.Last = rtt
+	if c.rtt.Samples == 0 {
+		c.rtt.Smoothed = rtt
+		c.rtt.Min = rtt
+	} else {
+		c.rtt.Smoothed += (rtt - c.rtt.Smoothed) / 8
+		if rtt < c.rtt.Min {
+			c.rtt.Min = rtt
+		}
+	}
+	c.rtt.Samples++
+}
+
+// Codec returns the codec this connection is using, which was negotiated
+// with the server when connecting.
+func (c *Conn) Codec() Codec {
+	return c.codec
+}
+
+// ProtocolVersion returns the protocol version of the server from the options
+// used to create this connection, or the zero value if it's not known.
+func (c *Conn) ProtocolVersion() ProtocolVersion {
+	return c.protocolVer
+}
+
+// TrySend queues the given packet to be sent without blocking. If the
+// SendQueue is full, the DropPolicy on the SendPolicy decides which packet is
+// discarded. Returns true if the given packet was queued, even if an older
+// packet had to be discarded to do so.
+func (c *Conn) TrySend(packet interface{}) bool {
+	select {
+	case c.SendQueue <- packet:
+		return true
+	default:
+	}
+
+	if c.sendPolicy != nil && c.sendPolicy.DropPolicy == DropOldest {
+		select {
+		case <-c.SendQueue:
+			atomic.AddInt64(&c.droppedCount, 1)
+		default:
+		}
+
+		select {
+		case c.SendQueue <- packet:
+			return true
+		default:
+		}
+	}
+
+	atomic.AddInt64(&c.droppedCount, 1)
+	return false
+}
+
+// QueueDepth returns the number of packets which have been queued but not
+// yet sent.
+func (c *Conn) QueueDepth() int {
+	return len(c.SendQueue) + int(atomic.LoadInt64(&c.pendingCount))
+}
+
+// SendStats returns statistics about the outgoing packets on this connection.
+// This may be called from any goroutine.
+func (c *Conn) SendStats() SendStats {
+	return SendStats{
+		Queued:    len(c.SendQueue),
+		Pending:   int(atomic.LoadInt64(&c.pendingCount)),
+		Sent:      atomic.LoadInt64(&c.sentCount),
+		Dropped:   atomic.LoadInt64(&c.droppedCount),
+		Coalesced: atomic.LoadInt64(&c.coalescedCount),
+	}
+}
+
+// manageSend reads from the SendQueue into the pending queue and hands off
+// batches to manageWrite whenever the writer is idle. This ensures the
+// SendQueue is drained promptly even if the websocket is slow.
+func (c *Conn) manageSend() {
+	writeQueue := make(chan []interface{})
+	writerDone := make(chan struct{}, 1)
+	go c.manageWrite(writeQueue, writerDone)
+
+	pending := newPendingQueue(c.sendPolicy, func() {
+		atomic.AddInt64(&c.coalescedCount, 1)
+	})
 
 outerLoop:
 	for {
+		now := time.Now()
+
+		// Batching sends can significantly improve performance
+		var outbox chan []interface{}
+		batchIndexes := pending.peek(now, 16)
+		batch := pending.packetsAt(batchIndexes)
+		if len(batch) > 0 {
+			outbox = writeQueue
+		}
+
+		var wakeChan <-chan time.Time
+		if len(batch) == 0 {
+			if wait, ok := pending.timeUntilSendable(now); ok && wait < time.Hour {
+				wakeChan = time.After(wait)
+			}
+		}
+
+		var inbox chan interface{}
+		if !pending.full() {
+			inbox = c.SendQueue
+		}
+
 		select {
-		case packet := <-c.SendQueue:
-			// Batching sends can significantly improve performance
-			packets = append(packets, packet)
+		case packet := <-inbox:
+			pending.push(packet)
 		readPacketsLoop:
-			for len(packets) < 16 {
+			for !pending.full() {
 				select {
 				case nextPacket := <-c.SendQueue:
-					packets = append(packets, nextPacket)
+					pending.push(nextPacket)
 				default:
 					break readPacketsLoop
 				}
 			}
-
-			err := c.sendPackets(packets)
-			packets = packets[:0]
-			if err != nil {
-				if !errors.Is(err, net.ErrClosed) {
-					log.Printf("error sending packets to conn %s: %v", c.UID, err)
-				}
-				c.Close()
-				break outerLoop
-			}
-		case <-pingTicker.C:
-			err := c.conn.SetWriteDeadline(time.Now().Add(utils.CONN_WRITE_TIMEOUT))
-			if err != nil {
-				log.Printf("Error setting write dealding for ping to %s: %v", c.UID, err)
-				c.Close()
-				break outerLoop
-			}
-
-			err = c.conn.WriteMessage(websocket.PingMessage, nil)
-			if err != nil {
-				if !errors.Is(err, net.ErrClosed) {
-					log.Printf("Failed to write ping to connection %s: %v", c.UID, err)
-				}
-				c.Close()
-				break outerLoop
-			}
+		case outbox <- batch:
+			pending.commit(batchIndexes, now)
+		case <-wakeChan:
+		case <-writerDone:
+			c.Close()
+			break outerLoop
 		case <-c.cancelSignal:
 			// It's nice to try and send the last couple packets here so that
 			// when using this you don't have to do this awkward thing where
@@ -156,27 +423,39 @@ outerLoop:
 			// the packets and wait "a bit". This isn't perfect since if there's
 			// too many packets in the queue it still won't get them all, but
 			// that should basically never happen
+			close(writeQueue)
+			<-writerDone
+
 		readPacketsLoop2:
-			for len(packets) < 16 {
+			for pending.len() < 16 {
 				select {
 				case nextPacket := <-c.SendQueue:
-					packets = append(packets, nextPacket)
+					pending.push(nextPacket)
 				default:
 					break readPacketsLoop2
 				}
 			}
 
-			if len(packets) > 0 {
-				err := c.sendPackets(packets)
+			now = time.Now()
+			batchIndexes = pending.peek(now, 16)
+			if len(batchIndexes) > 0 {
+				finalPackets := pending.commit(batchIndexes, now)
+				err := c.sendPackets(finalPackets)
 				if err != nil && !errors.Is(err, net.ErrClosed) {
 					log.Printf("error sending final packets to conn %s: %v", c.UID, err)
+				} else if err == nil {
+					atomic.AddInt64(&c.sentCount, int64(len(finalPackets)))
 				}
 			}
 			c.Close()
 			break outerLoop
 		}
+
+		atomic.StoreInt64(&c.pendingCount, int64(pending.len()))
 	}
 
+	atomic.StoreInt64(&c.pendingCount, int64(pending.len()))
+
 	cerr := c.conn.SetWriteDeadline(time.Now().Add(utils.CONN_WRITE_TIMEOUT))
 	if cerr != nil {
 		log.Printf("failed to set write deadline on %s for close code: %v", c.UID, cerr)
@@ -191,9 +470,61 @@ outerLoop:
 	if cerr != nil && !errors.Is(cerr, net.ErrClosed) {
 		log.Printf("failed to close connection %s on send close: %v", c.UID, cerr)
 	}
+	close(c.closedSignal)
 	c.closedQueue <- c.UID
 }
 
+// manageWrite is the only goroutine which writes to the websocket until the
+// writeQueue is closed, at which point it notifies writerDone and returns. It
+// also notifies writerDone and returns if a write fails.
+func (c *Conn) manageWrite(writeQueue chan []interface{}, writerDone chan struct{}) {
+	lowerTimeout := utils.CONN_READ_TIMEOUT
+	if utils.CONN_WRITE_TIMEOUT < lowerTimeout {
+		lowerTimeout = utils.CONN_WRITE_TIMEOUT
+	}
+
+	pingInterval := (lowerTimeout * 9) / 10
+	if c.pingInterval > 0 && c.pingInterval < pingInterval {
+		pingInterval = c.pingInterval
+	}
+	pingTicker := time.NewTicker(pingInterval)
+	defer pingTicker.Stop()
+	defer func() { writerDone <- struct{}{} }()
+
+	for {
+		select {
+		case packets, ok := <-writeQueue:
+			if !ok {
+				return
+			}
+
+			err := c.sendPackets(packets)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("error sending packets to conn %s: %v", c.UID, err)
+				}
+				return
+			}
+			atomic.AddInt64(&c.sentCount, int64(len(packets)))
+		case <-pingTicker.C:
+			err := c.conn.SetWriteDeadline(time.Now().Add(utils.CONN_WRITE_TIMEOUT))
+			if err != nil {
+				log.Printf("Error setting write dealding for ping to %s: %v", c.UID, err)
+				return
+			}
+
+			payload := strconv.FormatInt(int64(time.Since(c.createdAt)), 36)
+			err = c.conn.WriteMessage(websocket.PingMessage, []byte(payload))
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("Failed to write ping to connection %s: %v", c.UID, err)
+				}
+				return
+			}
+		}
+	}
+}
+
 func (c *Conn) sendPackets(packets []interface{}) error {
 	for _, pkt := range packets {
 		if preparablePacket, ok := pkt.(PreparablePacket); ok {
@@ -201,15 +532,9 @@ func (c *Conn) sendPackets(packets []interface{}) error {
 		}
 	}
 
-	marshalledPackets, err := json.Marshal(packets)
+	marshalledPackets, err := c.codec.EncodeFrame(packets)
 	if err != nil {
-		for _, pkt := range packets {
-			_, subErr := json.Marshal(pkt)
-			if subErr != nil {
-				return fmt.Errorf("failed to marshal packet %v: %w", pkt, err)
-			}
-		}
-		return fmt.Errorf("failed to marshal packets despite each individual packet marshalling fine! packets: %v, err: %w", packets, err)
+		return err
 	}
 
 	err = c.conn.SetWriteDeadline(time.Now().Add(utils.CONN_WRITE_TIMEOUT))
@@ -217,7 +542,7 @@ func (c *Conn) sendPackets(packets []interface{}) error {
 		return fmt.Errorf("failed to set write deadline: %w", err)
 	}
 
-	err = c.conn.WriteMessage(websocket.TextMessage, marshalledPackets)
+	err = c.conn.WriteMessage(c.codec.MessageType(), marshalledPackets)
 	if err != nil {
 		return fmt.Errorf("failed to write message: %w", err)
 	}
@@ -228,7 +553,8 @@ func (c *Conn) manageRecv() {
 	// Receive is naturally canceled promptly by manageSend
 	// closing the websocket
 
-	c.conn.SetPongHandler(func(string) error {
+	c.conn.SetPongHandler(func(payload string) error {
+		c.recordPong(payload)
 		return c.conn.SetReadDeadline(time.Now().Add(utils.CONN_READ_TIMEOUT))
 	})
 
@@ -249,39 +575,43 @@ func (c *Conn) manageRecv() {
 			break
 		}
 
-		if messageType != websocket.TextMessage {
+		if messageType != c.codec.MessageType() {
 			log.Printf("Invalid incoming message type from %s: %v", c.UID, messageType)
 			c.Close()
 			break
 		}
 
-		decoder := json.NewDecoder(bytes.NewBuffer(message))
-		decoder.UseNumber()
+		if splitter, ok := c.codec.(rawFrameSplitter); ok && c.rawMessages {
+			var packets []json.RawMessage
+			packets, err = splitter.SplitFrame(message)
+			if err != nil {
+				log.Printf("Failed to split incoming message from %s: %v", c.UID, err)
+				c.Close()
+				break
+			}
+
+			for _, packet := range packets {
+				c.recvQueue <- ReceivedMessage{
+					ConnectionUID: c.UID,
+					Raw:           packet,
+				}
+			}
+			continue
+		}
 
-		var decodedMessage interface{}
-		err = decoder.Decode(&decodedMessage)
+		var packets []map[string]interface{}
+		packets, err = c.codec.DecodeFrame(message)
 		if err != nil {
 			log.Printf("Failed to decode incoming message from %s: %v", c.UID, err)
 			c.Close()
 			break
 		}
 
-		if arr, ok := decodedMessage.([]interface{}); ok {
-			for _, packet := range arr {
-				c.recvQueue <- ReceivedMessage{
-					ConnectionUID: c.UID,
-					Message:       packet.(map[string]interface{}),
-				}
-			}
-		} else if packet, ok := decodedMessage.(map[string]interface{}); ok {
+		for _, packet := range packets {
 			c.recvQueue <- ReceivedMessage{
 				ConnectionUID: c.UID,
 				Message:       packet,
 			}
-		} else {
-			log.Printf("Unknown format for incoming message from %s: %v", c.UID, decodedMessage)
-			c.Close()
-			break
 		}
 	}
 }
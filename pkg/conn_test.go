@@ -0,0 +1,325 @@
+package pkg_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cos "github.com/calamity-of-subterfuge/cos/pkg"
+	"github.com/calamity-of-subterfuge/cos/pkg/clipkts"
+	"github.com/calamity-of-subterfuge/cos/pkg/orders"
+	"github.com/gorilla/websocket"
+)
+
+// startEchoServer starts a websocket server which writes every packet it
+// receives to the returned channel.
+func startEchoServer(t *testing.T) (string, chan map[string]interface{}) {
+	t.Helper()
+
+	received := make(chan map[string]interface{}, 1024)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrading: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var packets []map[string]interface{}
+			if err := json.Unmarshal(message, &packets); err != nil {
+				t.Errorf("server received bad message %s: %v", string(message), err)
+				return
+			}
+			for _, packet := range packets {
+				received <- packet
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), received
+}
+
+// startWritingServer starts a websocket server which writes each of the
+// given frames to every connection and then waits for it to close.
+func startWritingServer(t *testing.T, frames ...string) string {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrading: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		for _, frame := range frames {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+				return
+			}
+		}
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dialConn(t *testing.T, url string, opts *cos.ConnOptions) (*cos.Conn, chan string) {
+	t.Helper()
+
+	wsConn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dialing %s: %v", url, err)
+	}
+
+	closedQueue := make(chan string, 1)
+	conn := cos.NewConnWithOptions(wsConn, "test", make(chan cos.ReceivedMessage, 16), closedQueue, opts)
+	t.Cleanup(func() {
+		conn.Close()
+		<-closedQueue
+	})
+	return conn, closedQueue
+}
+
+func dialConnRecv(t *testing.T, url string, opts *cos.ConnOptions) chan cos.ReceivedMessage {
+	t.Helper()
+
+	wsConn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dialing %s: %v", url, err)
+	}
+
+	recvQueue := make(chan cos.ReceivedMessage, 16)
+	closedQueue := make(chan string, 1)
+	conn := cos.NewConnWithOptions(wsConn, "test", recvQueue, closedQueue, opts)
+	t.Cleanup(func() {
+		conn.Close()
+		<-closedQueue
+	})
+	return recvQueue
+}
+
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", desc)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConn_coalescesMovePackets(t *testing.T) {
+	url, received := startEchoServer(t)
+	conn, _ := dialConn(t, url, &cos.ConnOptions{
+		SendPolicy: &cos.SendPolicy{
+			RateLimits: map[string]cos.RateLimit{"move": {PerSecond: 5, Burst: 1}},
+			Coalescers: cos.ConservativeGameSendPolicy().Coalescers,
+		},
+	})
+
+	conn.SendQueue <- &clipkts.MovePacket{UID: "a", Direction: clipkts.Vector{X: 0}}
+	waitFor(t, "first move to send", func() bool { return conn.SendStats().Sent == 1 })
+
+	for i := 1; i <= 5; i++ {
+		conn.SendQueue <- &clipkts.MovePacket{UID: "a", Direction: clipkts.Vector{X: float64(i)}}
+	}
+	conn.SendQueue <- &clipkts.SendLocalMessagePacket{Text: "not rate limited"}
+
+	first := <-received
+	if first["type"] != "move" {
+		t.Fatalf("expected first packet to be a move, got %v", first)
+	}
+
+	second := <-received
+	if second["type"] != "send-local-message" {
+		t.Fatalf("expected unlimited packet to skip the rate limited move, got %v", second)
+	}
+
+	third := <-received
+	if third["type"] != "move" || third["dir"].(map[string]interface{})["x"] != 5.0 {
+		t.Fatalf("expected only the latest move to be sent, got %v", third)
+	}
+
+	stats := conn.SendStats()
+	if stats.Coalesced != 4 {
+		t.Errorf("expected 4 coalesced packets, got %d", stats.Coalesced)
+	}
+
+	select {
+	case extra := <-received:
+		t.Errorf("unexpected extra packet %v", extra)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func fillQueue(t *testing.T, conn *cos.Conn) {
+	t.Helper()
+
+	// the first packet consumes the only token ever available and the
+	// second fills the pending queue, after which the SendQueue backs up
+	conn.SendQueue <- &clipkts.MinePacket{MiningUID: "a", MinedUID: "b"}
+	waitFor(t, "first packet to send", func() bool { return conn.SendStats().Sent == 1 })
+	conn.SendQueue <- &clipkts.MinePacket{MiningUID: "a", MinedUID: "b"}
+	waitFor(t, "pending to fill", func() bool { return conn.SendStats().Pending == 1 })
+	for i := 0; i < cap(conn.SendQueue); i++ {
+		conn.SendQueue <- &clipkts.MinePacket{MiningUID: "a", MinedUID: "b"}
+	}
+
+	if depth := conn.QueueDepth(); depth != cap(conn.SendQueue)+1 {
+		t.Fatalf("expected queue depth %d, got %d", cap(conn.SendQueue)+1, depth)
+	}
+}
+
+func TestConn_TrySend_dropNewest(t *testing.T) {
+	url, _ := startEchoServer(t)
+	conn, _ := dialConn(t, url, &cos.ConnOptions{
+		SendPolicy: &cos.SendPolicy{
+			RateLimits: map[string]cos.RateLimit{"mine": {PerSecond: 0, Burst: 1}},
+			MaxPending: 1,
+			DropPolicy: cos.DropNewest,
+		},
+	})
+	fillQueue(t, conn)
+
+	if conn.TrySend(&clipkts.MinePacket{MiningUID: "a", MinedUID: "b"}) {
+		t.Errorf("expected TrySend to fail on a full queue")
+	}
+	if dropped := conn.SendStats().Dropped; dropped != 1 {
+		t.Errorf("expected 1 dropped packet, got %d", dropped)
+	}
+}
+
+func TestConn_TrySend_dropOldest(t *testing.T) {
+	url, _ := startEchoServer(t)
+	conn, _ := dialConn(t, url, &cos.ConnOptions{
+		SendPolicy: &cos.SendPolicy{
+			RateLimits: map[string]cos.RateLimit{"mine": {PerSecond: 0, Burst: 1}},
+			MaxPending: 1,
+			DropPolicy: cos.DropOldest,
+		},
+	})
+	fillQueue(t, conn)
+
+	if !conn.TrySend(&clipkts.MinePacket{MiningUID: "a", MinedUID: "b"}) {
+		t.Errorf("expected TrySend to succeed by dropping the oldest packet")
+	}
+	if dropped := conn.SendStats().Dropped; dropped != 1 {
+		t.Errorf("expected 1 dropped packet, got %d", dropped)
+	}
+}
+
+func TestConn_Send_validates(t *testing.T) {
+	url, received := startEchoServer(t)
+	conn, _ := dialConn(t, url, nil)
+
+	var nilMove *clipkts.MovePacket
+	invalid := []clipkts.Packet{
+		nil,
+		nilMove,
+		&clipkts.MovePacket{},
+		&clipkts.MovePacket{UID: "a", Direction: clipkts.Vector{X: math.NaN()}},
+		&clipkts.MinePacket{MiningUID: "a"},
+		&clipkts.SendLocalMessagePacket{Text: strings.Repeat("a", clipkts.MaxTextLength+1)},
+		&clipkts.IssueSmartObjectOrderPacket{UID: "a"},
+		&clipkts.IssueSmartObjectOrderPacket{UID: "a", Order: &orders.InitiateTradeOrder{Team: 1}},
+		&clipkts.IssueSmartObjectOrderPacket{UID: "a", Order: "initiate-trade"},
+	}
+	for idx, packet := range invalid {
+		if err := conn.Send(packet); err == nil {
+			t.Errorf("expected packet %d (%#v) to be rejected", idx, packet)
+		}
+	}
+
+	err := conn.Send(&clipkts.IssueSmartObjectOrderPacket{
+		UID:   "a",
+		Order: &orders.WithdrawTradeOrder{UID: "b"},
+	})
+	if err != nil {
+		t.Fatalf("expected valid packet to send, got %v", err)
+	}
+
+	packet := <-received
+	if packet["type"] != "issue-smart-object-order" {
+		t.Errorf("expected type to be set, got %v", packet)
+	}
+	if order := packet["order"].(map[string]interface{}); order["type"] != "withdraw-trade" {
+		t.Errorf("expected order type to be set, got %v", order)
+	}
+}
+
+func TestConn_Send_closed(t *testing.T) {
+	url, _ := startEchoServer(t)
+	conn, closedQueue := dialConn(t, url, nil)
+
+	conn.Close()
+	closedQueue <- <-closedQueue
+
+	err := conn.Send(&clipkts.SendLocalMessagePacket{Text: "hello"})
+	if !errors.Is(err, cos.ErrConnClosed) {
+		t.Errorf("expected ErrConnClosed, got %v", err)
+	}
+}
+
+func TestConn_RawMessages(t *testing.T) {
+	frames := []string{
+		`{"type": "game-object-removed", "uid": "a"}`,
+		` [{"type": "game-object-removed", "uid": "b"}, {"type": "chat-message", "text": "hi"}]`,
+	}
+
+	raw := dialConnRecv(t, startWritingServer(t, frames...), &cos.ConnOptions{RawMessages: true})
+	decoded := dialConnRecv(t, startWritingServer(t, frames...), nil)
+
+	for i := 0; i < 3; i++ {
+		rawMsg := <-raw
+		decodedMsg := <-decoded
+		if rawMsg.Message != nil || decodedMsg.Raw != nil {
+			t.Fatalf("expected only Raw to be set with RawMessages and only Message without")
+		}
+
+		var fromRaw map[string]interface{}
+		if err := json.Unmarshal(rawMsg.Raw, &fromRaw); err != nil {
+			t.Fatalf("raw message %q is not an object: %v", string(rawMsg.Raw), err)
+		}
+		if fromRaw["type"] != decodedMsg.Message["type"] || fromRaw["uid"] != decodedMsg.Message["uid"] {
+			t.Errorf("message %d differs: raw %s, decoded %v", i, string(rawMsg.Raw), decodedMsg.Message)
+		}
+	}
+}
+
+func TestConn_RTT(t *testing.T) {
+	url, _ := startEchoServer(t)
+	conn, _ := dialConn(t, url, &cos.ConnOptions{PingInterval: 5 * time.Millisecond})
+
+	if conn.RTT().Samples != 0 {
+		t.Fatalf("expected no samples before any pings")
+	}
+
+	waitFor(t, "rtt samples", func() bool { return conn.RTT().Samples >= 3 })
+
+	rtt := conn.RTT()
+	if rtt.Min <= 0 || rtt.Min > rtt.Last || rtt.Min > rtt.Smoothed || rtt.Smoothed > time.Second {
+		t.Errorf("unexpected rtt stats %+v", rtt)
+	}
+}
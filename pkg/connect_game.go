@@ -12,10 +12,26 @@ import (
 // ConnectGame will connect to the game server at the given url, authenticating
 // with the given JWT.
 func ConnectGame(url string, jwt string) (*websocket.Conn, error) {
+	return ConnectGameWithOptions(url, jwt, nil)
+}
+
+// ConnectGameWithOptions is equivalent to ConnectGame except it negotiates
+// the codecs and compression from the given options, which should then be
+// passed to NewConnWithOptions alongside the result. A nil opts is
+// equivalent to the zero value.
+func ConnectGameWithOptions(url string, jwt string, opts *ConnOptions) (*websocket.Conn, error) {
 	headers := make(http.Header)
 	headers.Add("Origin", utils.WEBSOCKET_ORIGIN)
 
-	conn, _, err := websocket.DefaultDialer.Dial(url, headers)
+	dialer := *websocket.DefaultDialer
+	if opts != nil {
+		dialer.EnableCompression = opts.EnableCompression
+		for _, codec := range opts.Codecs {
+			dialer.Subprotocols = append(dialer.Subprotocols, codec.Subprotocol())
+		}
+	}
+
+	conn, _, err := dialer.Dial(url, headers)
 	if err != nil {
 		return nil, fmt.Errorf("dialing %s: %w", url, err)
 	}
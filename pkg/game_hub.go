@@ -1,6 +1,8 @@
 package pkg
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -20,6 +22,10 @@ type GameHub struct {
 	connClosed        chan string
 	finishNotifyQueue chan string
 	cancelChan        chan struct{}
+
+	// warnedUnknownTypes contains the packet types without a parser which
+	// have already been logged, so they're only logged once per game
+	warnedUnknownTypes map[string]struct{}
 }
 
 // NewGameHub takes over management of the given game server websocket to
@@ -27,10 +33,33 @@ type GameHub struct {
 // start managing the game; that should be done in a dedicated goroutine
 // by calling the long-running function Manage()
 func NewGameHub(conn *websocket.Conn, uid string, finishNotifyQueue chan string, gameConstructor GameConstructor) *GameHub {
+	return NewGameHubWithOptions(conn, uid, finishNotifyQueue, gameConstructor.WithConn(), nil)
+}
+
+// DefaultGameConnOptions returns the options used for game server connections
+// unless otherwise specified, which use RawMessages so that packets are
+// decoded with the faster srvpkts.ParseSinglePacketJSON. There's no send
+// policy, so packets are sent as quickly as possible; set SendPolicy to
+// ConservativeGameSendPolicy to rate limit them.
+func DefaultGameConnOptions() *ConnOptions {
+	return &ConnOptions{
+		RawMessages: true,
+	}
+}
+
+// NewGameHubWithOptions is equivalent to NewGameHub except the game is
+// constructed from the managed connection rather than its send queue, and
+// the connection uses the given options. If connOptions is nil then
+// DefaultGameConnOptions is used.
+func NewGameHubWithOptions(conn *websocket.Conn, uid string, finishNotifyQueue chan string, gameConstructor ConnGameConstructor, connOptions *ConnOptions) *GameHub {
+	if connOptions == nil {
+		connOptions = DefaultGameConnOptions()
+	}
+
 	recvQueue := make(chan ReceivedMessage, 1024)
 	closedQueue := make(chan string, 1)
-	wrappedConn := NewConn(conn, uid, recvQueue, closedQueue)
-	game := gameConstructor(wrappedConn.SendQueue)
+	wrappedConn := NewConnWithOptions(conn, uid, recvQueue, closedQueue, connOptions)
+	game := gameConstructor(wrappedConn)
 
 	return &GameHub{
 		UID:               uid,
@@ -40,6 +69,8 @@ func NewGameHub(conn *websocket.Conn, uid string, finishNotifyQueue chan string,
 		connClosed:        closedQueue,
 		finishNotifyQueue: finishNotifyQueue,
 		cancelChan:        make(chan struct{}, 1),
+
+		warnedUnknownTypes: make(map[string]struct{}),
 	}
 }
 
@@ -63,9 +94,13 @@ manageLoop:
 	for {
 		select {
 		case msg := <-h.recvQueue:
-			srvPacket, err := srvpkts.ParseSinglePacket(msg.Message)
+			srvPacket, err := parseReceivedPacket(msg)
+			if errors.Is(err, srvpkts.ErrUnknownPacketType) {
+				h.warnUnknownPacket(msg)
+				break
+			}
 			if err != nil {
-				log.Printf("ignoring bad packet from server: %v (%v)", msg.Message, err)
+				log.Printf("ignoring bad packet from server: %v (%v)", receivedMessageString(msg), err)
 				break
 			}
 
@@ -104,3 +139,43 @@ manageLoop:
 	ticker.Stop()
 	h.finishNotifyQueue <- h.UID
 }
+
+// warnUnknownPacket logs that the given packet has a type without a parser,
+// but only the first time each type is seen. This is expected when the
+// server is using a newer protocol version than this library.
+func (h *GameHub) warnUnknownPacket(msg ReceivedMessage) {
+	var typ string
+	if msg.Raw != nil {
+		typ, _ = srvpkts.PeekPacketType(msg.Raw)
+	} else {
+		typ, _ = msg.Message["type"].(string)
+	}
+
+	if _, found := h.warnedUnknownTypes[typ]; found {
+		return
+	}
+	h.warnedUnknownTypes[typ] = struct{}{}
+
+	log.Printf(
+		"ignoring packets of unknown type %q from server (protocol version %s, supported %s); "+
+			"use srvpkts.RegisterPacketParser to handle them. Example: %v",
+		typ, h.conn.ProtocolVersion(), SupportedProtocolVersion, receivedMessageString(msg),
+	)
+}
+
+// parseReceivedPacket parses the server packet from the given message,
+// whether or not the connection is using RawMessages
+func parseReceivedPacket(msg ReceivedMessage) (srvpkts.Packet, error) {
+	if msg.Raw != nil {
+		return srvpkts.ParseSinglePacketJSON(msg.Raw)
+	}
+	return srvpkts.ParseSinglePacket(msg.Message)
+}
+
+// receivedMessageString formats the given message for logging
+func receivedMessageString(msg ReceivedMessage) string {
+	if msg.Raw != nil {
+		return string(msg.Raw)
+	}
+	return fmt.Sprintf("%v", msg.Message)
+}
@@ -0,0 +1,77 @@
+package pkg
+
+import "sync"
+
+// GameSlots limits how many games may be played concurrently. The same
+// GameSlots may be shared between multiple hubs, e.g., by a Supervisor, to
+// enforce a limit across all of them. GameSlots is safe to use from multiple
+// goroutines.
+type GameSlots struct {
+	mutex sync.Mutex
+	max   int
+	inUse int
+
+	// released is closed and replaced whenever a slot is released
+	released chan struct{}
+}
+
+// NewGameSlots returns GameSlots allowing at most max concurrent games. If
+// max is zero or negative there is no limit, though InUse is still tracked.
+func NewGameSlots(max int) *GameSlots {
+	return &GameSlots{max: max}
+}
+
+// TryAcquire acquires a slot for a game if one is available, returning true
+// if a slot was acquired. Every successful TryAcquire must eventually be
+// followed by a Release.
+func (s *GameSlots) TryAcquire() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.max > 0 && s.inUse >= s.max {
+		return false
+	}
+	s.inUse++
+	return true
+}
+
+// Release returns a slot acquired with TryAcquire
+func (s *GameSlots) Release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.inUse <= 0 {
+		panic("GameSlots.Release called without a matching TryAcquire")
+	}
+	s.inUse--
+	if s.released != nil {
+		close(s.released)
+		s.released = nil
+	}
+}
+
+// releasedChan returns a channel which is closed the next time a slot is
+// released
+func (s *GameSlots) releasedChan() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.released == nil {
+		s.released = make(chan struct{})
+	}
+	return s.released
+}
+
+// InUse returns how many slots are currently acquired
+func (s *GameSlots) InUse() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.inUse
+}
+
+// Max returns the maximum number of concurrent games, or zero or a negative
+// number if there is no limit
+func (s *GameSlots) Max() int {
+	return s.max
+}
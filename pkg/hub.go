@@ -1,7 +1,12 @@
 package pkg
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -32,6 +37,19 @@ func (e HubError) Error() string {
 // be sent to be forwarded to the server.
 type GameConstructor func(sendQueue chan interface{}) Game
 
+// ConnGameConstructor is an alternative to GameConstructor for games which
+// want access to the managed game connection, e.g., for TrySend or SendStats,
+// rather than just its send queue.
+type ConnGameConstructor func(conn *Conn) Game
+
+// WithConn adapts this GameConstructor to a ConnGameConstructor which
+// passes the send queue of the connection.
+func (c GameConstructor) WithConn() ConnGameConstructor {
+	return func(conn *Conn) Game {
+		return c(conn.SendQueue)
+	}
+}
+
 // Hub manages a lobby socket connection in order to detect and handle
 // game notifications by connecting to the server and then initializing
 // a game with a given GameConstructor, then managing the connection
@@ -41,11 +59,52 @@ type Hub struct {
 	lobbySocketRecvQueue  chan ReceivedMessage
 	lobbySocketClosedChan chan string
 
-	gameConstructor   GameConstructor
+	gameConstructor   ConnGameConstructor
+	gameConnOptions   *ConnOptions
 	gameHubsByUID     map[string]*GameHub
 	gameFinishedQueue chan string
 	cancelChan        chan struct{}
 	welcomeMsg        map[string]interface{}
+
+	// protocolVersionMutex guards protocolVersion, which changes if the
+	// hub is requeued
+	protocolVersionMutex sync.Mutex
+	protocolVersion      ProtocolVersion
+
+	// requeueChan receives new lobby socket registrations from Requeue,
+	// and doneChan is closed when Manage returns
+	requeueChan chan lobbyRegistration
+	doneChan    chan struct{}
+	doneOnce    sync.Once
+
+	// gameSlots, if not nil, limits the number of games which may be
+	// played concurrently and may be shared with other hubs
+	gameSlots *GameSlots
+
+	// maxConcurrentGames, if positive, limits the number of games this hub
+	// plays concurrently
+	maxConcurrentGames int
+
+	// admissionHooks may reject matches before we connect to them
+	admissionHooks []AdmissionHook
+
+	// pendingMatches are the matches refused for lack of room, oldest first,
+	// which are retried when room frees up. There are at most
+	// maxPendingMatches of them.
+	pendingMatches    []pendingMatch
+	maxPendingMatches int
+
+	// activeGames is the number of games currently being managed, and
+	// pendingCount is the number of pending matches, which are read
+	// atomically so that they can be reported from other goroutines
+	activeGames  int32
+	pendingCount int32
+}
+
+// lobbyRegistration is a lobby socket connection and its welcome message
+type lobbyRegistration struct {
+	conn       *websocket.Conn
+	welcomeMsg map[string]interface{}
 }
 
 // NewHub initializes a hub that will take over the given lobby socket
@@ -55,6 +114,23 @@ type Hub struct {
 // welcomeMsg should be the first packet received on the lobby connection
 // and is used exclusively for debugging.
 func NewHub(lobbyConn *websocket.Conn, welcomeMsg map[string]interface{}, gameConstructor GameConstructor) *Hub {
+	return NewHubWithOptions(lobbyConn, welcomeMsg, gameConstructor.WithConn(), nil)
+}
+
+// NewHubWithOptions is equivalent to NewHub except the games are constructed
+// from their connection rather than their send queue, and the game
+// connections use the given options. If gameConnOptions is nil then
+// DefaultGameConnOptions is used. The ProtocolVersion on the options is
+// replaced with the one from the welcome message.
+func NewHubWithOptions(lobbyConn *websocket.Conn, welcomeMsg map[string]interface{}, gameConstructor ConnGameConstructor, gameConnOptions *ConnOptions) *Hub {
+	protocolVersion := parseWelcomeProtocolVersion(welcomeMsg)
+
+	if gameConnOptions == nil {
+		gameConnOptions = DefaultGameConnOptions()
+	}
+	gameConnOptionsCopy := *gameConnOptions
+	gameConnOptionsCopy.ProtocolVersion = protocolVersion
+
 	recvQueue := make(chan ReceivedMessage, 64)
 	closedChan := make(chan string, 1)
 	return &Hub{
@@ -63,13 +139,104 @@ func NewHub(lobbyConn *websocket.Conn, welcomeMsg map[string]interface{}, gameCo
 		lobbySocketClosedChan: closedChan,
 
 		gameConstructor:   gameConstructor,
+		gameConnOptions:   &gameConnOptionsCopy,
 		gameHubsByUID:     make(map[string]*GameHub),
 		gameFinishedQueue: make(chan string, 16),
 		cancelChan:        make(chan struct{}, 1),
 		welcomeMsg:        welcomeMsg,
+		protocolVersion:   protocolVersion,
+		requeueChan:       make(chan lobbyRegistration),
+		doneChan:          make(chan struct{}),
+		maxPendingMatches: DefaultMaxPendingMatches,
 	}
 }
 
+// parseWelcomeProtocolVersion parses and logs the protocol version from the
+// given lobby welcome message
+func parseWelcomeProtocolVersion(welcomeMsg map[string]interface{}) ProtocolVersion {
+	protocolVersion, err := ParseProtocolVersion(welcomeMsg)
+	if err != nil && !errors.Is(err, ErrNoProtocolVersion) {
+		log.Printf("Failed to parse protocol version from welcome message %v: %v", welcomeMsg, err)
+	}
+	logProtocolVersion(protocolVersion)
+	return protocolVersion
+}
+
+// ProtocolVersion returns the protocol version the lobby reported in its
+// welcome message, or the zero value if it did not report one. This may be
+// called from any goroutine.
+func (h *Hub) ProtocolVersion() ProtocolVersion {
+	h.protocolVersionMutex.Lock()
+	defer h.protocolVersionMutex.Unlock()
+
+	return h.protocolVersion
+}
+
+// Requeue replaces the lobby socket connection with the given one, which
+// should have just been returned from QueueAI, e.g., after the AuthToken
+// used for the current connection was refreshed. Running games are not
+// affected. This may be called from any goroutine while Manage is running,
+// and returns false, closing the given connection, if Manage has returned.
+func (h *Hub) Requeue(lobbyConn *websocket.Conn, welcomeMsg map[string]interface{}) bool {
+	select {
+	case h.requeueChan <- lobbyRegistration{conn: lobbyConn, welcomeMsg: welcomeMsg}:
+		return true
+	case <-h.doneChan:
+		closeConn(websocket.CloseNormalClosure, lobbyConn)
+		return false
+	}
+}
+
+// SetGameSlots limits this hub to playing games while a slot can be acquired
+// from the given GameSlots, which may be shared with other hubs. Matches which
+// become available while no slot is available are held as pending matches
+// and retried whenever a slot is released. This must be called before
+// Manage.
+func (h *Hub) SetGameSlots(slots *GameSlots) {
+	h.gameSlots = slots
+}
+
+// SetMaxConcurrentGames limits this hub to playing at most the given number of
+// games at a time, which should typically be the MaxConcurrentInstances sent
+// with QueueAI. Zero or negative means no limit. Matches which become
+// available while at the limit are held as pending matches and retried
+// whenever a game finishes. This must be called before Manage.
+func (h *Hub) SetMaxConcurrentGames(max int) {
+	h.maxConcurrentGames = max
+}
+
+// SetMaxPendingMatches sets how many matches this hub holds onto while it
+// has no room for them, either because of SetMaxConcurrentGames or
+// SetGameSlots. Pending matches are retried in the order they became
+// available whenever room frees up, until their JWT expires, or for 30
+// seconds if the JWT doesn't say when it expires. Once the limit is reached
+// the oldest pending match is dropped. Zero or negative drops matches
+// straight away. Defaults to DefaultMaxPendingMatches. This must be called
+// before Manage.
+func (h *Hub) SetMaxPendingMatches(max int) {
+	h.maxPendingMatches = max
+}
+
+// AddAdmissionHook adds a hook which is consulted before connecting to each
+// match which is within the concurrent game limits. Hooks are consulted in
+// the order they were added. Matches rejected by a hook are dropped rather
+// than held as pending matches. This must be called before Manage.
+func (h *Hub) AddAdmissionHook(hook AdmissionHook) {
+	h.admissionHooks = append(h.admissionHooks, hook)
+}
+
+// ActiveGames returns the number of games this hub is currently managing.
+// This may be called from any goroutine.
+func (h *Hub) ActiveGames() int {
+	return int(atomic.LoadInt32(&h.activeGames))
+}
+
+// PendingMatches returns the number of matches this hub is holding onto
+// until it has room for them. This may be called from any goroutine.
+func (h *Hub) PendingMatches() int {
+	return int(atomic.LoadInt32(&h.pendingCount))
+}
+
 // Manage the hub forever or until we are disconnected from the lobby or
 // Cancel'd. This cannot be run in multiple routines simultaneously.
 func (h *Hub) Manage() error {
@@ -79,6 +246,11 @@ func (h *Hub) Manage() error {
 
 manageLoop:
 	for {
+		var slotReleased <-chan struct{}
+		if h.gameSlots != nil {
+			slotReleased = h.gameSlots.releasedChan()
+		}
+
 		select {
 		case msg := <-h.lobbySocketRecvQueue:
 			log.Printf("Notification from lobby-socket server: %v", msg.Message)
@@ -104,8 +276,12 @@ manageLoop:
 				log.Printf("Ignoring notification (unknown type: %s)", typeStr)
 			}
 		case gameUID := <-h.gameFinishedQueue:
-			log.Printf("Game finished: %s", gameUID)
-			delete(h.gameHubsByUID, gameUID)
+			h.handleGameFinished(gameUID)
+			h.retryPendingMatches()
+		case <-slotReleased:
+			h.retryPendingMatches()
+		case registration := <-h.requeueChan:
+			h.replaceLobbyConn(registration)
 		case <-h.lobbySocketClosedChan:
 			manageEndReason = ErrConnectionGoingAway
 			break manageLoop
@@ -115,6 +291,7 @@ manageLoop:
 		}
 	}
 
+	h.setPendingMatches(nil)
 	for _, gh := range h.gameHubsByUID {
 		gh.Close()
 	}
@@ -122,14 +299,48 @@ manageLoop:
 	// this avoids the game finished channel filling up and gives a chance
 	// for the game hubs to actually finish
 	for len(h.gameHubsByUID) > 0 {
-		gameUID := <-h.gameFinishedQueue
-		log.Printf("Game finished: %s", gameUID)
-		delete(h.gameHubsByUID, gameUID)
+		h.handleGameFinished(<-h.gameFinishedQueue)
 	}
 
+	h.lobbySocketConn.Close()
+	h.doneOnce.Do(func() { close(h.doneChan) })
 	return manageEndReason
 }
 
+// replaceLobbyConn switches to the given lobby socket connection, closing
+// the current one. The new connection shares the receive queue of the old
+// one, so matches the old connection received are still handled, and the
+// old connection can't block on a queue we no longer read.
+func (h *Hub) replaceLobbyConn(registration lobbyRegistration) {
+	log.Printf("Requeued; replacing lobby socket connection")
+	h.lobbySocketConn.Close()
+
+	protocolVersion := parseWelcomeProtocolVersion(registration.welcomeMsg)
+	gameConnOptionsCopy := *h.gameConnOptions
+	gameConnOptionsCopy.ProtocolVersion = protocolVersion
+
+	// the old connection writes to its own closed channel, which we no
+	// longer read
+	closedChan := make(chan string, 1)
+	h.lobbySocketConn = NewConn(registration.conn, "ls", h.lobbySocketRecvQueue, closedChan)
+	h.lobbySocketClosedChan = closedChan
+	h.gameConnOptions = &gameConnOptionsCopy
+	h.welcomeMsg = registration.welcomeMsg
+
+	h.protocolVersionMutex.Lock()
+	h.protocolVersion = protocolVersion
+	h.protocolVersionMutex.Unlock()
+}
+
+func (h *Hub) handleGameFinished(gameUID string) {
+	log.Printf("Game finished: %s", gameUID)
+	delete(h.gameHubsByUID, gameUID)
+	atomic.AddInt32(&h.activeGames, -1)
+	if h.gameSlots != nil {
+		h.gameSlots.Release()
+	}
+}
+
 func (h *Hub) handleMatchAvailable(msg ReceivedMessage) error {
 	urlRaw, found := msg.Message["url"]
 	if !found {
@@ -156,19 +367,133 @@ func (h *Hub) handleMatchAvailable(msg ReceivedMessage) error {
 		return nil
 	}
 
-	gconn, err := ConnectGame(url, jwt)
+	offer := &MatchOffer{URL: url, JWT: jwt, Message: msg.Message}
+	err := h.admit(offer)
 	if err != nil {
-		log.Printf("Failed to connect to %s: %v", url, err)
+		if isCapacityError(err) {
+			h.deferMatch(offer, err)
+		} else {
+			log.Printf("Ignoring match at %s: %v", url, err)
+		}
 		return nil
 	}
 
+	h.startMatch(offer)
+	return nil
+}
+
+// startMatch connects to the given match, which has been admitted
+func (h *Hub) startMatch(offer *MatchOffer) {
+	gconn, err := ConnectGameWithOptions(offer.URL, offer.JWT, h.gameConnOptions)
+	if err != nil {
+		log.Printf("Failed to connect to %s: %v", offer.URL, err)
+		if h.gameSlots != nil {
+			h.gameSlots.Release()
+		}
+		return
+	}
+
 	uid := generateSecureToken(23)
-	gh := NewGameHub(gconn, uid, h.gameFinishedQueue, h.gameConstructor)
+	gh := NewGameHubWithOptions(gconn, uid, h.gameFinishedQueue, h.gameConstructor, h.gameConnOptions)
 	h.gameHubsByUID[uid] = gh
+	atomic.AddInt32(&h.activeGames, 1)
 
 	go gh.Manage()
 
 	log.Printf("Assigned match the uid %s", uid)
+}
+
+// deferMatch holds onto the given match, which was refused for lack of room,
+// so that it can be retried when room frees up
+func (h *Hub) deferMatch(offer *MatchOffer, reason error) {
+	if h.maxPendingMatches <= 0 {
+		log.Printf("Ignoring match at %s: %v", offer.URL, reason)
+		return
+	}
+
+	now := time.Now()
+	expiresAt, ok := jwtExpiry(offer.JWT)
+	if !ok {
+		expiresAt = now.Add(pendingMatchTTL)
+	}
+	if !now.Before(expiresAt) {
+		log.Printf("Ignoring match at %s: %v (JWT already expired)", offer.URL, reason)
+		return
+	}
+
+	pending := h.unexpiredPendingMatches(now)
+	if len(pending) >= h.maxPendingMatches {
+		dropped := len(pending) - h.maxPendingMatches + 1
+		for _, match := range pending[:dropped] {
+			log.Printf("Dropping pending match at %s (too many pending matches)", match.offer.URL)
+		}
+		pending = pending[dropped:]
+	}
+
+	log.Printf("Holding match at %s until there's room: %v", offer.URL, reason)
+	h.setPendingMatches(append(pending, pendingMatch{offer: offer, expiresAt: expiresAt}))
+}
+
+// retryPendingMatches starts as many pending matches as there is room for,
+// in the order they became available
+func (h *Hub) retryPendingMatches() {
+	pending := h.unexpiredPendingMatches(time.Now())
+	for len(pending) > 0 {
+		match := pending[0]
+		err := h.admit(match.offer)
+		if err != nil && isCapacityError(err) {
+			break
+		}
+
+		pending = pending[1:]
+		if err != nil {
+			log.Printf("Ignoring pending match at %s: %v", match.offer.URL, err)
+			continue
+		}
+		log.Printf("Retrying pending match at %s", match.offer.URL)
+		h.startMatch(match.offer)
+	}
+	h.setPendingMatches(pending)
+}
+
+// unexpiredPendingMatches returns the pending matches which haven't expired
+// as of the given time
+func (h *Hub) unexpiredPendingMatches(now time.Time) []pendingMatch {
+	res := make([]pendingMatch, 0, len(h.pendingMatches)+1)
+	for _, match := range h.pendingMatches {
+		if now.Before(match.expiresAt) {
+			res = append(res, match)
+		} else {
+			log.Printf("Dropping pending match at %s (JWT expired)", match.offer.URL)
+		}
+	}
+	return res
+}
+
+// setPendingMatches replaces the pending matches
+func (h *Hub) setPendingMatches(pending []pendingMatch) {
+	h.pendingMatches = pending
+	atomic.StoreInt32(&h.pendingCount, int32(len(pending)))
+}
+
+// admit decides if we should play the given match, returning the reason if
+// we should not. If this returns nil and the hub has GameSlots then a slot
+// has been acquired for the match.
+func (h *Hub) admit(offer *MatchOffer) error {
+	if h.maxConcurrentGames > 0 && len(h.gameHubsByUID) >= h.maxConcurrentGames {
+		return ErrTooManyGames
+	}
+
+	for _, hook := range h.admissionHooks {
+		err := hook(offer)
+		if err != nil {
+			return fmt.Errorf("admission hook: %w", err)
+		}
+	}
+
+	if h.gameSlots != nil && !h.gameSlots.TryAcquire() {
+		return ErrNoGameSlots
+	}
 	return nil
 }
 
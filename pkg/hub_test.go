@@ -0,0 +1,277 @@
+package pkg_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cos "github.com/calamity-of-subterfuge/cos/pkg"
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+	"github.com/gorilla/websocket"
+)
+
+// startLobbyServer starts a websocket server which writes each of the given
+// frames to every connection and then writes every packet it receives to
+// the returned channel.
+func startLobbyServer(t *testing.T, frames ...string) (string, chan map[string]interface{}) {
+	t.Helper()
+
+	received := make(chan map[string]interface{}, 16)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrading: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		for _, frame := range frames {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+				return
+			}
+		}
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var packets []map[string]interface{}
+			if err := json.Unmarshal(message, &packets); err != nil {
+				t.Errorf("lobby received bad message %s: %v", string(message), err)
+				return
+			}
+			for _, packet := range packets {
+				received <- packet
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), received
+}
+
+type idleGame struct{}
+
+func (g idleGame) OnReceiveMessage(srvpkts.Packet) {}
+func (g idleGame) OnDisconnected()                 {}
+func (g idleGame) Tick(time.Duration)              {}
+
+func matchAvailable(t *testing.T, url string, extra map[string]interface{}) string {
+	msg := map[string]interface{}{"type": "match-available", "url": url, "jwt": "test-jwt"}
+	for k, v := range extra {
+		msg[k] = v
+	}
+	return string(mustMarshal(t, msg))
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	res, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshalling: %v", err)
+	}
+	return res
+}
+
+func TestHub_rejectsMatches(t *testing.T) {
+	gameURL, _ := startMirrorServer(t, websocket.Upgrader{})
+	lobbyURL, _ := startLobbyServer(t,
+		matchAvailable(t, gameURL, nil),
+		matchAvailable(t, gameURL, map[string]interface{}{"reject": true}),
+		matchAvailable(t, gameURL, nil),
+		matchAvailable(t, gameURL, nil),
+	)
+
+	lobbyConn, _, err := websocket.DefaultDialer.Dial(lobbyURL, nil)
+	if err != nil {
+		t.Fatalf("dialing lobby: %v", err)
+	}
+
+	errRejected := errors.New("rejected by hook")
+	hub := cos.NewHubWithOptions(
+		lobbyConn,
+		map[string]interface{}{"protocol_version": "1.1"},
+		func(conn *cos.Conn) cos.Game { return idleGame{} },
+		nil,
+	)
+	hub.SetMaxConcurrentGames(2)
+	var rejections int32
+	hub.AddAdmissionHook(func(offer *cos.MatchOffer) error {
+		if offer.URL != gameURL || offer.JWT != "test-jwt" {
+			t.Errorf("unexpected offer %+v", offer)
+		}
+		if offer.Message["reject"] == true {
+			atomic.AddInt32(&rejections, 1)
+			return errRejected
+		}
+		return nil
+	})
+
+	manageErr := make(chan error, 1)
+	go func() { manageErr <- hub.Manage() }()
+
+	// the rejected match is dropped, and the last match is held until
+	// there's room for it
+	waitFor(t, "games to start", func() bool { return hub.ActiveGames() == 2 && hub.PendingMatches() == 1 })
+	if atomic.LoadInt32(&rejections) != 1 {
+		t.Errorf("expected the hook to reject 1 match, got %d", rejections)
+	}
+
+	hub.Cancel()
+	if err := <-manageErr; !errors.Is(err, cos.ErrCanceled) {
+		t.Errorf("expected ErrCanceled, got %v", err)
+	}
+	if hub.ActiveGames() != 0 {
+		t.Errorf("expected games to be closed, got %d", hub.ActiveGames())
+	}
+}
+
+// expiredJWT returns a JWT whose exp claim is in the past
+func expiredJWT(t *testing.T) string {
+	payload := mustMarshal(t, map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()})
+	return "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+func TestHub_retriesPendingMatches(t *testing.T) {
+	gameURL, _ := startMirrorServer(t, websocket.Upgrader{})
+	lobbyURL, _ := startLobbyServer(t,
+		matchAvailable(t, gameURL, nil),
+		matchAvailable(t, gameURL, map[string]interface{}{"jwt": expiredJWT(t)}),
+		matchAvailable(t, gameURL, nil),
+		matchAvailable(t, gameURL, nil),
+	)
+
+	lobbyConn, _, err := websocket.DefaultDialer.Dial(lobbyURL, nil)
+	if err != nil {
+		t.Fatalf("dialing lobby: %v", err)
+	}
+
+	slots := cos.NewGameSlots(2)
+	if !slots.TryAcquire() || !slots.TryAcquire() {
+		t.Fatal("expected to acquire both slots")
+	}
+
+	hub := cos.NewHubWithOptions(lobbyConn, nil, func(conn *cos.Conn) cos.Game { return idleGame{} }, nil)
+	hub.SetGameSlots(slots)
+	hub.SetMaxPendingMatches(2)
+	manageErr := make(chan error, 1)
+	go func() { manageErr <- hub.Manage() }()
+
+	// the expired match is never held, and the first match is dropped to
+	// make room for the last
+	waitFor(t, "matches to be held", func() bool { return hub.PendingMatches() == 2 })
+	time.Sleep(50 * time.Millisecond)
+	if hub.PendingMatches() != 2 || hub.ActiveGames() != 0 {
+		t.Fatalf("expected 2 pending matches and no games, got %d and %d", hub.PendingMatches(), hub.ActiveGames())
+	}
+
+	// another hub sharing the slots finishes a game
+	slots.Release()
+	waitFor(t, "a pending match to start", func() bool { return hub.ActiveGames() == 1 && hub.PendingMatches() == 1 })
+
+	slots.Release()
+	waitFor(t, "the other pending match to start", func() bool { return hub.ActiveGames() == 2 && hub.PendingMatches() == 0 })
+
+	hub.Cancel()
+	if err := <-manageErr; !errors.Is(err, cos.ErrCanceled) {
+		t.Errorf("expected ErrCanceled, got %v", err)
+	}
+	if slots.InUse() != 0 {
+		t.Errorf("expected every slot to be released, got %d in use", slots.InUse())
+	}
+}
+
+func TestHub_Requeue_keepsReceivedMatches(t *testing.T) {
+	gameURL, _ := startMirrorServer(t, websocket.Upgrader{})
+	firstLobbyURL, _ := startLobbyServer(t,
+		matchAvailable(t, gameURL, nil),
+		matchAvailable(t, gameURL, nil),
+		matchAvailable(t, gameURL, nil),
+	)
+	secondLobbyURL, _ := startLobbyServer(t)
+
+	firstLobbyConn, _, err := websocket.DefaultDialer.Dial(firstLobbyURL, nil)
+	if err != nil {
+		t.Fatalf("dialing lobby: %v", err)
+	}
+	secondLobbyConn, _, err := websocket.DefaultDialer.Dial(secondLobbyURL, nil)
+	if err != nil {
+		t.Fatalf("dialing lobby: %v", err)
+	}
+
+	hub := cos.NewHubWithOptions(firstLobbyConn, nil, func(conn *cos.Conn) cos.Game { return idleGame{} }, nil)
+
+	// give the matches time to be received before we start handling them,
+	// so they're still queued when the lobby connection is replaced
+	time.Sleep(50 * time.Millisecond)
+	manageErr := make(chan error, 1)
+	go func() { manageErr <- hub.Manage() }()
+	if !hub.Requeue(secondLobbyConn, nil) {
+		t.Fatalf("expected requeue to succeed while managing")
+	}
+
+	waitFor(t, "every received match to start", func() bool { return hub.ActiveGames() == 3 })
+
+	hub.Cancel()
+	if err := <-manageErr; !errors.Is(err, cos.ErrCanceled) {
+		t.Errorf("expected ErrCanceled, got %v", err)
+	}
+}
+
+func TestHub_Requeue_keepsGames(t *testing.T) {
+	gameURL, _ := startMirrorServer(t, websocket.Upgrader{})
+	firstLobbyURL, _ := startLobbyServer(t, matchAvailable(t, gameURL, nil))
+	secondLobbyURL, _ := startLobbyServer(t, matchAvailable(t, gameURL, nil))
+
+	firstLobbyConn, _, err := websocket.DefaultDialer.Dial(firstLobbyURL, nil)
+	if err != nil {
+		t.Fatalf("dialing lobby: %v", err)
+	}
+
+	hub := cos.NewHubWithOptions(
+		firstLobbyConn,
+		map[string]interface{}{"protocol_version": "1.0"},
+		func(conn *cos.Conn) cos.Game { return idleGame{} },
+		nil,
+	)
+	manageErr := make(chan error, 1)
+	go func() { manageErr <- hub.Manage() }()
+
+	waitFor(t, "first game to start", func() bool { return hub.ActiveGames() == 1 })
+
+	secondLobbyConn, _, err := websocket.DefaultDialer.Dial(secondLobbyURL, nil)
+	if err != nil {
+		t.Fatalf("dialing lobby: %v", err)
+	}
+	if !hub.Requeue(secondLobbyConn, map[string]interface{}{"protocol_version": "1.1"}) {
+		t.Fatalf("expected requeue to succeed while managing")
+	}
+
+	waitFor(t, "second game to start", func() bool { return hub.ActiveGames() == 2 })
+	if version := hub.ProtocolVersion(); version != (cos.ProtocolVersion{Major: 1, Minor: 1}) {
+		t.Errorf("expected protocol version from the new welcome message, got %v", version)
+	}
+
+	hub.Cancel()
+	if err := <-manageErr; !errors.Is(err, cos.ErrCanceled) {
+		t.Errorf("expected ErrCanceled, got %v", err)
+	}
+
+	thirdLobbyConn, _, err := websocket.DefaultDialer.Dial(secondLobbyURL, nil)
+	if err != nil {
+		t.Fatalf("dialing lobby: %v", err)
+	}
+	if hub.Requeue(thirdLobbyConn, nil) {
+		t.Errorf("expected requeue to fail after Manage returned")
+	}
+}
@@ -90,3 +90,41 @@ func Login(email, grantIden, secret string) (*AuthToken, error) {
 		ExpiresAt: utils.TimeFromUnix(parsedBody.ExpiresAt),
 	}, nil
 }
+
+// Logout tries to invalidate the given token, which should not be used
+// afterward. The API doesn't document a way to logout, so this assumes
+// DELETE on the same endpoint as Login and is best-effort: an error doesn't
+// mean the token is still usable, and tokens expire regardless. Nothing in
+// this library logs out unless asked to, e.g., with AuthManagerOptions.
+func Logout(auth *AuthToken) error {
+	client := &http.Client{
+		Transport: &http2.Transport{},
+	}
+	req, err := http.NewRequest("DELETE", utils.API_BASE+"/api/1/auth/sessions", nil)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request: %w", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("bearer %s", auth.Token))
+
+	var resp *http.Response
+	resp, err = client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to DELETE: %w", err)
+	}
+
+	var respBody []byte
+	respBody, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+
+	err = resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close body: %w", err)
+	}
+
+	if resp.StatusCode != 200 && resp.StatusCode != 204 {
+		return fmt.Errorf("unexpected status code: %d (body: %s)", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
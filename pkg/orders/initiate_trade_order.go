@@ -36,8 +36,15 @@ func (o *InitiateTradeOrder) PrepareForMarshal() {
 	o.Type = o.GetType()
 }
 
+func (o *InitiateTradeOrder) validate() error {
+	if len(o.Offer) == 0 && len(o.Request) == 0 {
+		return errors.New("cannot initiate a trade with neither an offer nor a request")
+	}
+	return nil
+}
+
 func init() {
-	registerOrderParser("initiate-trade", func(m map[string]interface{}) (Order, error) {
+	RegisterOrderParser("initiate-trade", func(m map[string]interface{}) (Order, error) {
 		var res InitiateTradeOrder
 		_, err := parseSingleOrderOfType(m, &res)
 		if err != nil {
@@ -52,8 +59,8 @@ func init() {
 			res.Request = make(map[string]int)
 		}
 
-		if len(res.Offer) == 0 && len(res.Request) == 0 {
-			return nil, errors.New("cannot initiate a trade with neither an offer nor a request")
+		if err = res.validate(); err != nil {
+			return nil, err
 		}
 
 		return &res, nil
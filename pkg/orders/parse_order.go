@@ -3,18 +3,47 @@ package orders
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/calamity-of-subterfuge/cos/pkg/utils"
 )
 
-type orderParser func(map[string]interface{}) (Order, error)
+// OrderParser parses a single order of a particular type from its map
+// representation.
+type OrderParser func(map[string]interface{}) (Order, error)
 
-var orderParsersByType map[string]orderParser = make(map[string]orderParser)
+// ErrUnknownOrderType is wrapped by the error from ParseOrder when there is
+// no parser registered for the type of the order.
+var ErrUnknownOrderType = errors.New("unknown order type")
 
-func registerOrderParser(typ string, parser orderParser) {
+var orderParsersLock sync.RWMutex
+var orderParsersByType map[string]OrderParser = make(map[string]OrderParser)
+
+// RegisterOrderParser registers the parser to use for orders of the given
+// type, replacing the existing parser for that type if there is one. This
+// allows applications to use orders which this library does not know about
+// yet without forking it. This is typically called from init().
+func RegisterOrderParser(typ string, parser OrderParser) {
+	orderParsersLock.Lock()
+	defer orderParsersLock.Unlock()
 	orderParsersByType[typ] = parser
 }
 
+// KnownOrderTypes returns the order types which have a registered parser, in
+// sorted order.
+func KnownOrderTypes() []string {
+	orderParsersLock.RLock()
+	defer orderParsersLock.RUnlock()
+
+	res := make([]string, 0, len(orderParsersByType))
+	for typ := range orderParsersByType {
+		res = append(res, typ)
+	}
+	sort.Strings(res)
+	return res
+}
+
 // ParseOrder parses a single order from its map representation within a packet.
 // This typically comes from the clipkts.IssueSmartObjectOrderPacket#Order
 func ParseOrder(parsed map[string]interface{}) (Order, error) {
@@ -28,10 +57,11 @@ func ParseOrder(parsed map[string]interface{}) (Order, error) {
 		return nil, errors.New("order has type but it's not a string")
 	}
 
-	var parser orderParser
-	parser, found = orderParsersByType[orderType]
+	orderParsersLock.RLock()
+	parser, found := orderParsersByType[orderType]
+	orderParsersLock.RUnlock()
 	if !found {
-		return nil, fmt.Errorf("unknown order type: %s", orderType)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownOrderType, orderType)
 	}
 
 	return parser(parsed)
@@ -25,16 +25,23 @@ func (o *RespondTradeOrder) PrepareForMarshal() {
 	o.Type = o.GetType()
 }
 
+func (o *RespondTradeOrder) validate() error {
+	if o.UID == "" {
+		return errors.New("missing UID")
+	}
+	return nil
+}
+
 func init() {
-	registerOrderParser("respond-trade", func(m map[string]interface{}) (Order, error) {
+	RegisterOrderParser("respond-trade", func(m map[string]interface{}) (Order, error) {
 		var res RespondTradeOrder
 		_, err := parseSingleOrderOfType(m, &res)
 		if err != nil {
 			return nil, err
 		}
 
-		if res.UID == "" {
-			return nil, errors.New("missing UID")
+		if err = res.validate(); err != nil {
+			return nil, err
 		}
 
 		return &res, err
@@ -0,0 +1,31 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// validatableOrder is implemented by orders which have rules beyond what can
+// be described by their types.
+type validatableOrder interface {
+	validate() error
+}
+
+// ValidateOrder checks that the given order follows the same rules that
+// ParseOrder enforces. The order must be a non-nil pointer to one of the
+// orders in this package.
+func ValidateOrder(order Order) error {
+	if order == nil {
+		return errors.New("order is nil")
+	}
+
+	if v := reflect.ValueOf(order); v.Kind() == reflect.Ptr && v.IsNil() {
+		return fmt.Errorf("order is a nil %T", order)
+	}
+
+	if validatable, ok := order.(validatableOrder); ok {
+		return validatable.validate()
+	}
+	return nil
+}
@@ -22,16 +22,23 @@ func (o *WithdrawTradeOrder) PrepareForMarshal() {
 	o.Type = o.GetType()
 }
 
+func (o *WithdrawTradeOrder) validate() error {
+	if o.UID == "" {
+		return errors.New("missing UID")
+	}
+	return nil
+}
+
 func init() {
-	registerOrderParser("withdraw-trade", func(m map[string]interface{}) (Order, error) {
+	RegisterOrderParser("withdraw-trade", func(m map[string]interface{}) (Order, error) {
 		var res WithdrawTradeOrder
 		_, err := parseSingleOrderOfType(m, &res)
 		if err != nil {
 			return nil, err
 		}
 
-		if res.UID == "" {
-			return nil, errors.New("missing UID")
+		if err = res.validate(); err != nil {
+			return nil, err
 		}
 
 		return &res, nil
@@ -2,8 +2,10 @@ package pkg
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"math"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -21,8 +23,50 @@ type Config struct {
 	// Secret is the secret that allows the use of the grant
 	Secret string
 
+	// Auth, if not nil, manages the token for the account instead of Email,
+	// GrantIden and Secret, which allows sharing it between several
+	// configs. It is started but not closed by the Play loop. If nil, an
+	// AuthManager is created which is closed when the loop stops, leaving
+	// its token to expire.
+	Auth *AuthManager
+
 	// AIConfig is the configuration for the AI
 	AIConfig *AIConfig
+
+	// GameConnOptions are the options for connections to game servers. If
+	// nil, DefaultGameConnOptions is used.
+	GameConnOptions *ConnOptions
+
+	// AdmissionHooks may reject matches before connecting to them, in
+	// addition to the MaxConcurrentInstances on the AIConfig which is
+	// always enforced.
+	AdmissionHooks []AdmissionHook
+}
+
+// redactedSecret replaces secrets in String output
+const redactedSecret = "[redacted]"
+
+// redact returns redactedSecret unless the secret is empty, so it's still
+// clear when a secret is missing
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedSecret
+}
+
+// String formats the config with the secret redacted, so that configs can be
+// logged safely
+func (c Config) String() string {
+	return fmt.Sprintf(
+		"{Email:%s GrantIden:%s Secret:%s Auth:%v AIConfig:%+v GameConnOptions:%p AdmissionHooks:%d}",
+		c.Email, c.GrantIden, redact(c.Secret), c.Auth, c.AIConfig, c.GameConnOptions, len(c.AdmissionHooks),
+	)
+}
+
+// GoString is equivalent to String, so the secret is also redacted with %#v
+func (c Config) GoString() string {
+	return c.String()
 }
 
 // Play is an optional function to take over the majority of the boilerplate
@@ -34,51 +78,262 @@ type Config struct {
 // Essentially, this goes through all the boilerplate prior to having a Game
 // initialized.
 func Play(cfg *Config, gameConstructor GameConstructor) {
+	PlayWithConn(cfg, gameConstructor.WithConn())
+}
+
+// PlayWithConn is equivalent to Play except games are constructed from their
+// managed connection rather than their send queue.
+func PlayWithConn(cfg *Config, gameConstructor ConnGameConstructor) {
+	newPlayer(cfg, gameConstructor, nil).run()
+}
+
+// player runs the login, queue and hub loop for a single Config until it
+// is stopped, keeping track of its status.
+type player struct {
+	cfg             *Config
+	gameConstructor ConnGameConstructor
+	gameSlots       *GameSlots
+
+	// stopChan is closed when the player should stop
+	stopChan chan struct{}
+
+	mutex        sync.Mutex
+	stopped      bool
+	hub          *Hub
+	state        PersonalityState
+	stateSince   time.Time
+	lastError    error
+	lastErrorAt  time.Time
+	gamesStarted int64
+}
+
+func newPlayer(cfg *Config, gameConstructor ConnGameConstructor, gameSlots *GameSlots) *player {
+	return &player{
+		cfg:             cfg,
+		gameConstructor: gameConstructor,
+		gameSlots:       gameSlots,
+		stopChan:        make(chan struct{}),
+		state:           PersonalityStopped,
+		stateSince:      time.Now(),
+	}
+}
+
+// run the play loop until stop is called
+func (p *player) run() {
+	defer p.setState(PersonalityStopped)
+
+	gameConstructor := func(conn *Conn) Game {
+		p.mutex.Lock()
+		p.gamesStarted++
+		p.mutex.Unlock()
+		return p.gameConstructor(conn)
+	}
+
+	authManager := p.cfg.Auth
+	if authManager == nil {
+		authManager = NewAuthManager(p.cfg.Email, p.cfg.GrantIden, p.cfg.Secret)
+		defer authManager.Close()
+	}
+	authManager.Start()
+
+	// subscribe before the first login so no refresh can be missed
+	refreshed := authManager.Subscribe()
+	defer authManager.Unsubscribe(refreshed)
+
+	relogin := false
 	for {
+		select {
+		case <-p.stopChan:
+			return
+		default:
+		}
+
+		p.setState(PersonalityLoggingIn)
 		log.Println("Logging in...")
 		var auth *AuthToken
 		var err error
 		retryCounter := 0
 		for {
-			auth, err = Login(cfg.Email, cfg.GrantIden, cfg.Secret)
+			if relogin {
+				auth, err = authManager.Refresh()
+			} else {
+				auth, err = authManager.Token()
+			}
 			if err == nil {
+				relogin = false
 				break
 			}
 
+			p.recordError(err)
 			sleepSeconds := int64(math.Pow(2, float64(retryCounter))) * 60
 			log.Printf("Error logging in, retrying in %d seconds: %v", sleepSeconds, err)
-			time.Sleep(time.Duration(sleepSeconds) * time.Second)
+			if !p.sleep(time.Duration(sleepSeconds) * time.Second) {
+				return
+			}
 			if retryCounter < 4 {
 				retryCounter++
 			}
 		}
 		log.Println("Successfully logged in; connecting to lobby socket server...")
 
+		p.setState(PersonalityQueueing)
 		var socketConn *websocket.Conn
 		var welcomeMessage map[string]interface{}
 		retryCounter = 0
-		socketConn, welcomeMessage, err = QueueAI(cfg.AIConfig, auth)
+		socketConn, welcomeMessage, err = QueueAI(p.cfg.AIConfig, auth)
 		for err != nil && retryCounter < 5 {
+			p.recordError(err)
 			sleepSeconds := int64(math.Pow(2, float64(retryCounter))) * 60
 			log.Printf("Failed to queue AI, retrying in %d seconds: %v", sleepSeconds, err)
-			time.Sleep(time.Duration(sleepSeconds) * time.Second)
+			if !p.sleep(time.Duration(sleepSeconds) * time.Second) {
+				return
+			}
 			retryCounter = retryCounter + 1
-			socketConn, welcomeMessage, err = QueueAI(cfg.AIConfig, auth)
+			socketConn, welcomeMessage, err = QueueAI(p.cfg.AIConfig, auth)
 		}
 		if retryCounter == 5 {
 			log.Println("Too many failures to queue ai in a row; relogging in")
+			relogin = true
 			continue
 		}
 
-		hub := NewHub(socketConn, welcomeMessage, gameConstructor)
+		hub := NewHubWithOptions(socketConn, welcomeMessage, gameConstructor, p.cfg.GameConnOptions)
+		hub.SetMaxConcurrentGames(p.cfg.AIConfig.MaxConcurrentInstances)
+		for _, hook := range p.cfg.AdmissionHooks {
+			hub.AddAdmissionHook(hook)
+		}
+		if p.gameSlots != nil {
+			hub.SetGameSlots(p.gameSlots)
+		}
+		if !p.setHub(hub) {
+			closeConn(websocket.CloseNormalClosure, socketConn)
+			return
+		}
+
+		p.setState(PersonalityQueued)
+		requeueDone := make(chan struct{})
+		go p.requeueOnRefresh(hub, auth, refreshed, requeueDone)
 		err = hub.Manage()
+		close(requeueDone)
+		p.setHub(nil)
 		if err != nil {
 			if errors.Is(err, ErrCanceled) {
 				break
 			} else {
+				p.recordError(err)
 				log.Printf("Error while managing the hub, relogging in in 5 seconds: %v", err)
 			}
 		}
-		time.Sleep(5 * time.Second)
+		if !p.sleep(5 * time.Second) {
+			return
+		}
+	}
+}
+
+// requeueOnRefresh registers the AI again whenever the token is refreshed,
+// replacing the lobby socket connection of the given hub without affecting
+// its games, until done is closed
+func (p *player) requeueOnRefresh(hub *Hub, queuedWith *AuthToken, refreshed chan *AuthToken, done chan struct{}) {
+	for {
+		select {
+		case token := <-refreshed:
+			if token == queuedWith {
+				break
+			}
+
+			socketConn, welcomeMessage, err := QueueAI(p.cfg.AIConfig, token)
+			if err != nil {
+				// the current lobby connection is still usable until the
+				// old token expires, at which point the hub fails and we
+				// relogin
+				p.recordError(err)
+				log.Printf("Failed to requeue AI after refreshing token: %v", err)
+				break
+			}
+
+			if !hub.Requeue(socketConn, welcomeMessage) {
+				return
+			}
+			queuedWith = token
+		case <-done:
+			return
+		}
+	}
+}
+
+// stop the player, canceling its hub if it has one. This may be called
+// from any goroutine and more than once.
+func (p *player) stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.stopped {
+		return
+	}
+	p.stopped = true
+	close(p.stopChan)
+	if p.hub != nil {
+		p.hub.Cancel()
+	}
+}
+
+// sleep for the given duration, returning false if the player was stopped
+// before then
+func (p *player) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-p.stopChan:
+		return false
+	}
+}
+
+// setHub sets the hub which is currently being managed, returning false if
+// the player was stopped and hence the hub should not be managed
+func (p *player) setHub(hub *Hub) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if hub != nil && p.stopped {
+		return false
+	}
+	p.hub = hub
+	return true
+}
+
+func (p *player) setState(state PersonalityState) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.state = state
+	p.stateSince = time.Now()
+}
+
+func (p *player) recordError(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.lastError = err
+	p.lastErrorAt = time.Now()
+}
+
+// status returns the current status of the player
+func (p *player) status() PersonalityStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	res := PersonalityStatus{
+		State:        p.state,
+		StateSince:   p.stateSince,
+		GamesStarted: p.gamesStarted,
+		LastError:    p.lastError,
+		LastErrorAt:  p.lastErrorAt,
+	}
+	if p.hub != nil {
+		res.ActiveGames = p.hub.ActiveGames()
 	}
+	return res
 }
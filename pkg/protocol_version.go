@@ -0,0 +1,127 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+)
+
+// ProtocolVersion describes the version of the calamity of subterfuge
+// protocol. Servers with a newer minor version may send fields or packets
+// which this library does not know about, which are ignored. Servers with a
+// different major version may not be compatible at all.
+type ProtocolVersion struct {
+	// Major version, which changes when the protocol changes incompatibly
+	Major int
+
+	// Minor version, which changes when fields or packets are added
+	Minor int
+}
+
+// SupportedProtocolVersion is the protocol version this library was written
+// against.
+var SupportedProtocolVersion = ProtocolVersion{Major: 1, Minor: 0}
+
+// ErrNoProtocolVersion is returned from ParseProtocolVersion if the welcome
+// message does not include a protocol version.
+var ErrNoProtocolVersion = errors.New("welcome message has no protocol version")
+
+// String formats the version as major.minor
+func (v ProtocolVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// IsZero returns true if this is the zero value, which is used when the
+// protocol version is not known.
+func (v ProtocolVersion) IsZero() bool {
+	return v.Major == 0 && v.Minor == 0
+}
+
+// Compare returns -1 if this version is older than the other version, 0 if
+// they are the same, and 1 if this version is newer.
+func (v ProtocolVersion) Compare(other ProtocolVersion) int {
+	switch {
+	case v.Major != other.Major:
+		if v.Major < other.Major {
+			return -1
+		}
+		return 1
+	case v.Minor != other.Minor:
+		if v.Minor < other.Minor {
+			return -1
+		}
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ParseProtocolVersion parses the protocol version from the welcome message
+// of the lobby socket, which is returned from QueueAI. No current server
+// reports its version, so this expects the version to be a string like "1.2"
+// under protocol_version, and returns ErrNoProtocolVersion if it's missing.
+// Numbers are rejected since they can't tell 1.1 apart from 1.10.
+func ParseProtocolVersion(welcomeMsg map[string]interface{}) (ProtocolVersion, error) {
+	raw, found := welcomeMsg["protocol_version"]
+	if !found || raw == nil {
+		return ProtocolVersion{}, ErrNoProtocolVersion
+	}
+
+	str, ok := raw.(string)
+	if !ok {
+		return ProtocolVersion{}, fmt.Errorf("protocol_version should be a string, got %T", raw)
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(str, "v"), ".", 3)
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return ProtocolVersion{}, fmt.Errorf("parsing major version of %q: %w", str, err)
+	}
+
+	var minor int
+	if len(parts) > 1 {
+		minor, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return ProtocolVersion{}, fmt.Errorf("parsing minor version of %q: %w", str, err)
+		}
+	}
+
+	return ProtocolVersion{Major: major, Minor: minor}, nil
+}
+
+// missingProtocolVersionOnce ensures we only mention once that the server
+// did not report a protocol version, since no current server does
+var missingProtocolVersionOnce sync.Once
+
+// logProtocolVersion logs a warning if the given server protocol version
+// might not be fully supported by this library.
+func logProtocolVersion(version ProtocolVersion) {
+	if version.IsZero() {
+		missingProtocolVersionOnce.Do(func() {
+			log.Printf("DEBUG: server did not report a protocol version; assuming %s", SupportedProtocolVersion)
+		})
+		return
+	}
+
+	switch {
+	case version.Major != SupportedProtocolVersion.Major:
+		log.Printf(
+			"WARN: server protocol version %s is incompatible with the supported version %s; "+
+				"update this library",
+			version, SupportedProtocolVersion,
+		)
+	case version.Compare(SupportedProtocolVersion) > 0:
+		log.Printf(
+			"Server protocol version %s is newer than the supported version %s; unknown fields "+
+				"are ignored and unknown packets are logged. Known packet types: %s",
+			version, SupportedProtocolVersion, strings.Join(srvpkts.KnownPacketTypes(), ", "),
+		)
+	default:
+		log.Printf("Server protocol version %s", version)
+	}
+}
@@ -0,0 +1,62 @@
+package pkg_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	cos "github.com/calamity-of-subterfuge/cos/pkg"
+)
+
+func TestParseProtocolVersion(t *testing.T) {
+	cases := []struct {
+		raw      interface{}
+		expected cos.ProtocolVersion
+	}{
+		{"1.2", cos.ProtocolVersion{Major: 1, Minor: 2}},
+		{"v2.0.7", cos.ProtocolVersion{Major: 2, Minor: 0}},
+		{"3", cos.ProtocolVersion{Major: 3}},
+		{"1.10", cos.ProtocolVersion{Major: 1, Minor: 10}},
+	}
+
+	for _, c := range cases {
+		got, err := cos.ParseProtocolVersion(map[string]interface{}{"protocol_version": c.raw})
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", c.raw, err)
+			continue
+		}
+		if got != c.expected {
+			t.Errorf("%v: expected %v, got %v", c.raw, c.expected, got)
+		}
+	}
+
+	_, err := cos.ParseProtocolVersion(map[string]interface{}{"type": "welcome"})
+	if !errors.Is(err, cos.ErrNoProtocolVersion) {
+		t.Errorf("expected ErrNoProtocolVersion, got %v", err)
+	}
+
+	_, err = cos.ParseProtocolVersion(map[string]interface{}{"protocol_version": "one.two"})
+	if err == nil {
+		t.Errorf("expected an error for a malformed version")
+	}
+
+	for _, raw := range []interface{}{1.1, json.Number("1.10")} {
+		_, err = cos.ParseProtocolVersion(map[string]interface{}{"protocol_version": raw})
+		if err == nil || errors.Is(err, cos.ErrNoProtocolVersion) {
+			t.Errorf("%v: expected an error for a numeric version, got %v", raw, err)
+		}
+	}
+}
+
+func TestProtocolVersion_Compare(t *testing.T) {
+	older := cos.ProtocolVersion{Major: 1, Minor: 2}
+	newer := cos.ProtocolVersion{Major: 1, Minor: 10}
+	major := cos.ProtocolVersion{Major: 2}
+
+	if older.Compare(newer) != -1 || newer.Compare(older) != 1 || older.Compare(older) != 0 {
+		t.Errorf("minor versions compared incorrectly")
+	}
+	if newer.Compare(major) != -1 || major.Compare(newer) != 1 {
+		t.Errorf("major versions compared incorrectly")
+	}
+}
@@ -35,7 +35,9 @@ type AIConfig struct {
 	ClientAllowList []string
 
 	// MaxConcurrentInstances is the maximum number of games which can
-	// be played simultaneously by this machine.
+	// be played simultaneously by this machine. Play also enforces this
+	// locally, holding matches beyond the limit and retrying them once a
+	// game finishes.
 	MaxConcurrentInstances int
 }
 
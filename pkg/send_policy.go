@@ -0,0 +1,346 @@
+package pkg
+
+import (
+	"math"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/clipkts"
+)
+
+// DropPolicy is an enum describing which packet is discarded when TrySend
+// finds the SendQueue of a Conn full.
+type DropPolicy int
+
+const (
+	// DropNewest discards the packet which is being added, keeping everything
+	// which was already queued.
+	DropNewest DropPolicy = 0
+
+	// DropOldest discards the oldest packet in the SendQueue in order to make
+	// room for the packet being added.
+	DropOldest DropPolicy = 1
+)
+
+// RateLimit describes a token bucket limiting how quickly packets can be sent.
+type RateLimit struct {
+	// PerSecond is the sustained number of packets which may be sent per
+	// second.
+	PerSecond float64
+
+	// Burst is the number of packets which can be sent at once after not
+	// sending for a while. Values less than 1 are treated as 1.
+	Burst int
+}
+
+// PacketCoalescer determines if a packet supersedes older packets of the same
+// type. Packets of the same type which produce the same key supersede each
+// other, so that only the most recent one which has not yet been sent is
+// actually sent. If the second result is false the packet is never coalesced.
+type PacketCoalescer func(packet interface{}) (string, bool)
+
+// SendPolicy describes how a Conn should handle outgoing packets when they
+// are produced faster than they can or should be sent. Packets are identified
+// by the result of their GetType function; packets without a GetType function
+// are never rate limited or coalesced.
+type SendPolicy struct {
+	// RateLimits maps from packet types to the rate limit for that packet
+	// type. Packets of a type without a rate limit are sent as quickly as
+	// possible. Packets of the same type are always sent in order, but a
+	// rate limited packet may be sent after packets of other types which
+	// were queued after it.
+	RateLimits map[string]RateLimit
+
+	// Coalescers maps from packet types to the coalescer for that packet
+	// type.
+	Coalescers map[string]PacketCoalescer
+
+	// MaxPending is the maximum number of packets which have been read from
+	// the SendQueue but not yet sent. Once this is reached the Conn stops
+	// reading from the SendQueue until some are sent, so writes to the
+	// SendQueue will eventually block. Values less than 1 mean 1024.
+	MaxPending int
+
+	// DropPolicy decides which packet is discarded when TrySend finds the
+	// SendQueue full.
+	DropPolicy DropPolicy
+}
+
+// ConservativeGameSendPolicy returns a send policy for game connections
+// which avoids flooding the server. Only the latest MovePacket for each UID
+// is sent, movement is limited to 30 packets per second, and chat and
+// commands are limited to 1 message per second with a burst of 3. These
+// limits are intentionally conservative; they are not published limits from
+// the server, so they may delay messages the server would have accepted.
+// Game connections don't use a send policy unless given one, e.g., via
+// ConnOptions.SendPolicy.
+func ConservativeGameSendPolicy() *SendPolicy {
+	return &SendPolicy{
+		RateLimits: map[string]RateLimit{
+			"move":               {PerSecond: 30, Burst: 5},
+			"send-local-message": {PerSecond: 1, Burst: 3},
+			"send-command":       {PerSecond: 1, Burst: 3},
+		},
+		Coalescers: map[string]PacketCoalescer{
+			"move": coalesceMovePacket,
+		},
+		MaxPending: 1024,
+		DropPolicy: DropOldest,
+	}
+}
+
+func coalesceMovePacket(packet interface{}) (string, bool) {
+	movePacket, ok := packet.(*clipkts.MovePacket)
+	if !ok {
+		return "", false
+	}
+	return movePacket.UID, true
+}
+
+// typedPacket is implemented by every packet in clipkts and srvpkts
+type typedPacket interface {
+	GetType() string
+}
+
+func packetType(packet interface{}) string {
+	if typed, ok := packet.(typedPacket); ok {
+		return typed.GetType()
+	}
+	return ""
+}
+
+// tokenBucket implements a RateLimit
+type tokenBucket struct {
+	perSecond float64
+	burst     float64
+	tokens    float64
+	updatedAt time.Time
+}
+
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		perSecond: limit.PerSecond,
+		burst:     burst,
+		tokens:    burst,
+		updatedAt: now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.perSecond)
+		b.updatedAt = now
+	}
+}
+
+// available returns how many packets could be sent right now
+func (b *tokenBucket) available(now time.Time) int {
+	b.refill(now)
+	return int(b.tokens)
+}
+
+// take consumes the given number of tokens, which must be available
+func (b *tokenBucket) take(n int) {
+	b.tokens -= float64(n)
+}
+
+// timeUntilAvailable returns how long until at least one packet can be sent
+func (b *tokenBucket) timeUntilAvailable(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	if b.perSecond <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration((1 - b.tokens) / b.perSecond * float64(time.Second))
+}
+
+type pendingPacket struct {
+	packet interface{}
+	typ    string
+
+	// coalesceKey is blank if the packet cannot be coalesced, otherwise
+	// it's the type and the key from the coalescer
+	coalesceKey string
+}
+
+// pendingQueue contains the packets which have been read from the SendQueue
+// but not yet sent. It's only accessed from the manageSend goroutine.
+type pendingQueue struct {
+	policy  *SendPolicy
+	packets []pendingPacket
+	buckets map[string]*tokenBucket
+
+	// coalesced is called whenever a pending packet is replaced
+	coalesced func()
+}
+
+func newPendingQueue(policy *SendPolicy, coalesced func()) *pendingQueue {
+	return &pendingQueue{
+		policy:    policy,
+		packets:   make([]pendingPacket, 0, 16),
+		buckets:   make(map[string]*tokenBucket),
+		coalesced: coalesced,
+	}
+}
+
+func (q *pendingQueue) maxPending() int {
+	if q.policy == nil || q.policy.MaxPending < 1 {
+		return 1024
+	}
+	return q.policy.MaxPending
+}
+
+// push adds the given packet to the end of the queue, or replaces an older
+// packet which it supersedes.
+func (q *pendingQueue) push(packet interface{}) {
+	pending := pendingPacket{packet: packet, typ: packetType(packet)}
+
+	if q.policy != nil && q.policy.Coalescers != nil {
+		if coalescer, found := q.policy.Coalescers[pending.typ]; found {
+			if key, ok := coalescer(packet); ok {
+				pending.coalesceKey = pending.typ + "\x00" + key
+
+				for idx := range q.packets {
+					if q.packets[idx].coalesceKey == pending.coalesceKey {
+						q.packets[idx].packet = packet
+						q.coalesced()
+						return
+					}
+				}
+			}
+		}
+	}
+
+	q.packets = append(q.packets, pending)
+}
+
+// full returns true if no more packets should be pushed until some are sent
+func (q *pendingQueue) full() bool {
+	return len(q.packets) >= q.maxPending()
+}
+
+func (q *pendingQueue) bucket(typ string, now time.Time) *tokenBucket {
+	if q.policy == nil || q.policy.RateLimits == nil {
+		return nil
+	}
+
+	limit, found := q.policy.RateLimits[typ]
+	if !found {
+		return nil
+	}
+
+	bucket, found := q.buckets[typ]
+	if !found {
+		bucket = newTokenBucket(limit, now)
+		q.buckets[typ] = bucket
+	}
+	return bucket
+}
+
+// peek returns the indexes of up to max packets which may be sent right now
+// without actually removing them. This respects the rate limits and ensures
+// packets of the same type stay in order.
+func (q *pendingQueue) peek(now time.Time, max int) []int {
+	var allowance map[string]int
+	res := make([]int, 0, max)
+
+	for idx, pending := range q.packets {
+		if len(res) >= max {
+			break
+		}
+
+		bucket := q.bucket(pending.typ, now)
+		if bucket == nil {
+			res = append(res, idx)
+			continue
+		}
+
+		if allowance == nil {
+			allowance = make(map[string]int)
+		}
+		remaining, found := allowance[pending.typ]
+		if !found {
+			remaining = bucket.available(now)
+		}
+		if remaining > 0 {
+			res = append(res, idx)
+		}
+		// once one packet of a type is blocked all later ones are too
+		allowance[pending.typ] = remaining - 1
+	}
+
+	return res
+}
+
+// packetsAt returns the packets at the given indexes, typically from peek
+func (q *pendingQueue) packetsAt(indexes []int) []interface{} {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	res := make([]interface{}, len(indexes))
+	for i, idx := range indexes {
+		res[i] = q.packets[idx].packet
+	}
+	return res
+}
+
+// commit removes the packets at the given indexes, which must have come from
+// peek without any intervening changes, and returns them in order.
+func (q *pendingQueue) commit(indexes []int, now time.Time) []interface{} {
+	res := make([]interface{}, len(indexes))
+	taken := make(map[int]struct{}, len(indexes))
+	for i, idx := range indexes {
+		pending := q.packets[idx]
+		res[i] = pending.packet
+		taken[idx] = struct{}{}
+
+		if bucket := q.bucket(pending.typ, now); bucket != nil {
+			bucket.take(1)
+		}
+	}
+
+	kept := q.packets[:0]
+	for idx, pending := range q.packets {
+		if _, found := taken[idx]; !found {
+			kept = append(kept, pending)
+		}
+	}
+	for idx := len(kept); idx < len(q.packets); idx++ {
+		q.packets[idx] = pendingPacket{}
+	}
+	q.packets = kept
+	return res
+}
+
+// timeUntilSendable returns how long until at least one packet which is
+// pending could be sent, assuming nothing else is sent in the meantime. The
+// second result is false if there are no pending packets.
+func (q *pendingQueue) timeUntilSendable(now time.Time) (time.Duration, bool) {
+	if len(q.packets) == 0 {
+		return 0, false
+	}
+
+	res := time.Duration(math.MaxInt64)
+	for _, pending := range q.packets {
+		bucket := q.bucket(pending.typ, now)
+		if bucket == nil {
+			return 0, true
+		}
+
+		if wait := bucket.timeUntilAvailable(now); wait < res {
+			res = wait
+		}
+	}
+	return res, true
+}
+
+func (q *pendingQueue) len() int {
+	return len(q.packets)
+}
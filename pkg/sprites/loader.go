@@ -0,0 +1,218 @@
+package sprites
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+)
+
+// Fetcher retrieves the contents of a URL
+type Fetcher interface {
+	Fetch(url string) ([]byte, error)
+}
+
+// FetcherFunc adapts a function to a Fetcher
+type FetcherFunc func(url string) ([]byte, error)
+
+// Fetch calls f(url)
+func (f FetcherFunc) Fetch(url string) ([]byte, error) {
+	return f(url)
+}
+
+// HTTPFetcher returns a Fetcher which uses the given client, or
+// http.DefaultClient if nil
+func HTTPFetcher(httpClient *http.Client) Fetcher {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return FetcherFunc(func(url string) ([]byte, error) {
+		resp, err := httpClient.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("unexpected status code fetching %s: %d", url, resp.StatusCode)
+		}
+		return ioutil.ReadAll(resp.Body)
+	})
+}
+
+// LoaderOptions configures a Loader
+type LoaderOptions struct {
+	// Fetcher retrieves sheets which aren't cached. Defaults to
+	// HTTPFetcher(nil).
+	Fetcher Fetcher
+
+	// CacheDir is the directory sheets are cached in, if not empty. Cached
+	// sheets are used rather than fetching, so tests and replays can run
+	// offline once the cache is populated.
+	CacheDir string
+
+	// BaseURL is what relative sheet URLs are resolved against. Defaults to
+	// utils.API_BASE.
+	BaseURL string
+}
+
+// Loader loads spritesheets, caching them in memory and optionally on disk.
+// A Loader is safe for concurrent use.
+type Loader struct {
+	opts LoaderOptions
+
+	mutex  sync.Mutex
+	sheets map[string]*Sheet
+}
+
+// NewLoader creates a loader which fetches sheets over HTTP without caching
+// them on disk
+func NewLoader() *Loader {
+	return NewLoaderWithOptions(LoaderOptions{})
+}
+
+// NewLoaderWithOptions creates a loader with the given options
+func NewLoaderWithOptions(opts LoaderOptions) *Loader {
+	if opts.Fetcher == nil {
+		opts.Fetcher = HTTPFetcher(nil)
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = utils.API_BASE
+	}
+
+	return &Loader{
+		opts:   opts,
+		sheets: make(map[string]*Sheet),
+	}
+}
+
+// Load returns the sheet at the given URL, which may be relative to the
+// base URL. Sheets are fetched once and then served from memory.
+func (l *Loader) Load(sheetURL string) (*Sheet, error) {
+	resolved, err := l.resolve(sheetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mutex.Lock()
+	sheet, found := l.sheets[resolved]
+	l.mutex.Unlock()
+	if found {
+		return sheet, nil
+	}
+
+	sheet, err = l.load(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("loading sheet %s: %w", resolved, err)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if existing, found := l.sheets[resolved]; found {
+		// loaded concurrently; keep the first so callers share a sheet
+		return existing, nil
+	}
+	l.sheets[resolved] = sheet
+	return sheet, nil
+}
+
+// Progress loads the sheet for the given game object and infers the progress
+// of its animation at the given game time, as in Sheet.Progress
+func (l *Loader) Progress(obj *client.GameObject, gameTime float64) (Progress, error) {
+	sheet, err := l.Load(obj.SheetURL)
+	if err != nil {
+		return Progress{}, err
+	}
+	return sheet.Progress(obj, gameTime)
+}
+
+// resolve returns the absolute URL for the given sheet URL
+func (l *Loader) resolve(sheetURL string) (string, error) {
+	base, err := url.Parse(l.opts.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing base url: %w", err)
+	}
+	ref, err := url.Parse(sheetURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing sheet url: %w", err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// load the sheet at the given absolute URL from the disk cache if possible,
+// otherwise from the fetcher
+func (l *Loader) load(sheetURL string) (*Sheet, error) {
+	cachePath := l.cachePath(sheetURL)
+	if cachePath != "" {
+		raw, err := ioutil.ReadFile(cachePath)
+		if err == nil {
+			sheet, err := ParseSheet(raw)
+			if err == nil {
+				sheet.URL = sheetURL
+				return sheet, nil
+			}
+			// a corrupt cache entry is replaced by fetching again
+		}
+	}
+
+	raw, err := l.opts.Fetcher.Fetch(sheetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet, err := ParseSheet(raw)
+	if err != nil {
+		return nil, err
+	}
+	sheet.URL = sheetURL
+
+	if cachePath != "" {
+		err = writeCacheFile(cachePath, raw)
+		if err != nil {
+			return nil, fmt.Errorf("caching: %w", err)
+		}
+	}
+	return sheet, nil
+}
+
+// cachePath returns where the sheet at the given URL is cached, or an empty
+// string if there is no cache
+func (l *Loader) cachePath(sheetURL string) string {
+	if l.opts.CacheDir == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(sheetURL))
+	return filepath.Join(l.opts.CacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// writeCacheFile writes the given file via a temporary file, so concurrent
+// loaders never read a partial file
+func writeCacheFile(path string, contents []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".sheet-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(contents)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,108 @@
+package sprites_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/calamity-of-subterfuge/cos/pkg/sprites"
+)
+
+// countingFetcher serves testSheet and counts the fetches of each URL
+type countingFetcher struct {
+	mutex  sync.Mutex
+	counts map[string]int
+}
+
+func (f *countingFetcher) Fetch(url string) ([]byte, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.counts == nil {
+		f.counts = make(map[string]int)
+	}
+	f.counts[url]++
+	return []byte(testSheet), nil
+}
+
+func TestLoader_Load(t *testing.T) {
+	fetcher := &countingFetcher{}
+	loader := sprites.NewLoaderWithOptions(sprites.LoaderOptions{
+		Fetcher: fetcher,
+		BaseURL: "https://example.com/game/",
+	})
+
+	first, err := loader.Load("/sheets/villager.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := loader.Load("https://example.com/sheets/villager.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != second {
+		t.Errorf("expected the same sheet for equivalent urls")
+	}
+	if first.URL != "https://example.com/sheets/villager.json" {
+		t.Errorf("unexpected url: %s", first.URL)
+	}
+	if fetcher.counts[first.URL] != 1 {
+		t.Errorf("expected 1 fetch, got %v", fetcher.counts)
+	}
+}
+
+func TestLoader_Load_cache(t *testing.T) {
+	dir := t.TempDir()
+	fetcher := &countingFetcher{}
+	loader := sprites.NewLoaderWithOptions(sprites.LoaderOptions{Fetcher: fetcher, CacheDir: dir})
+	_, err := loader.Load("/sheets/villager.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 cached file, got %d", len(files))
+	}
+
+	offline := sprites.NewLoaderWithOptions(sprites.LoaderOptions{
+		Fetcher: sprites.FetcherFunc(func(url string) ([]byte, error) {
+			return nil, errors.New("offline")
+		}),
+		CacheDir: dir,
+	})
+	sheet, err := offline.Load("/sheets/villager.json")
+	if err != nil {
+		t.Fatalf("expected the cached sheet, got %v", err)
+	}
+	if sheet.Animation("attack") == nil {
+		t.Errorf("expected the cached sheet to have the attack animation")
+	}
+
+	_, err = offline.Load("/sheets/other.json")
+	if err == nil {
+		t.Errorf("expected an error for an uncached sheet while offline")
+	}
+}
+
+func TestLoader_Progress(t *testing.T) {
+	loader := sprites.NewLoaderWithOptions(sprites.LoaderOptions{Fetcher: &countingFetcher{}})
+	progress, err := loader.Progress(&client.GameObject{
+		SheetURL:           "/sheets/villager.json",
+		Animation:          "attack",
+		AnimationSpeed:     1,
+		AnimationPlaying:   true,
+		AnimationStartedAt: 3,
+	}, 3.02)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Frame != 1 || progress.Finished {
+		t.Errorf("unexpected progress: %+v", progress)
+	}
+}
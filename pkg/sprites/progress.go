@@ -0,0 +1,73 @@
+package sprites
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+)
+
+// Progress is how far a game object is through its current animation
+type Progress struct {
+	// Animation the object is showing
+	Animation *Animation
+
+	// Frame is the index of the frame being shown. Paused animations are
+	// frozen on an arbitrary frame, so this is 0 for them.
+	Frame int
+
+	// Elapsed is how long the animation has been playing, since the start of
+	// the current loop for looping animations
+	Elapsed time.Duration
+
+	// Remaining is how long until the animation finishes, or until the
+	// current loop ends for looping animations. This is 0 for finished or
+	// paused animations.
+	Remaining time.Duration
+
+	// Playing is true if the animation is advancing
+	Playing bool
+
+	// Looping is true if the animation restarts when it finishes
+	Looping bool
+
+	// Finished is true if the animation doesn't loop and has played through
+	Finished bool
+}
+
+// Progress infers the progress of the given game object's animation at the
+// given game time, usually State.EstimatedServerGameTime. This relies on
+// GameObject.AnimationStartedAt, so it's only as accurate as that is. Returns
+// an error if the object's animation isn't in the sheet.
+func (s *Sheet) Progress(obj *client.GameObject, gameTime float64) (Progress, error) {
+	anim := s.Animation(obj.Animation)
+	if anim == nil {
+		return Progress{}, fmt.Errorf("sheet %s has no animation %q", s.URL, obj.Animation)
+	}
+
+	res := Progress{
+		Animation: anim,
+		Playing:   obj.AnimationPlaying && obj.AnimationSpeed > 0 && anim.FrameCount() > 0,
+		Looping:   obj.AnimationLooping,
+	}
+	if !res.Playing {
+		return res, nil
+	}
+
+	elapsed := math.Max(gameTime-obj.AnimationStartedAt, 0)
+	frames := elapsed * FramesPerSecond * obj.AnimationSpeed
+	count := float64(anim.FrameCount())
+	if !res.Looping && frames >= count {
+		res.Frame = anim.FrameCount() - 1
+		res.Elapsed = anim.Duration(obj.AnimationSpeed)
+		res.Finished = true
+		return res, nil
+	}
+
+	frames = math.Mod(frames, count)
+	res.Frame = int(frames)
+	res.Elapsed = time.Duration(frames / (FramesPerSecond * obj.AnimationSpeed) * float64(time.Second))
+	res.Remaining = anim.Duration(obj.AnimationSpeed) - res.Elapsed
+	return res, nil
+}
@@ -0,0 +1,218 @@
+// Package sprites loads the PixiJS spritesheets referred to by
+// GameObject.SheetURL, which lets bots reason about animations, e.g., how long
+// until a unit finishes attacking.
+package sprites
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// FramesPerSecond is the number of animation frames shown per second at an
+// AnimationSpeed of 1
+const FramesPerSecond = 60
+
+// Rect is a rectangle within a spritesheet image in pixels
+type Rect struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+// Size is the size of an image in pixels
+type Size struct {
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+// Point is a point relative to a frame, e.g., its anchor
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Frame is a single image within a spritesheet
+type Frame struct {
+	// Name of the frame, which animations refer to
+	Name string `json:"filename,omitempty"`
+
+	// Frame is where the frame is within the sheet image
+	Frame Rect `json:"frame"`
+
+	// Rotated is true if the frame is rotated 90 degrees within the image
+	Rotated bool `json:"rotated"`
+
+	// Trimmed is true if transparent pixels were removed from the frame
+	Trimmed bool `json:"trimmed"`
+
+	// SpriteSourceSize is where the trimmed frame is within the original
+	SpriteSourceSize Rect `json:"spriteSourceSize"`
+
+	// SourceSize is the size of the frame before trimming
+	SourceSize Size `json:"sourceSize"`
+
+	// Anchor is the origin of the frame, or nil to use the sprite's
+	Anchor *Point `json:"anchor,omitempty"`
+}
+
+// Meta is the meta section of a spritesheet
+type Meta struct {
+	// Image is the URL of the image, relative to the sheet
+	Image string `json:"image"`
+
+	// Format is the pixel format of the image, e.g., RGBA8888
+	Format string `json:"format"`
+
+	// Size of the image in pixels
+	Size Size `json:"size"`
+
+	// Scale of the image, which exporters write as a string or a number
+	Scale json.Number `json:"scale"`
+}
+
+// ScaleFactor returns the scale of the image, defaulting to 1
+func (m *Meta) ScaleFactor() float64 {
+	res, err := m.Scale.Float64()
+	if err != nil || res <= 0 {
+		return 1
+	}
+	return res
+}
+
+// Sheet is a parsed PixiJS spritesheet
+type Sheet struct {
+	// URL the sheet was loaded from, if any
+	URL string
+
+	// Frames by name
+	Frames map[string]Frame
+
+	// Animations are the names of the frames of each animation, in order
+	Animations map[string][]string
+
+	// Meta describes the sheet image
+	Meta Meta
+
+	// AnimationMeta is the additional animationMeta section, which contains
+	// render hints that aren't controlled by the server. It's left as
+	// decoded JSON since its contents vary by sheet.
+	AnimationMeta map[string]interface{}
+}
+
+// rawSheet is the JSON format of a sheet. Frames are either an object keyed
+// by name or an array of frames with filenames, depending on the exporter.
+type rawSheet struct {
+	Frames        json.RawMessage        `json:"frames"`
+	Animations    map[string][]string    `json:"animations"`
+	Meta          Meta                   `json:"meta"`
+	AnimationMeta map[string]interface{} `json:"animationMeta"`
+}
+
+// ParseSheet parses a PixiJS spritesheet in JSON
+func ParseSheet(raw []byte) (*Sheet, error) {
+	var parsed rawSheet
+	err := json.Unmarshal(raw, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("parsing sheet: %w", err)
+	}
+
+	res := &Sheet{
+		Frames:        make(map[string]Frame),
+		Animations:    parsed.Animations,
+		Meta:          parsed.Meta,
+		AnimationMeta: parsed.AnimationMeta,
+	}
+	if res.Animations == nil {
+		res.Animations = make(map[string][]string)
+	}
+
+	frames := bytes.TrimSpace(parsed.Frames)
+	if len(frames) > 0 && frames[0] == '[' {
+		var list []Frame
+		err = json.Unmarshal(frames, &list)
+		if err != nil {
+			return nil, fmt.Errorf("parsing frames: %w", err)
+		}
+		for _, frame := range list {
+			res.Frames[frame.Name] = frame
+		}
+	} else if len(frames) > 0 && !bytes.Equal(frames, []byte("null")) {
+		var byName map[string]Frame
+		err = json.Unmarshal(frames, &byName)
+		if err != nil {
+			return nil, fmt.Errorf("parsing frames: %w", err)
+		}
+		for name, frame := range byName {
+			frame.Name = name
+			res.Frames[name] = frame
+		}
+	}
+
+	for name, frameNames := range res.Animations {
+		for _, frameName := range frameNames {
+			if _, found := res.Frames[frameName]; !found {
+				return nil, fmt.Errorf("animation %q refers to unknown frame %q", name, frameName)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// AnimationNames returns the names of the animations in the sheet, sorted
+func (s *Sheet) AnimationNames() []string {
+	res := make([]string, 0, len(s.Animations))
+	for name := range s.Animations {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Animation returns the animation with the given name, or nil if there is
+// no such animation in the sheet
+func (s *Sheet) Animation(name string) *Animation {
+	frames, found := s.Animations[name]
+	if !found {
+		return nil
+	}
+	return &Animation{Name: name, Frames: frames}
+}
+
+// Animation is a named sequence of frames within a sheet
+type Animation struct {
+	// Name of the animation
+	Name string
+
+	// Frames are the names of the frames in order
+	Frames []string
+}
+
+// FrameCount returns the number of frames in the animation
+func (a *Animation) FrameCount() int {
+	return len(a.Frames)
+}
+
+// FrameDuration returns how long each frame is shown at the given speed,
+// which is as in GameObject.AnimationSpeed. Returns 0 if the speed is not
+// positive, i.e., the animation doesn't advance.
+func (a *Animation) FrameDuration(speed float64) time.Duration {
+	if speed <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / (FramesPerSecond * speed))
+}
+
+// Duration returns how long a single play through the animation takes at
+// the given speed, which is as in GameObject.AnimationSpeed. Returns 0 if
+// the speed is not positive.
+func (a *Animation) Duration(speed float64) time.Duration {
+	if speed <= 0 {
+		return 0
+	}
+	return time.Duration(float64(a.FrameCount()) * float64(time.Second) / (FramesPerSecond * speed))
+}
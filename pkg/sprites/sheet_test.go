@@ -0,0 +1,153 @@
+package sprites_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/calamity-of-subterfuge/cos/pkg/sprites"
+)
+
+const testSheet = `{
+	"frames": {
+		"attack0.png": {"frame": {"x": 0, "y": 0, "w": 32, "h": 32}, "rotated": false, "trimmed": false, "spriteSourceSize": {"x": 0, "y": 0, "w": 32, "h": 32}, "sourceSize": {"w": 32, "h": 32}},
+		"attack1.png": {"frame": {"x": 32, "y": 0, "w": 32, "h": 32}, "rotated": false, "trimmed": false, "spriteSourceSize": {"x": 0, "y": 0, "w": 32, "h": 32}, "sourceSize": {"w": 32, "h": 32}},
+		"attack2.png": {"frame": {"x": 64, "y": 0, "w": 32, "h": 32}, "rotated": true, "trimmed": false, "spriteSourceSize": {"x": 0, "y": 0, "w": 32, "h": 32}, "sourceSize": {"w": 32, "h": 32}, "anchor": {"x": 0.5, "y": 1}},
+		"idle0.png": {"frame": {"x": 96, "y": 0, "w": 32, "h": 32}, "rotated": false, "trimmed": false, "spriteSourceSize": {"x": 0, "y": 0, "w": 32, "h": 32}, "sourceSize": {"w": 32, "h": 32}}
+	},
+	"animations": {
+		"attack": ["attack0.png", "attack1.png", "attack2.png"],
+		"idle": ["idle0.png"]
+	},
+	"meta": {"image": "villager.png", "format": "RGBA8888", "size": {"w": 128, "h": 32}, "scale": "0.5"},
+	"animationMeta": {"attack": {"hitFrame": 2}}
+}`
+
+func TestParseSheet(t *testing.T) {
+	sheet, err := sprites.ParseSheet([]byte(testSheet))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sheet.Frames) != 4 {
+		t.Errorf("expected 4 frames, got %d", len(sheet.Frames))
+	}
+	frame := sheet.Frames["attack2.png"]
+	if frame.Name != "attack2.png" || !frame.Rotated || frame.Frame.X != 64 || frame.Anchor == nil || frame.Anchor.Y != 1 {
+		t.Errorf("unexpected frame: %+v", frame)
+	}
+	if sheet.Meta.Image != "villager.png" || sheet.Meta.ScaleFactor() != 0.5 {
+		t.Errorf("unexpected meta: %+v", sheet.Meta)
+	}
+	if names := sheet.AnimationNames(); len(names) != 2 || names[0] != "attack" || names[1] != "idle" {
+		t.Errorf("unexpected animations: %v", names)
+	}
+
+	hints, ok := sheet.AnimationMeta["attack"].(map[string]interface{})
+	if !ok || hints["hitFrame"] != float64(2) {
+		t.Errorf("unexpected animation meta: %v", sheet.AnimationMeta)
+	}
+
+	attack := sheet.Animation("attack")
+	if attack.FrameCount() != 3 {
+		t.Errorf("expected 3 frames, got %d", attack.FrameCount())
+	}
+	if attack.Duration(1) != 50*time.Millisecond {
+		t.Errorf("expected 50ms at speed 1, got %v", attack.Duration(1))
+	}
+	if attack.Duration(0.5) != 100*time.Millisecond {
+		t.Errorf("expected 100ms at speed 0.5, got %v", attack.Duration(0.5))
+	}
+	if sheet.Animation("missing") != nil {
+		t.Errorf("expected no missing animation")
+	}
+}
+
+func TestParseSheet_frameArray(t *testing.T) {
+	sheet, err := sprites.ParseSheet([]byte(`{
+		"frames": [
+			{"filename": "a", "frame": {"x": 0, "y": 0, "w": 8, "h": 8}},
+			{"filename": "b", "frame": {"x": 8, "y": 0, "w": 8, "h": 8}}
+		],
+		"animations": {"blink": ["a", "b"]},
+		"meta": {"scale": 1}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sheet.Frames["b"].Frame.X != 8 {
+		t.Errorf("unexpected frames: %+v", sheet.Frames)
+	}
+	if sheet.Animation("blink").FrameCount() != 2 {
+		t.Errorf("expected 2 frames")
+	}
+	if sheet.Meta.ScaleFactor() != 1 {
+		t.Errorf("expected scale 1, got %v", sheet.Meta.ScaleFactor())
+	}
+}
+
+func TestParseSheet_unknownFrame(t *testing.T) {
+	_, err := sprites.ParseSheet([]byte(`{"frames": {}, "animations": {"walk": ["walk0"]}}`))
+	if err == nil {
+		t.Fatal("expected an error for an animation with an unknown frame")
+	}
+}
+
+func TestSheet_Progress(t *testing.T) {
+	sheet, err := sprites.ParseSheet([]byte(testSheet))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// at speed 0.5 each frame is 1/30s, so the attack takes 100ms
+	obj := &client.GameObject{
+		Animation:          "attack",
+		AnimationSpeed:     0.5,
+		AnimationPlaying:   true,
+		AnimationStartedAt: 10,
+	}
+
+	progress, err := sheet.Progress(obj, 10.04)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Frame != 1 || progress.Finished || !progress.Playing {
+		t.Errorf("unexpected progress mid-attack: %+v", progress)
+	}
+	if diff := progress.Remaining - 60*time.Millisecond; diff > time.Millisecond || diff < -time.Millisecond {
+		t.Errorf("expected about 60ms remaining, got %v", progress.Remaining)
+	}
+
+	progress, err = sheet.Progress(obj, 10.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !progress.Finished || progress.Remaining != 0 || progress.Frame != 2 {
+		t.Errorf("unexpected progress after the attack: %+v", progress)
+	}
+
+	obj.AnimationLooping = true
+	progress, err = sheet.Progress(obj, 10.14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Finished || progress.Frame != 1 || !progress.Looping {
+		t.Errorf("unexpected progress while looping: %+v", progress)
+	}
+
+	obj.AnimationPlaying = false
+	progress, err = sheet.Progress(obj, 10.14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Playing || progress.Remaining != 0 {
+		t.Errorf("unexpected progress while paused: %+v", progress)
+	}
+
+	obj.Animation = "missing"
+	_, err = sheet.Progress(obj, 10)
+	if err == nil {
+		t.Errorf("expected an error for a missing animation")
+	}
+}
@@ -20,8 +20,10 @@ func (p *ChatAuthorAddedPacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *ChatAuthorAddedPacket) GetGameTime() float64 {
+	return p.GameTime
+}
+
 func init() {
-	registerPacketParser("chat-author-added", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &ChatAuthorAddedPacket{})
-	})
+	RegisterPacketType("chat-author-added", func() Packet { return &ChatAuthorAddedPacket{} })
 }
@@ -21,8 +21,10 @@ func (p *ChatAuthorRemovedPacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *ChatAuthorRemovedPacket) GetGameTime() float64 {
+	return p.GameTime
+}
+
 func init() {
-	registerPacketParser("chat-author-removed", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &ChatAuthorRemovedPacket{})
-	})
+	RegisterPacketType("chat-author-removed", func() Packet { return &ChatAuthorRemovedPacket{} })
 }
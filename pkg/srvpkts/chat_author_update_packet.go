@@ -21,8 +21,10 @@ func (p *ChatAuthorUpdatePacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *ChatAuthorUpdatePacket) GetGameTime() float64 {
+	return p.GameTime
+}
+
 func init() {
-	registerPacketParser("chat-author-update", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &ChatAuthorUpdatePacket{})
-	})
+	RegisterPacketType("chat-author-update", func() Packet { return &ChatAuthorUpdatePacket{} })
 }
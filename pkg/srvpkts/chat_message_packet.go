@@ -30,8 +30,10 @@ func (p *ChatMessagePacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *ChatMessagePacket) GetGameTime() float64 {
+	return p.GameTime
+}
+
 func init() {
-	registerPacketParser("chat-message", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &ChatMessagePacket{})
-	})
+	RegisterPacketType("chat-message", func() Packet { return &ChatMessagePacket{} })
 }
@@ -21,8 +21,10 @@ func (p *GameObjectAddedPacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *GameObjectAddedPacket) GetGameTime() float64 {
+	return p.GameTime
+}
+
 func init() {
-	registerPacketParser("game-object-added", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &GameObjectAddedPacket{})
-	})
+	RegisterPacketType("game-object-added", func() Packet { return &GameObjectAddedPacket{} })
 }
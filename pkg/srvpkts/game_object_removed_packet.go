@@ -21,8 +21,10 @@ func (p *GameObjectRemovedPacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *GameObjectRemovedPacket) GetGameTime() float64 {
+	return p.GameTime
+}
+
 func init() {
-	registerPacketParser("game-object-removed", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &GameObjectRemovedPacket{})
-	})
+	RegisterPacketType("game-object-removed", func() Packet { return &GameObjectRemovedPacket{} })
 }
@@ -45,8 +45,10 @@ func (p *GameObjectUpdatePacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *GameObjectUpdatePacket) GetGameTime() float64 {
+	return p.GameTime
+}
+
 func init() {
-	registerPacketParser("game-object-update", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &GameObjectUpdatePacket{})
-	})
+	RegisterPacketType("game-object-update", func() Packet { return &GameObjectUpdatePacket{} })
 }
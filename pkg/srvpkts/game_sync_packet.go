@@ -69,8 +69,10 @@ func (p *GameSyncPacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *GameSyncPacket) GetGameTime() float64 {
+	return p.GameTime
+}
+
 func init() {
-	registerPacketParser("game-sync", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &GameSyncPacket{})
-	})
+	RegisterPacketType("game-sync", func() Packet { return &GameSyncPacket{} })
 }
@@ -10,3 +10,12 @@ type Packet interface {
 	// set correctly.
 	PrepareForMarshal()
 }
+
+// TimedPacket is implemented by every packet in this package and describes
+// packets which include the game time at which they were sent.
+type TimedPacket interface {
+	Packet
+
+	// GetGameTime returns the game time at which the packet was sent
+	GetGameTime() float64
+}
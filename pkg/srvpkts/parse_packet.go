@@ -1,19 +1,98 @@
 package srvpkts
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/calamity-of-subterfuge/cos/pkg/utils"
 )
 
-type packetParser func(map[string]interface{}) (Packet, error)
+// PacketParser parses a single packet of a particular type from its map
+// representation.
+type PacketParser func(map[string]interface{}) (Packet, error)
 
-var packetParsersByType map[string]packetParser = make(map[string]packetParser)
+// ErrUnknownPacketType is wrapped by the error from ParseSinglePacket when
+// there is no parser registered for the type of the packet.
+var ErrUnknownPacketType = errors.New("unknown packet type")
 
-func registerPacketParser(typ string, parser packetParser) {
-	packetParsersByType[typ] = parser
+// PacketJSONParser parses a single packet of a particular type directly from
+// its JSON representation, which avoids building the map representation.
+type PacketJSONParser func(raw []byte) (Packet, error)
+
+// packetParsers are the parsers registered for a single packet type
+type packetParsers struct {
+	parser     PacketParser
+	jsonParser PacketJSONParser
+}
+
+var packetParsersLock sync.RWMutex
+var packetParsersByType map[string]packetParsers = make(map[string]packetParsers)
+
+// RegisterPacketParser registers the parser to use for packets of the given
+// type, replacing the existing parsers for that type if there are any. This
+// allows applications to handle packets which this library does not know
+// about yet without forking it. This is typically called from init().
+//
+// Packets of this type will be parsed from the map representation even by
+// ParseSinglePacketJSON unless a JSON parser is registered afterward with
+// RegisterPacketJSONParser.
+func RegisterPacketParser(typ string, parser PacketParser) {
+	packetParsersLock.Lock()
+	defer packetParsersLock.Unlock()
+	packetParsersByType[typ] = packetParsers{parser: parser}
+}
+
+// RegisterPacketJSONParser registers a parser which is used by
+// ParseSinglePacketJSON for packets of the given type. It must produce the
+// same result as the parser registered with RegisterPacketParser, which must
+// be registered first and is used if this parser fails.
+func RegisterPacketJSONParser(typ string, parser PacketJSONParser) {
+	packetParsersLock.Lock()
+	defer packetParsersLock.Unlock()
+
+	parsers, found := packetParsersByType[typ]
+	if !found {
+		panic(fmt.Sprintf("RegisterPacketJSONParser: no parser registered for %s", typ))
+	}
+	parsers.jsonParser = parser
+	packetParsersByType[typ] = parsers
+}
+
+// RegisterPacketType registers both parsers for packets of the given type,
+// for packets which are decoded directly into a struct with matching json
+// and mapstructure tags. newPacket must return a pointer to a new zero value
+// of the struct each time it's called.
+func RegisterPacketType(typ string, newPacket func() Packet) {
+	RegisterPacketParser(typ, func(parsed map[string]interface{}) (Packet, error) {
+		return parseSinglePacketOfType(parsed, newPacket())
+	})
+	RegisterPacketJSONParser(typ, func(raw []byte) (Packet, error) {
+		packet := newPacket()
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(packet); err != nil {
+			return nil, err
+		}
+		return packet, nil
+	})
+}
+
+// KnownPacketTypes returns the packet types which have a registered parser,
+// in sorted order.
+func KnownPacketTypes() []string {
+	packetParsersLock.RLock()
+	defer packetParsersLock.RUnlock()
+
+	res := make([]string, 0, len(packetParsersByType))
+	for typ := range packetParsersByType {
+		res = append(res, typ)
+	}
+	sort.Strings(res)
+	return res
 }
 
 // ParsePacket attempts to parse the packet described by the given bytes as one
@@ -78,13 +157,108 @@ func ParseSinglePacket(parsed map[string]interface{}) (Packet, error) {
 		return nil, errors.New("packet has type but it's not a string")
 	}
 
-	var parser packetParser
-	parser, found = packetParsersByType[packetType]
+	packetParsersLock.RLock()
+	parsers, found := packetParsersByType[packetType]
+	packetParsersLock.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPacketType, packetType)
+	}
+
+	return parsers.parser(parsed)
+}
+
+// ParsePacketJSON is equivalent to ParsePacket except it uses the JSON
+// parsers where they are available, which is significantly faster for large
+// packets. Like packets received through a Conn, but unlike ParsePacket,
+// numbers within interface{} fields such as Additional are json.Number's.
+func ParsePacketJSON(raw []byte) ([]Packet, error) {
+	trimmed := bytes.TrimLeft(raw, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var elements []json.RawMessage
+		err := json.Unmarshal(trimmed, &elements)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+		}
+
+		result := make([]Packet, 0, len(elements))
+		for _, ele := range elements {
+			interpreted, err := ParseSinglePacketJSON(ele)
+			if err != nil {
+				return nil, fmt.Errorf("element in array not valid: %w", err)
+			}
+			result = append(result, interpreted)
+		}
+		return result, nil
+	}
+
+	interpreted, err := ParseSinglePacketJSON(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed interpret JSON object: %w", err)
+	}
+	return []Packet{interpreted}, nil
+}
+
+// ParseSinglePacketJSON parses a single packet from its JSON representation,
+// which must be a JSON object. This produces the same result as decoding the
+// object with json.Decoder#UseNumber and passing the result to
+// ParseSinglePacket, but is significantly faster for packet types with a
+// JSON parser, such as all the packet types in this package.
+func ParseSinglePacketJSON(raw []byte) (Packet, error) {
+	packetType, err := PeekPacketType(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	packetParsersLock.RLock()
+	parsers, found := packetParsersByType[packetType]
+	packetParsersLock.RUnlock()
 	if !found {
-		return nil, fmt.Errorf("unknown packet type: %s", packetType)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPacketType, packetType)
+	}
+
+	if parsers.jsonParser != nil {
+		packet, err := parsers.jsonParser(raw)
+		if err == nil {
+			return packet, nil
+		}
+		// the map parser may accept things the JSON parser does not, such
+		// as numbers within strings, and otherwise produces the canonical
+		// error message
+	}
+
+	var parsed map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	err = decoder.Decode(&parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return parsers.parser(parsed)
+}
+
+// packetTypePeek is used to read just the type of a packet
+type packetTypePeek struct {
+	Type json.RawMessage `json:"type"`
+}
+
+// PeekPacketType returns the type of the packet described by the given JSON
+// object without parsing the rest of it.
+func PeekPacketType(raw []byte) (string, error) {
+	var peek packetTypePeek
+	err := json.Unmarshal(raw, &peek)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal JSON: %w", err)
 	}
 
-	return parser(parsed)
+	if len(peek.Type) == 0 {
+		return "", errors.New("packet missing type")
+	}
+
+	var packetType string
+	if peek.Type[0] != '"' || json.Unmarshal(peek.Type, &packetType) != nil {
+		return "", errors.New("packet has type but it's not a string")
+	}
+	return packetType, nil
 }
 
 func parseSinglePacketOfType(parsed map[string]interface{}, typ Packet) (Packet, error) {
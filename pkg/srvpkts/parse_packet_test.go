@@ -0,0 +1,351 @@
+package srvpkts_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+)
+
+func randomUID() string {
+	return fmt.Sprintf("%016x", rand.Uint64())
+}
+
+func randomVector(scale float64) map[string]interface{} {
+	return map[string]interface{}{
+		"x": (rand.Float64()*2 - 1) * scale,
+		"y": (rand.Float64()*2 - 1) * scale,
+	}
+}
+
+func randomShapes() []interface{} {
+	res := make([]interface{}, 1+rand.Intn(2))
+	for i := range res {
+		verts := make([]interface{}, 3+rand.Intn(4))
+		for j := range verts {
+			verts[j] = randomVector(2)
+		}
+		res[i] = map[string]interface{}{
+			"shape_type": "polygon",
+			"mass":       rand.Float64() * 10,
+			"details": map[string]interface{}{
+				"vertices": verts,
+				"radius":   rand.Float64() * 0.1,
+			},
+		}
+	}
+	return res
+}
+
+func randomGameObject() map[string]interface{} {
+	return map[string]interface{}{
+		"uid":               randomUID(),
+		"sheet_url":         "https://example.com/sheets/" + randomUID() + ".json",
+		"sprite_scale":      randomVector(1),
+		"sprite_rotation":   rand.Float64(),
+		"render_offset":     randomVector(1),
+		"animation":         "idle",
+		"animation_speed":   rand.Float64(),
+		"animation_playing": rand.Intn(2) == 0,
+		"animation_looping": rand.Intn(2) == 0,
+		"shapes":            randomShapes(),
+		"position":          randomVector(100),
+		"velocity":          randomVector(5),
+		"rotation":          rand.Float64() * 6,
+		"angular_velocity":  rand.Float64(),
+	}
+}
+
+func randomTentOffers() map[string]interface{} {
+	res := make(map[string]interface{})
+	for i := rand.Intn(3); i > 0; i-- {
+		res[randomUID()] = map[string]interface{}{
+			"initiating_team": rand.Intn(4),
+			"target_team":     rand.Intn(4),
+			"offer":           map[string]interface{}{"gold": rand.Intn(100)},
+			"request":         map[string]interface{}{"wood": rand.Intn(100)},
+		}
+	}
+	return res
+}
+
+func randomSmartObject() map[string]interface{} {
+	res := randomGameObject()
+	res["current_health"] = rand.Intn(100)
+	res["max_health"] = 100
+	res["controlling_team"] = rand.Intn(4)
+	res["controlling_role"] = "economy"
+	if rand.Intn(2) == 0 {
+		res["unit_type"] = "tent"
+		res["additional"] = map[string]interface{}{
+			"incoming_offers": randomTentOffers(),
+			"outgoing_offers": randomTentOffers(),
+		}
+	} else {
+		res["unit_type"] = "villager"
+		res["additional"] = map[string]interface{}{}
+	}
+	return res
+}
+
+// randomGameSync produces a game sync similar to what the server sends when
+// a client joins a game in progress
+func randomGameSync(numObjects int) map[string]interface{} {
+	resources := make(map[string]interface{})
+	for _, name := range []string{"gold", "wood", "stone"} {
+		resources[name] = map[string]interface{}{
+			"uid":       name,
+			"sheet_url": "https://example.com/sheets/resources.json",
+			"animation": name,
+			"name":      name,
+		}
+	}
+
+	players := make(map[string]interface{})
+	chatAuthors := make(map[string]interface{})
+	for i := 0; i < 8; i++ {
+		player := randomGameObject()
+		player["role"] = "military"
+		player["team"] = rand.Intn(4)
+		players[player["uid"].(string)] = player
+
+		chatAuthors[player["uid"].(string)] = map[string]interface{}{
+			"uid":           player["uid"],
+			"name":          "Player " + randomUID(),
+			"color":         "#ff0000",
+			"bonus_classes": []interface{}{"veteran"},
+		}
+	}
+
+	dumbObjects := make(map[string]interface{})
+	smartObjects := make(map[string]interface{})
+	for i := 0; i < numObjects; i++ {
+		dumb := randomGameObject()
+		dumbObjects[dumb["uid"].(string)] = dumb
+
+		smart := randomSmartObject()
+		smartObjects[smart["uid"].(string)] = smart
+	}
+
+	return map[string]interface{}{
+		"type":      "game-sync",
+		"game_time": rand.Float64() * 1000,
+		"player": map[string]interface{}{
+			"uid":  randomUID(),
+			"team": 1,
+			"role": "economy",
+		},
+		"team": map[string]interface{}{
+			"resources": map[string]interface{}{"gold": rand.Intn(1000), "wood": rand.Intn(1000)},
+		},
+		"resources":     resources,
+		"players":       players,
+		"dumb_objects":  dumbObjects,
+		"smart_objects": smartObjects,
+		"chat_authors":  chatAuthors,
+	}
+}
+
+// randomUpdate produces one of the packets which are sent continuously
+// during a game
+func randomUpdate() map[string]interface{} {
+	gameTime := rand.Float64() * 1000
+
+	switch rand.Intn(8) {
+	case 0:
+		obj := randomSmartObject()
+		return map[string]interface{}{"type": "smart-object-added", "game_time": gameTime, "object": obj}
+	case 1:
+		return map[string]interface{}{"type": "game-object-removed", "game_time": gameTime, "uid": randomUID()}
+	case 2:
+		return map[string]interface{}{
+			"type":           "smart-object-update",
+			"game_time":      gameTime,
+			"uid":            randomUID(),
+			"position":       randomVector(100),
+			"velocity":       randomVector(5),
+			"current_health": rand.Intn(100),
+			"additional": map[string]interface{}{
+				"removed_incoming_offers": []interface{}{randomUID()},
+				"added_outgoing_offers":   randomTentOffers(),
+			},
+		}
+	case 3:
+		return map[string]interface{}{
+			"type":      "team-resource-changed",
+			"game_time": gameTime,
+			"resources": map[string]interface{}{"gold": rand.Intn(1000)},
+		}
+	case 4:
+		return map[string]interface{}{
+			"type":       "chat-message",
+			"game_time":  gameTime,
+			"time":       1.6e9 + rand.Float64(),
+			"author_uid": randomUID(),
+			"text":       "hello " + randomUID(),
+		}
+	default:
+		return map[string]interface{}{
+			"type":              "game-object-update",
+			"game_time":         gameTime,
+			"uid":               randomUID(),
+			"position":          randomVector(100),
+			"velocity":          randomVector(5),
+			"rotation":          rand.Float64() * 6,
+			"angular_velocity":  rand.Float64(),
+			"animation":         "walk",
+			"animation_playing": true,
+			"animation_looping": true,
+		}
+	}
+}
+
+func randomUpdateFrame(numPackets int) []interface{} {
+	res := make([]interface{}, numPackets)
+	for i := range res {
+		res[i] = randomUpdate()
+	}
+	return res
+}
+
+func mustMarshal(t testing.TB, v interface{}) []byte {
+	t.Helper()
+
+	res, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshalling: %v", err)
+	}
+	return res
+}
+
+// parsePacketMap parses packets the way they were parsed before
+// ParsePacketJSON, i.e., how a Conn without RawMessages decodes them
+// followed by ParseSinglePacket
+func parsePacketMap(raw []byte) ([]srvpkts.Packet, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	err := decoder.Decode(&decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	arr, ok := decoded.([]interface{})
+	if !ok {
+		arr = []interface{}{decoded}
+	}
+
+	res := make([]srvpkts.Packet, len(arr))
+	for idx, ele := range arr {
+		res[idx], err = srvpkts.ParseSinglePacket(ele.(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func checkSameResult(t *testing.T, desc string, raw []byte) {
+	t.Helper()
+
+	expected, expectedErr := parsePacketMap(raw)
+	got, gotErr := srvpkts.ParsePacketJSON(raw)
+	if (expectedErr == nil) != (gotErr == nil) {
+		t.Fatalf("%s: map error %v but json error %v", desc, expectedErr, gotErr)
+	}
+	if errors.Is(expectedErr, srvpkts.ErrUnknownPacketType) != errors.Is(gotErr, srvpkts.ErrUnknownPacketType) {
+		t.Fatalf("%s: map error %v but json error %v", desc, expectedErr, gotErr)
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Fatalf("%s: results differ\nmap:  %s\njson: %s", desc, mustMarshal(t, expected), mustMarshal(t, got))
+	}
+}
+
+func TestParsePacketJSON_gameSync(t *testing.T) {
+	var seed int64
+	for seed = 0; seed < 50; seed++ {
+		rand.Seed(seed)
+		checkSameResult(t, fmt.Sprintf("seed %d", seed), mustMarshal(t, randomGameSync(10)))
+	}
+}
+
+func TestParsePacketJSON_updates(t *testing.T) {
+	var seed int64
+	for seed = 0; seed < 200; seed++ {
+		rand.Seed(seed)
+		checkSameResult(t, fmt.Sprintf("seed %d frame", seed), mustMarshal(t, randomUpdateFrame(20)))
+		checkSameResult(t, fmt.Sprintf("seed %d single", seed), mustMarshal(t, randomUpdate()))
+	}
+}
+
+func TestParsePacketJSON_edgeCases(t *testing.T) {
+	cases := []string{
+		`{"type": "chat-message", "game_time": "12.5", "text": "numbers in strings"}`,
+		`{"type": "team-resource-changed", "resources": {"gold": "12"}}`,
+		`{"type": "team-resource-changed", "resources": {"gold": 1.5}}`,
+		`{"type": "game-object-removed", "uid": null}`,
+		`{"type": "game-sync", "resources": {}, "players": null}`,
+		`{"type": "smart-object-update", "additional": [1, 2.5, "x"]}`,
+		`{"type": "not-a-real-packet"}`,
+		`{"type": 5}`,
+		`{"type": null}`,
+		`{"game_time": 5}`,
+		`[{"type": "game-object-removed", "uid": "a"}, {"type": "chat-message", "text": 5}]`,
+		`[]`,
+	}
+
+	for _, c := range cases {
+		checkSameResult(t, c, []byte(c))
+	}
+}
+
+func TestResourceSync_sheetURL(t *testing.T) {
+	packets, err := parsePacketMap([]byte(`{"type": "game-sync", "resources": {"gold": {"uid": "gold", "sheet_url": "a.json"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource := packets[0].(*srvpkts.GameSyncPacket).Resources["gold"]
+	if resource.SheetURL != "a.json" {
+		t.Errorf("expected sheet_url to be decoded, got %#v", resource)
+	}
+}
+
+func benchmarkParse(b *testing.B, raw []byte, parse func([]byte) ([]srvpkts.Packet, error)) {
+	b.SetBytes(int64(len(raw)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := parse(raw)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseGameSync_map(b *testing.B) {
+	rand.Seed(1)
+	benchmarkParse(b, mustMarshal(b, randomGameSync(200)), parsePacketMap)
+}
+
+func BenchmarkParseGameSync_json(b *testing.B) {
+	rand.Seed(1)
+	benchmarkParse(b, mustMarshal(b, randomGameSync(200)), srvpkts.ParsePacketJSON)
+}
+
+func BenchmarkParseUpdates_map(b *testing.B) {
+	rand.Seed(1)
+	benchmarkParse(b, mustMarshal(b, randomUpdateFrame(100)), parsePacketMap)
+}
+
+func BenchmarkParseUpdates_json(b *testing.B) {
+	rand.Seed(1)
+	benchmarkParse(b, mustMarshal(b, randomUpdateFrame(100)), srvpkts.ParsePacketJSON)
+}
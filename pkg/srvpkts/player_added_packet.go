@@ -21,8 +21,10 @@ func (p *PlayerAddedPacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *PlayerAddedPacket) GetGameTime() float64 {
+	return p.GameTime
+}
+
 func init() {
-	registerPacketParser("player-added", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &PlayerAddedPacket{})
-	})
+	RegisterPacketType("player-added", func() Packet { return &PlayerAddedPacket{} })
 }
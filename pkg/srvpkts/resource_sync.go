@@ -4,17 +4,17 @@ package srvpkts
 // they've never seen before
 type ResourceSync struct {
 	// UID is the unique identifier for this resource
-	UID string `map_structure:"uid" json:"uid"`
+	UID string `mapstructure:"uid" json:"uid"`
 
 	// SheetURL is the URL of the spritesheet (JSON) where the
 	// icon for this resource can be found
-	SheetURL string `map_structure:"sheet_url" json:"sheet_url"`
+	SheetURL string `mapstructure:"sheet_url" json:"sheet_url"`
 
 	// Animation is the name within the sheet for the icon for this
 	// resource. Currently this is always a single-icon animation but we
 	// leave room for real animations later
-	Animation string `map_structure:"animation" json:"animation"`
+	Animation string `mapstructure:"animation" json:"animation"`
 
 	// Name is the display name for this resource
-	Name string `map_structure:"name" json:"name"`
+	Name string `mapstructure:"name" json:"name"`
 }
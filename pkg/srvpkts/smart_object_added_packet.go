@@ -21,8 +21,10 @@ func (p *SmartObjectAddedPacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *SmartObjectAddedPacket) GetGameTime() float64 {
+	return p.GameTime
+}
+
 func init() {
-	registerPacketParser("smart-object-added", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &SmartObjectAddedPacket{})
-	})
+	RegisterPacketType("smart-object-added", func() Packet { return &SmartObjectAddedPacket{} })
 }
@@ -21,7 +21,5 @@ func (p *SmartObjectUpdatePacket) PrepareForMarshal() {
 }
 
 func init() {
-	registerPacketParser("smart-object-update", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &SmartObjectUpdatePacket{})
-	})
+	RegisterPacketType("smart-object-update", func() Packet { return &SmartObjectUpdatePacket{} })
 }
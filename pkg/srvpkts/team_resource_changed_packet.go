@@ -23,8 +23,10 @@ func (p *TeamResourceChangedPacket) PrepareForMarshal() {
 	p.Type = p.GetType()
 }
 
+func (p *TeamResourceChangedPacket) GetGameTime() float64 {
+	return p.GameTime
+}
+
 func init() {
-	registerPacketParser("team-resource-changed", func(parsed map[string]interface{}) (Packet, error) {
-		return parseSinglePacketOfType(parsed, &TeamResourceChangedPacket{})
-	})
+	RegisterPacketType("team-resource-changed", func() Packet { return &TeamResourceChangedPacket{} })
 }
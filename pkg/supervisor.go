@@ -0,0 +1,190 @@
+package pkg
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+)
+
+// PersonalityState is an enum describing what a personality run by a
+// Supervisor is currently doing
+type PersonalityState int
+
+const (
+	// PersonalityStopped means the personality is not running, either
+	// because the supervisor hasn't started or because it was shut down
+	PersonalityStopped PersonalityState = 0
+
+	// PersonalityLoggingIn means the personality is logging in, possibly
+	// waiting to retry after a failed login
+	PersonalityLoggingIn PersonalityState = 1
+
+	// PersonalityQueueing means the personality is registering with the
+	// lobby server, possibly waiting to retry after a failed attempt
+	PersonalityQueueing PersonalityState = 2
+
+	// PersonalityQueued means the personality is connected to the lobby
+	// socket server and is playing matches as they become available
+	PersonalityQueued PersonalityState = 3
+)
+
+// String returns a human readable name for the state
+func (s PersonalityState) String() string {
+	switch s {
+	case PersonalityStopped:
+		return "stopped"
+	case PersonalityLoggingIn:
+		return "logging in"
+	case PersonalityQueueing:
+		return "queueing"
+	case PersonalityQueued:
+		return "queued"
+	default:
+		return "unknown"
+	}
+}
+
+// Personality is a single AI personality run by a Supervisor
+type Personality struct {
+	// Name identifies this personality in logs and statuses. If empty, the
+	// AIName from the Config is used.
+	Name string
+
+	// Config is the configuration for this personality, including the
+	// account it logs in with. Personalities may share accounts.
+	Config *Config
+
+	// GameConstructor constructs the games for this personality
+	GameConstructor ConnGameConstructor
+}
+
+// PersonalityStatus describes the status of a personality run by a
+// Supervisor
+type PersonalityStatus struct {
+	// Name of the personality
+	Name string
+
+	// Role the personality plays
+	Role utils.Role
+
+	// Version of the personality
+	Version string
+
+	// State is what the personality is currently doing
+	State PersonalityState
+
+	// StateSince is when the personality entered its current state
+	StateSince time.Time
+
+	// ActiveGames is the number of games the personality is playing
+	ActiveGames int
+
+	// GamesStarted is the number of games the personality has started
+	// since the supervisor started
+	GamesStarted int64
+
+	// LastError is the most recent error logging in, queueing, or managing
+	// the lobby connection, or nil if there hasn't been one
+	LastError error
+
+	// LastErrorAt is when LastError occurred
+	LastErrorAt time.Time
+}
+
+// Supervisor runs several AI personalities concurrently, each with its own
+// login and queue loop as in PlayWithConn, while sharing a single limit on
+// the number of games played concurrently across all of them.
+type Supervisor struct {
+	personalities []Personality
+	players       []*player
+	gameSlots     *GameSlots
+
+	mutex    sync.Mutex
+	running  bool
+	shutdown bool
+	wg       sync.WaitGroup
+}
+
+// NewSupervisor initializes a supervisor for the given personalities which
+// plays at most maxConcurrentGames games at a time across all of them, or
+// any number of games if maxConcurrentGames is zero. The supervisor does
+// not start until Run is called.
+func NewSupervisor(personalities []Personality, maxConcurrentGames int) *Supervisor {
+	gameSlots := NewGameSlots(maxConcurrentGames)
+
+	res := &Supervisor{
+		personalities: make([]Personality, len(personalities)),
+		players:       make([]*player, len(personalities)),
+		gameSlots:     gameSlots,
+	}
+	for idx, personality := range personalities {
+		if personality.Name == "" {
+			personality.Name = personality.Config.AIConfig.AIName
+		}
+		res.personalities[idx] = personality
+		res.players[idx] = newPlayer(personality.Config, personality.GameConstructor, gameSlots)
+	}
+	return res
+}
+
+// Run all the personalities until Shutdown is called and every personality
+// has finished its games. A supervisor can only be run once.
+func (s *Supervisor) Run() {
+	s.mutex.Lock()
+	if s.running {
+		s.mutex.Unlock()
+		panic("Supervisor.Run called more than once")
+	}
+	s.running = true
+	if s.shutdown {
+		s.mutex.Unlock()
+		return
+	}
+
+	for idx, p := range s.players {
+		s.wg.Add(1)
+		go func(name string, p *player) {
+			defer s.wg.Done()
+			log.Printf("Starting personality %s", name)
+			p.run()
+			log.Printf("Personality %s stopped", name)
+		}(s.personalities[idx].Name, p)
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+}
+
+// Shutdown stops every personality, closing their games and lobby
+// connections. This may be called from any goroutine and more than once.
+// Run returns once every personality has stopped.
+func (s *Supervisor) Shutdown() {
+	s.mutex.Lock()
+	s.shutdown = true
+	s.mutex.Unlock()
+
+	for _, p := range s.players {
+		p.stop()
+	}
+}
+
+// Status returns the current status of each personality, in the order they
+// were given to NewSupervisor
+func (s *Supervisor) Status() []PersonalityStatus {
+	res := make([]PersonalityStatus, len(s.players))
+	for idx, p := range s.players {
+		res[idx] = p.status()
+		res[idx].Name = s.personalities[idx].Name
+		res[idx].Role = s.personalities[idx].Config.AIConfig.Role
+		res[idx].Version = s.personalities[idx].Config.AIConfig.Version
+	}
+	return res
+}
+
+// GameSlots returns the slots shared by every personality, which can be
+// used to see how many games are being played in total
+func (s *Supervisor) GameSlots() *GameSlots {
+	return s.gameSlots
+}
@@ -0,0 +1,100 @@
+package pkg_test
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	cos "github.com/calamity-of-subterfuge/cos/pkg"
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+)
+
+func TestGameSlots_limitsConcurrent(t *testing.T) {
+	var seed int64
+	for seed = 0; seed < 20; seed++ {
+		rand.Seed(seed)
+		max := 1 + rand.Intn(5)
+		slots := cos.NewGameSlots(max)
+
+		var wg sync.WaitGroup
+		var mutex sync.Mutex
+		highest := 0
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					if !slots.TryAcquire() {
+						continue
+					}
+
+					inUse := slots.InUse()
+					mutex.Lock()
+					if inUse > highest {
+						highest = inUse
+					}
+					mutex.Unlock()
+					slots.Release()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if highest > max {
+			t.Errorf("seed %d: %d slots in use with max %d", seed, highest, max)
+		}
+		if slots.InUse() != 0 {
+			t.Errorf("seed %d: expected all slots released, got %d", seed, slots.InUse())
+		}
+	}
+}
+
+func TestGameSlots_unlimited(t *testing.T) {
+	slots := cos.NewGameSlots(0)
+	for i := 0; i < 100; i++ {
+		if !slots.TryAcquire() {
+			t.Fatalf("expected unlimited slots, failed at %d", i)
+		}
+	}
+	if slots.InUse() != 100 {
+		t.Errorf("expected 100 in use, got %d", slots.InUse())
+	}
+}
+
+func TestSupervisor_shutdownBeforeRun(t *testing.T) {
+	personalities := []cos.Personality{
+		{Config: &cos.Config{AIConfig: &cos.AIConfig{AIName: "econ", Role: utils.RoleEconomyAI, Version: "1.0.0"}}},
+		{Name: "war", Config: &cos.Config{AIConfig: &cos.AIConfig{AIName: "mil", Role: utils.RoleMilitaryAI, Version: "2.0.0"}}},
+	}
+	supervisor := cos.NewSupervisor(personalities, 3)
+
+	status := supervisor.Status()
+	if len(status) != 2 || status[0].Name != "econ" || status[1].Name != "war" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if status[1].Role != utils.RoleMilitaryAI || status[1].Version != "2.0.0" {
+		t.Errorf("expected role and version from config, got %+v", status[1])
+	}
+	for _, s := range status {
+		if s.State != cos.PersonalityStopped || s.ActiveGames != 0 {
+			t.Errorf("expected stopped personality before run, got %+v", s)
+		}
+	}
+	if supervisor.GameSlots().Max() != 3 {
+		t.Errorf("expected shared limit of 3, got %d", supervisor.GameSlots().Max())
+	}
+
+	supervisor.Shutdown()
+	done := make(chan struct{})
+	go func() {
+		supervisor.Run()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected Run to return immediately after Shutdown")
+	}
+}
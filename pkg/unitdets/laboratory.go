@@ -0,0 +1,52 @@
+package unitdets
+
+// LaboratoryResearch describes a single research project in a laboratory
+type LaboratoryResearch struct {
+	// UID of this research project, which is distinct from the technology
+	// since the same technology may be queued in multiple laboratories
+	UID string `json:"uid" mapstructure:"uid"`
+
+	// Technology is the name of the technology being researched
+	Technology string `json:"technology" mapstructure:"technology"`
+
+	// Duration is the game time in seconds the research takes to complete
+	// once it reaches the front of the queue
+	Duration float64 `json:"duration" mapstructure:"duration"`
+}
+
+// LaboratorySyncDetails is the information provided under Additional for a
+// laboratory unit during a sync event, typically a GameSync or
+// SmartObjectAdded. If the laboratory is not owned by the player's team then
+// the queue is always empty.
+type LaboratorySyncDetails struct {
+	// Owner is the uid of the player which can queue research in this
+	// laboratory, or blank if no player owns it
+	Owner string `json:"owner" mapstructure:"owner"`
+
+	// Queue is the research queued in this laboratory, in order. The first
+	// research is the one in progress.
+	Queue []LaboratoryResearch `json:"queue" mapstructure:"queue"`
+
+	// Progress is how much of the first research in the queue is complete,
+	// from 0 to 1
+	Progress float64 `json:"progress" mapstructure:"progress"`
+}
+
+// LaboratoryUpdateDetails contains the information under Additional for a
+// laboratory unit during an update event, typically SmartObjectUpdate.
+type LaboratoryUpdateDetails struct {
+	// Owner is the uid of the player which can queue research in this
+	// laboratory, or blank if no player owns it
+	Owner string `json:"owner" mapstructure:"owner"`
+
+	// Progress is how much of the first research in the queue is complete,
+	// from 0 to 1, after the removals and additions in this update
+	Progress float64 `json:"progress" mapstructure:"progress"`
+
+	// RemovedResearch is the slice of research uids which are no longer in the
+	// queue, either because they were completed or cancelled
+	RemovedResearch []string `json:"removed_research" mapstructure:"removed_research"`
+
+	// AddedResearch is the research added to the end of the queue, in order
+	AddedResearch []LaboratoryResearch `json:"added_research" mapstructure:"added_research"`
+}
@@ -1,32 +1,102 @@
 package utils
 
-import "github.com/jakecoffman/cp"
+import (
+	"math"
 
-// BodyBodyDistanceSq approximates the squared distance between the two bodies.
-// This is stable but may be an overestimate of the squared distance.
-func BodyBodyDistanceSq(a *cp.Body, b *cp.Body) float64 {
-	var resSq float64 = -1
+	"github.com/jakecoffman/cp"
+)
 
-	// I'm not really sure why there isn't a builtin function to do this?
-	// I don't even think this is even necessarily always correct, but it
-	// should be very close
+// BodyBodyClosestPoints computes the exact closest points between the two
+// bodies, i.e., the closest points between any shape on the first body and
+// any shape on the second body. If either body has no shapes the Distance
+// is positive infinity.
+func BodyBodyClosestPoints(a *cp.Body, b *cp.Body) ClosestPoints {
+	res := ClosestPoints{Distance: math.Inf(1)}
 
 	a.EachShape(func(s1 *cp.Shape) {
-		b.EachShape(func(s2 *cp.Shape) {
-			s1NearestPoint := s1.PointQuery(b.LocalToWorld(s2.CenterOfGravity()))
-			s2NearestPoint := s2.PointQuery(a.LocalToWorld(s1.CenterOfGravity()))
+		if res.Distance <= 0 {
+			return
+		}
 
-			if s1NearestPoint.Distance <= 0 || s2NearestPoint.Distance <= 0 {
-				resSq = 0
+		b.EachShape(func(s2 *cp.Shape) {
+			if res.Distance <= 0 {
 				return
 			}
 
-			distanceSq := s2NearestPoint.Point.Sub(s1NearestPoint.Point).LengthSq()
-			if resSq == -1 || distanceSq < resSq {
-				resSq = distanceSq
+			points := ShapeShapeClosestPoints(s1, s2)
+			if points.Distance < res.Distance {
+				res = points
 			}
 		})
 	})
 
-	return resSq
+	return res
+}
+
+// BodyBodyDistance computes the exact distance between the two bodies, which
+// is 0 if they overlap and positive infinity if either has no shapes.
+func BodyBodyDistance(a *cp.Body, b *cp.Body) float64 {
+	return BodyBodyClosestPoints(a, b).Distance
+}
+
+// BodyBodyDistanceSq computes the exact squared distance between the two
+// bodies. This is convenient for comparing against squared ranges such as
+// MINE_DISTANCE * MINE_DISTANCE.
+func BodyBodyDistanceSq(a *cp.Body, b *cp.Body) float64 {
+	dist := BodyBodyDistance(a, b)
+	return dist * dist
+}
+
+// BodiesOverlap returns true if any shape on the first body overlaps or
+// touches any shape on the second body.
+func BodiesOverlap(a *cp.Body, b *cp.Body) bool {
+	return BodyBodyClosestPoints(a, b).Overlapping()
+}
+
+// BodyPointQuery finds the point on the given body which is nearest to the
+// given point. The Distance on the result is negative if the point is within
+// the body, and the Shape is nil if the body has no shapes.
+func BodyPointQuery(body *cp.Body, point cp.Vector) cp.PointQueryInfo {
+	res := cp.PointQueryInfo{Distance: math.Inf(1)}
+
+	body.EachShape(func(s *cp.Shape) {
+		info := s.PointQuery(point)
+		if info.Distance < res.Distance {
+			res = info
+		}
+	})
+
+	return res
+}
+
+// BodyPointDistance computes the exact distance between the body and the
+// point, which is 0 if the point is within the body.
+func BodyPointDistance(body *cp.Body, point cp.Vector) float64 {
+	return math.Max(0, BodyPointQuery(body, point).Distance)
+}
+
+// BodySegmentClosestPoints computes the exact closest points between the
+// body and the segment from start to end, thickened by the given radius. B
+// is the point on the segment.
+func BodySegmentClosestPoints(body *cp.Body, start, end cp.Vector, radius float64) ClosestPoints {
+	res := ClosestPoints{Distance: math.Inf(1)}
+
+	body.EachShape(func(s *cp.Shape) {
+		if res.Distance <= 0 {
+			return
+		}
+
+		points := ShapeSegmentClosestPoints(s, start, end, radius)
+		if points.Distance < res.Distance {
+			res = points
+		}
+	})
+
+	return res
+}
+
+// BodySegmentDistance computes the exact distance between the body and the
+// segment from start to end, which is 0 if they overlap.
+func BodySegmentDistance(body *cp.Body, start, end cp.Vector) float64 {
+	return BodySegmentClosestPoints(body, start, end, 0).Distance
 }
@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"log"
+	"math"
+
+	"github.com/jakecoffman/cp"
+)
+
+// gjkMaxIterations is the maximum number of iterations of the GJK loop before
+// we accept whatever the current best estimate is. Shapes in the game have a
+// handful of vertices so this is never reached in practice.
+const gjkMaxIterations = 64
+
+// gjkTolerance is the relative tolerance used to decide that the GJK loop has
+// converged.
+const gjkTolerance = 1e-12
+
+// ClosestPoints describes the closest pair of points between two convex
+// objects, typically shapes or bodies.
+type ClosestPoints struct {
+	// A is the point on the first object which is closest to the second
+	// object, in world coordinates.
+	A cp.Vector
+
+	// B is the point on the second object which is closest to the first
+	// object, in world coordinates.
+	B cp.Vector
+
+	// Distance is the distance between A and B. This is 0 if the objects
+	// overlap or touch, in which case A and B are the same point and that
+	// point is within both objects.
+	Distance float64
+}
+
+// Overlapping returns true if the two objects that these closest points were
+// computed from overlap or touch.
+func (p ClosestPoints) Overlapping() bool {
+	return p.Distance <= 0
+}
+
+// convexCore describes a convex object as a convex core, which is the convex
+// hull of a set of points, expanded by a radius in every direction. Every
+// chipmunk shape can be described this way.
+type convexCore struct {
+	points []cp.Vector
+	radius float64
+}
+
+// support returns the point in the core which is furthest in the direction d
+func (c *convexCore) support(d cp.Vector) cp.Vector {
+	best := c.points[0]
+	bestDot := best.Dot(d)
+	for _, pt := range c.points[1:] {
+		if dot := pt.Dot(d); dot > bestDot {
+			best = pt
+			bestDot = dot
+		}
+	}
+	return best
+}
+
+// shapeCore describes the given shape in world coordinates. The shape must
+// have been updated with the transform of its body for this to be accurate.
+func shapeCore(shape *cp.Shape) convexCore {
+	switch v := shape.Class.(type) {
+	case *cp.PolyShape:
+		points := make([]cp.Vector, v.Count())
+		for i := range points {
+			points[i] = v.TransformVert(i)
+		}
+		return convexCore{points: points, radius: v.Radius()}
+	case *cp.Circle:
+		return convexCore{points: []cp.Vector{v.TransformC()}, radius: v.Radius()}
+	case *cp.Segment:
+		return convexCore{points: []cp.Vector{v.TransformA(), v.TransformB()}, radius: v.Radius()}
+	default:
+		log.Panicf("unknown shape class: %T", shape.Class)
+		return convexCore{}
+	}
+}
+
+// simplexPoint is a point on the minkowski difference of two cores alongside
+// the points on the original cores that produced it.
+type simplexPoint struct {
+	a cp.Vector
+	b cp.Vector
+	w cp.Vector
+}
+
+// simplexClosest is the closest point to the origin on a simplex, described
+// by the reduced simplex which contains it and the barycentric coordinates
+// of the point within the reduced simplex.
+type simplexClosest struct {
+	simplex []simplexPoint
+	lambdas []float64
+	point   cp.Vector
+}
+
+func closestOnSegment(p0, p1 simplexPoint) simplexClosest {
+	edge := p1.w.Sub(p0.w)
+	lenSq := edge.LengthSq()
+	if lenSq == 0 {
+		return simplexClosest{simplex: []simplexPoint{p0}, lambdas: []float64{1}, point: p0.w}
+	}
+
+	t := -p0.w.Dot(edge) / lenSq
+	if t <= 0 {
+		return simplexClosest{simplex: []simplexPoint{p0}, lambdas: []float64{1}, point: p0.w}
+	}
+	if t >= 1 {
+		return simplexClosest{simplex: []simplexPoint{p1}, lambdas: []float64{1}, point: p1.w}
+	}
+	return simplexClosest{
+		simplex: []simplexPoint{p0, p1},
+		lambdas: []float64{1 - t, t},
+		point:   p0.w.Add(edge.Mult(t)),
+	}
+}
+
+// closestOnTriangle finds the closest point on the triangle to the origin. The
+// second result is true if the origin is within the triangle, in which case the
+// closest point is the origin itself.
+func closestOnTriangle(p0, p1, p2 simplexPoint) (simplexClosest, bool) {
+	area := p1.w.Sub(p0.w).Cross(p2.w.Sub(p0.w))
+	if area != 0 {
+		// barycentric coordinates of the origin
+		l0 := p1.w.Cross(p2.w) / area
+		l1 := p2.w.Cross(p0.w) / area
+		l2 := p0.w.Cross(p1.w) / area
+		if l0 >= 0 && l1 >= 0 && l2 >= 0 {
+			return simplexClosest{
+				simplex: []simplexPoint{p0, p1, p2},
+				lambdas: []float64{l0, l1, l2},
+				point:   cp.Vector{},
+			}, true
+		}
+	}
+
+	best := closestOnSegment(p0, p1)
+	for _, candidate := range [2]simplexClosest{closestOnSegment(p1, p2), closestOnSegment(p0, p2)} {
+		if candidate.point.LengthSq() < best.point.LengthSq() {
+			best = candidate
+		}
+	}
+	return best, false
+}
+
+// coreClosestPoints finds the closest points between the two cores, ignoring
+// their radii, using the GJK algorithm. The last result is true if the cores
+// overlap, in which case both points are the same point within both cores.
+func coreClosestPoints(a, b *convexCore) (cp.Vector, cp.Vector, bool) {
+	supportOf := func(d cp.Vector) simplexPoint {
+		pa := a.support(d)
+		pb := b.support(d.Neg())
+		return simplexPoint{a: pa, b: pb, w: pa.Sub(pb)}
+	}
+
+	initial := supportOf(cp.Vector{X: 1})
+	closest := simplexClosest{
+		simplex: []simplexPoint{initial},
+		lambdas: []float64{1},
+		point:   initial.w,
+	}
+	overlapping := false
+
+gjkLoop:
+	for iter := 0; iter < gjkMaxIterations; iter++ {
+		v := closest.point
+		vLenSq := v.LengthSq()
+		if vLenSq == 0 {
+			overlapping = true
+			break
+		}
+
+		next := supportOf(v.Neg())
+		if vLenSq-v.Dot(next.w) <= gjkTolerance*math.Max(1, vLenSq) {
+			break
+		}
+
+		for _, existing := range closest.simplex {
+			if existing.w == next.w {
+				break gjkLoop
+			}
+		}
+
+		switch len(closest.simplex) {
+		case 1:
+			closest = closestOnSegment(closest.simplex[0], next)
+		case 2:
+			closest, overlapping = closestOnTriangle(closest.simplex[0], closest.simplex[1], next)
+			if overlapping {
+				break gjkLoop
+			}
+		}
+	}
+
+	var pa, pb cp.Vector
+	for idx, pt := range closest.simplex {
+		pa = pa.Add(pt.a.Mult(closest.lambdas[idx]))
+		pb = pb.Add(pt.b.Mult(closest.lambdas[idx]))
+	}
+	if overlapping {
+		return pa, pa, true
+	}
+	return pa, pb, false
+}
+
+// coreCoreClosestPoints finds the closest points between the two cores
+// including their radii.
+func coreCoreClosestPoints(a, b *convexCore) ClosestPoints {
+	pa, pb, overlapping := coreClosestPoints(a, b)
+	if overlapping {
+		return ClosestPoints{A: pa, B: pa, Distance: 0}
+	}
+
+	coreDist := pa.Distance(pb)
+	radii := a.radius + b.radius
+	if coreDist <= radii {
+		// the rounding overlaps; this point is within a.radius of pa and
+		// within b.radius of pb
+		var witness cp.Vector
+		if radii > 0 {
+			witness = pa.Lerp(pb, a.radius/radii)
+		} else {
+			witness = pa
+		}
+		return ClosestPoints{A: witness, B: witness, Distance: 0}
+	}
+
+	normal := pb.Sub(pa).Mult(1 / coreDist)
+	return ClosestPoints{
+		A:        pa.Add(normal.Mult(a.radius)),
+		B:        pb.Sub(normal.Mult(b.radius)),
+		Distance: coreDist - radii,
+	}
+}
+
+// ShapeShapeClosestPoints computes the exact closest points between the two
+// shapes. The shapes must have been updated to the transform of their bodies,
+// which is done automatically by the client package.
+func ShapeShapeClosestPoints(a, b *cp.Shape) ClosestPoints {
+	coreA := shapeCore(a)
+	coreB := shapeCore(b)
+	return coreCoreClosestPoints(&coreA, &coreB)
+}
+
+// ShapeShapeDistance computes the exact distance between the two shapes, which
+// is 0 if they overlap.
+func ShapeShapeDistance(a, b *cp.Shape) float64 {
+	return ShapeShapeClosestPoints(a, b).Distance
+}
+
+// ShapesOverlap returns true if the two shapes overlap or touch.
+func ShapesOverlap(a, b *cp.Shape) bool {
+	return ShapeShapeClosestPoints(a, b).Overlapping()
+}
+
+// ShapeSegmentClosestPoints computes the exact closest points between the
+// shape and the segment from start to end, which is thickened by the given
+// radius. B is the point on the segment.
+func ShapeSegmentClosestPoints(shape *cp.Shape, start, end cp.Vector, radius float64) ClosestPoints {
+	coreA := shapeCore(shape)
+	coreB := convexCore{points: []cp.Vector{start, end}, radius: radius}
+	return coreCoreClosestPoints(&coreA, &coreB)
+}
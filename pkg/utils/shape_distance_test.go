@@ -0,0 +1,297 @@
+package utils_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+	"github.com/jakecoffman/cp"
+)
+
+const distanceEpsilon = 1e-7
+
+func randomPolyShape(body *cp.Body) *cp.Shape {
+	numVerts := 3 + rand.Intn(6)
+	size := 0.1 + rand.Float64()*3
+	verts := make([]cp.Vector, numVerts)
+	for i := range verts {
+		verts[i] = cp.Vector{X: (rand.Float64()*2 - 1) * size, Y: (rand.Float64()*2 - 1) * size}
+	}
+
+	var radius float64
+	if rand.Intn(2) == 0 {
+		radius = rand.Float64() * 0.5
+	}
+
+	shape := cp.NewPolyShape(body, numVerts, verts, cp.NewTransformIdentity(), radius)
+	body.AddShape(shape)
+	return shape
+}
+
+func randomTransform() cp.Transform {
+	return cp.NewTransformRigid(
+		cp.Vector{X: (rand.Float64()*2 - 1) * 8, Y: (rand.Float64()*2 - 1) * 8},
+		rand.Float64()*2*math.Pi,
+	)
+}
+
+func randomBody(numShapes int) *cp.Body {
+	body := cp.NewBody(0, 0)
+	for i := 0; i < numShapes; i++ {
+		randomPolyShape(body)
+	}
+	transform := randomTransform()
+	body.EachShape(func(s *cp.Shape) {
+		s.Update(transform)
+	})
+	return body
+}
+
+func worldVerts(shape *cp.Shape) ([]cp.Vector, float64) {
+	poly := shape.Class.(*cp.PolyShape)
+	verts := make([]cp.Vector, poly.Count())
+	for i := range verts {
+		verts[i] = poly.TransformVert(i)
+	}
+	return verts, poly.Radius()
+}
+
+func bruteInsideConvex(pt cp.Vector, verts []cp.Vector) bool {
+	if len(verts) < 3 {
+		return false
+	}
+
+	sawPos, sawNeg := false, false
+	for i := range verts {
+		a := verts[i]
+		b := verts[(i+1)%len(verts)]
+		cross := b.Sub(a).Cross(pt.Sub(a))
+		if cross > 0 {
+			sawPos = true
+		} else if cross < 0 {
+			sawNeg = true
+		}
+	}
+	return !(sawPos && sawNeg)
+}
+
+func bruteSegmentsIntersect(a, b, c, d cp.Vector) bool {
+	d1 := b.Sub(a).Cross(c.Sub(a))
+	d2 := b.Sub(a).Cross(d.Sub(a))
+	d3 := d.Sub(c).Cross(a.Sub(c))
+	d4 := d.Sub(c).Cross(b.Sub(c))
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+func bruteEdges(verts []cp.Vector) [][2]cp.Vector {
+	if len(verts) == 2 {
+		return [][2]cp.Vector{{verts[0], verts[1]}}
+	}
+	res := make([][2]cp.Vector, len(verts))
+	for i := range verts {
+		res[i] = [2]cp.Vector{verts[i], verts[(i+1)%len(verts)]}
+	}
+	return res
+}
+
+// bruteCoreDistance computes the distance between two convex polygons by
+// checking every vertex against every edge.
+func bruteCoreDistance(a, b []cp.Vector) float64 {
+	for _, v := range a {
+		if bruteInsideConvex(v, b) {
+			return 0
+		}
+	}
+	for _, v := range b {
+		if bruteInsideConvex(v, a) {
+			return 0
+		}
+	}
+
+	edgesA := bruteEdges(a)
+	edgesB := bruteEdges(b)
+	for _, ea := range edgesA {
+		for _, eb := range edgesB {
+			if bruteSegmentsIntersect(ea[0], ea[1], eb[0], eb[1]) {
+				return 0
+			}
+		}
+	}
+
+	res := math.Inf(1)
+	for _, v := range a {
+		for _, e := range edgesB {
+			res = math.Min(res, v.Distance(v.ClosestPointOnSegment(e[0], e[1])))
+		}
+	}
+	for _, v := range b {
+		for _, e := range edgesA {
+			res = math.Min(res, v.Distance(v.ClosestPointOnSegment(e[0], e[1])))
+		}
+	}
+	return res
+}
+
+func bruteShapeShapeDistance(a, b *cp.Shape) float64 {
+	vertsA, radiusA := worldVerts(a)
+	vertsB, radiusB := worldVerts(b)
+	return math.Max(0, bruteCoreDistance(vertsA, vertsB)-radiusA-radiusB)
+}
+
+func checkClosestPoints(t *testing.T, seed int64, points utils.ClosestPoints, a, b *cp.Shape) {
+	t.Helper()
+
+	if math.Abs(points.A.Distance(points.B)-points.Distance) > distanceEpsilon {
+		t.Errorf("seed %d: |A-B| = %v but Distance = %v", seed, points.A.Distance(points.B), points.Distance)
+	}
+
+	if points.Overlapping() {
+		if d := a.PointQuery(points.A).Distance; d > distanceEpsilon {
+			t.Errorf("seed %d: overlap witness %v is %v outside of a", seed, points.A, d)
+		}
+		if d := b.PointQuery(points.B).Distance; d > distanceEpsilon {
+			t.Errorf("seed %d: overlap witness %v is %v outside of b", seed, points.B, d)
+		}
+		return
+	}
+
+	if d := a.PointQuery(points.A).Distance; math.Abs(d) > distanceEpsilon {
+		t.Errorf("seed %d: A = %v is not on the surface of a (distance %v)", seed, points.A, d)
+	}
+	if d := b.PointQuery(points.B).Distance; math.Abs(d) > distanceEpsilon {
+		t.Errorf("seed %d: B = %v is not on the surface of b (distance %v)", seed, points.B, d)
+	}
+}
+
+func TestShapeShapeClosestPoints_rand(t *testing.T) {
+	var seed int64
+	for seed = 0; seed < 5_000; seed++ {
+		rand.Seed(seed)
+
+		a := randomBody(1)
+		b := randomBody(1)
+		var shapeA, shapeB *cp.Shape
+		a.EachShape(func(s *cp.Shape) { shapeA = s })
+		b.EachShape(func(s *cp.Shape) { shapeB = s })
+
+		points := utils.ShapeShapeClosestPoints(shapeA, shapeB)
+		expected := bruteShapeShapeDistance(shapeA, shapeB)
+		if math.Abs(points.Distance-expected) > distanceEpsilon {
+			t.Fatalf("seed %d: got distance %v, expected %v", seed, points.Distance, expected)
+		}
+
+		checkClosestPoints(t, seed, points, shapeA, shapeB)
+
+		if utils.ShapesOverlap(shapeA, shapeB) != (expected == 0) {
+			t.Fatalf("seed %d: ShapesOverlap disagrees with distance %v", seed, expected)
+		}
+	}
+}
+
+func TestShapeShapeClosestPoints_symmetric(t *testing.T) {
+	var seed int64
+	for seed = 0; seed < 1_000; seed++ {
+		rand.Seed(seed)
+
+		a := randomBody(1)
+		b := randomBody(1)
+		var shapeA, shapeB *cp.Shape
+		a.EachShape(func(s *cp.Shape) { shapeA = s })
+		b.EachShape(func(s *cp.Shape) { shapeB = s })
+
+		ab := utils.ShapeShapeDistance(shapeA, shapeB)
+		ba := utils.ShapeShapeDistance(shapeB, shapeA)
+		if math.Abs(ab-ba) > distanceEpsilon {
+			t.Fatalf("seed %d: distance a->b = %v but b->a = %v", seed, ab, ba)
+		}
+	}
+}
+
+func TestBodyBodyDistanceSq_rand(t *testing.T) {
+	var seed int64
+	for seed = 0; seed < 1_000; seed++ {
+		rand.Seed(seed)
+
+		a := randomBody(1 + rand.Intn(3))
+		b := randomBody(1 + rand.Intn(3))
+
+		expected := math.Inf(1)
+		a.EachShape(func(s1 *cp.Shape) {
+			b.EachShape(func(s2 *cp.Shape) {
+				expected = math.Min(expected, bruteShapeShapeDistance(s1, s2))
+			})
+		})
+
+		got := utils.BodyBodyDistanceSq(a, b)
+		if math.Abs(got-expected*expected) > distanceEpsilon {
+			t.Fatalf("seed %d: got squared distance %v, expected %v", seed, got, expected*expected)
+		}
+
+		if utils.BodiesOverlap(a, b) != (expected == 0) {
+			t.Fatalf("seed %d: BodiesOverlap disagrees with distance %v", seed, expected)
+		}
+	}
+}
+
+func TestBodyBodyDistance_noShapes(t *testing.T) {
+	a := randomBody(1)
+	b := cp.NewBody(0, 0)
+
+	if dist := utils.BodyBodyDistance(a, b); !math.IsInf(dist, 1) {
+		t.Errorf("expected infinite distance to a body without shapes, got %v", dist)
+	}
+}
+
+func TestBodySegmentDistance_rand(t *testing.T) {
+	var seed int64
+	for seed = 0; seed < 1_000; seed++ {
+		rand.Seed(seed)
+
+		body := randomBody(1)
+		start := cp.Vector{X: (rand.Float64()*2 - 1) * 10, Y: (rand.Float64()*2 - 1) * 10}
+		end := cp.Vector{X: (rand.Float64()*2 - 1) * 10, Y: (rand.Float64()*2 - 1) * 10}
+
+		var expected float64
+		body.EachShape(func(s *cp.Shape) {
+			verts, radius := worldVerts(s)
+			expected = math.Max(0, bruteCoreDistance(verts, []cp.Vector{start, end})-radius)
+		})
+
+		got := utils.BodySegmentDistance(body, start, end)
+		if math.Abs(got-expected) > distanceEpsilon {
+			t.Fatalf("seed %d: got distance %v, expected %v", seed, got, expected)
+		}
+	}
+}
+
+func TestBodyPointDistance_rand(t *testing.T) {
+	var seed int64
+	for seed = 0; seed < 1_000; seed++ {
+		rand.Seed(seed)
+
+		body := randomBody(1)
+		point := cp.Vector{X: (rand.Float64()*2 - 1) * 10, Y: (rand.Float64()*2 - 1) * 10}
+
+		var expected float64
+		body.EachShape(func(s *cp.Shape) {
+			verts, radius := worldVerts(s)
+			expected = math.Max(0, bruteCoreDistance(verts, []cp.Vector{point, point})-radius)
+		})
+
+		got := utils.BodyPointDistance(body, point)
+		if math.Abs(got-expected) > distanceEpsilon {
+			t.Fatalf("seed %d: got distance %v, expected %v", seed, got, expected)
+		}
+	}
+}
+
+func BenchmarkBodyBodyDistanceSq(b *testing.B) {
+	rand.Seed(1)
+	bodyA := randomBody(1)
+	bodyB := randomBody(1)
+
+	for i := 0; i < b.N; i++ {
+		utils.BodyBodyDistanceSq(bodyA, bodyB)
+	}
+}
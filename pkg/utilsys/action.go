@@ -1,6 +1,9 @@
 package utilsys
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type ActionState int
 
@@ -27,6 +30,28 @@ const (
 	ActionStateFailure ActionState = 5
 )
 
+var actionStateNames = map[ActionState]string{
+	ActionStateInit:      "init",
+	ActionStateRequested: "requested",
+	ActionStateExecuting: "executing",
+	ActionStateCanceled:  "canceled",
+	ActionStateSuccess:   "success",
+	ActionStateFailure:   "failure",
+}
+
+// String returns the lowercase name of the state, e.g., "executing"
+func (s ActionState) String() string {
+	if name, ok := actionStateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("ActionState(%d)", int(s))
+}
+
+// MarshalText marshals the state as its name
+func (s ActionState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 // Action is a stateful object that acts upon a given actor in a given world.
 // These are produced by action builders, which are what go into the AI.
 type Action interface {
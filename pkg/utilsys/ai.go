@@ -8,6 +8,11 @@ import (
 type actionActor struct {
 	actor  interface{}
 	action Action
+	ctx    *actionContext
+
+	// builder is the builder for the action of this actor, or nil if it
+	// uses the core action
+	builder ActionBuilder
 }
 
 // AI runs Actions on all the actors within the world.
@@ -16,6 +21,17 @@ type AI struct {
 	coreAction ActionBuilder
 
 	actors []actionActor
+	// indices of each actor within actors
+	indices map[interface{}]int
+
+	clock     Clock
+	tracer    Tracer
+	actorName func(actor interface{}) string
+	ticks     uint64
+
+	// workers is the number of goroutines which score ahead of time, or 0
+	// to score while executing
+	workers int
 }
 
 // NewAI constructs a new AI within the given world, which uses the given
@@ -26,38 +42,216 @@ func NewAI(world interface{}, coreAction ActionBuilder) *AI {
 		world:      world,
 		coreAction: coreAction,
 		actors:     make([]actionActor, 0),
+		indices:    make(map[interface{}]int),
+		actorName:  DefaultActorName,
+	}
+}
+
+// SetClock sets the clock used by the actions of every actor which measure
+// time, such as cooldowns and idling, unless they were given their own, or
+// goes back to the defaults if clock is nil. By default cooldowns use the
+// wall clock and idling counts down the deltas given to Tick; a
+// GameTimeClock is usually more accurate.
+func (ai *AI) SetClock(clock Clock) {
+	ai.clock = clock
+}
+
+// SetTracer sets the tracer which records the decisions made for every
+// actor, or disables tracing if tracer is nil. The actorName function names
+// actors in the trace, and may be nil to use DefaultActorName. Tracing
+// explains every score, so it shouldn't be left on when it isn't needed.
+func (ai *AI) SetTracer(tracer Tracer, actorName func(actor interface{}) string) {
+	if actorName == nil {
+		actorName = DefaultActorName
+	}
+	ai.tracer = tracer
+	ai.actorName = actorName
+	for _, actorAction := range ai.actors {
+		actorAction.ctx.name = actorName(actorAction.actor)
+	}
+}
+
+// SetParallelScoring enables scoring across the given number of worker
+// goroutines, or disables it if workers is less than 2. Typically workers is
+// runtime.GOMAXPROCS(0). Disabled by default.
+//
+// When enabled, each Tick starts by scoring every thinker which is about to
+// select or re-evaluate its children, across the workers, before any action
+// is executed. The workers are new goroutines started on every Tick, so this
+// only pays off when there's enough scoring per tick to outweigh starting
+// them. The actions are then executed in order on the calling
+// goroutine just as when disabled, with the thinkers using the scores from
+// the start of the tick. Since nothing else runs during scoring, the world
+// acts as a read-only snapshot, e.g., a client.State which is only updated
+// from the same goroutine which ticks the AI.
+//
+// Scorers of different actors are run concurrently, so they must not write
+// to anything shared between actors, such as the shared blackboard, though
+// they may read it. Scorers of the same actor are never run concurrently.
+// Thinkers are scored ahead of time when they're the action of an actor, or
+// the running child of such a thinker, optionally wrapped with a
+// NamedAction or a CooldownQualifiedScoredAction; other thinkers score
+// while executing.
+func (ai *AI) SetParallelScoring(workers int) {
+	if workers < 2 {
+		workers = 0
+	}
+	ai.workers = workers
+}
+
+// SetCoreAction replaces the core action used for all actors, rebuilding the
+// actions of the actors already handled by this AI. Any of their old actions
+// which are running are canceled so they can clean up, but they aren't given
+// a chance to finish canceling. Actors which were added with their own
+// action are unaffected.
+//
+// performance: O(n) where n is the number of actors
+func (ai *AI) SetCoreAction(coreAction ActionBuilder) {
+	ai.coreAction = coreAction
+	for idx := range ai.actors {
+		if ai.actors[idx].builder == nil {
+			ai.rebuild(&ai.actors[idx])
+		}
 	}
 }
 
-// AddActor adds the given actor to be handled by this AI.
+// RebuildActors rebuilds the actions of the actors whose actions are built
+// by the given builder, whether it's the core action or the actor was added
+// with it, e.g., by a StateBinder. As with SetCoreAction, their old actions
+// are canceled if they're running. The builder must be comparable, e.g., a
+// pointer such as a HotReloader.
+//
+// performance: O(n) where n is the number of actors
+func (ai *AI) RebuildActors(builder ActionBuilder) {
+	usesCore := ai.coreAction == builder
+	for idx := range ai.actors {
+		actorAction := &ai.actors[idx]
+		if actorAction.builder == builder || (usesCore && actorAction.builder == nil) {
+			ai.rebuild(actorAction)
+		}
+	}
+}
+
+// rebuild cancels the actor's action if it's running and replaces it with a
+// new action from its builder
+func (ai *AI) rebuild(actorAction *actionActor) {
+	cancelRunning(actorAction.action)
+
+	builder := actorAction.builder
+	if builder == nil {
+		builder = ai.coreAction
+	}
+	actorAction.action = builder.Build()
+	setContext(actorAction.action, actorAction.ctx)
+	actorAction.action.Attached(ai.world, actorAction.actor)
+	actorAction.ctx.transition(ActionStateInit, actorAction.action.State())
+}
+
+// AddActor adds the given actor to be handled by this AI using the core
+// action. The actor must be comparable, and is typically a pointer. If the
+// actor is already handled by this AI, it's removed first.
 //
 // performance: O(1) amortized
 func (ai *AI) AddActor(actor interface{}) {
-	action := ai.coreAction.Build()
+	ai.addActor(actor, nil)
+}
+
+// AddActorWithAction adds the given actor to be handled by this AI using
+// an action built from the given builder rather than the core action, e.g.,
+// because the actor is a different kind of unit. Otherwise this is the same
+// as AddActor.
+//
+// performance: O(1) amortized
+func (ai *AI) AddActorWithAction(actor interface{}, builder ActionBuilder) {
+	ai.addActor(actor, builder)
+}
+
+// addActor adds the given actor using the given builder, or the core action
+// if builder is nil
+func (ai *AI) addActor(actor interface{}, builder ActionBuilder) {
+	ai.RemoveActor(actor)
+
+	var action Action
+	if builder != nil {
+		action = builder.Build()
+	} else {
+		action = ai.coreAction.Build()
+	}
+	ctx := &actionContext{
+		contextActor: &contextActor{ai: ai, actor: actor, name: ai.actorName(actor)},
+		path:         RootNode,
+	}
+	setContext(action, ctx)
 	action.Attached(ai.world, actor)
+	ctx.transition(ActionStateInit, action.State())
 
+	ai.indices[actor] = len(ai.actors)
 	ai.actors = append(ai.actors, actionActor{
-		actor:  actor,
-		action: action,
+		actor:   actor,
+		action:  action,
+		ctx:     ctx,
+		builder: builder,
 	})
 }
 
-// RemoveActor removes the given actor from being handled by this AI.
+// RemoveActor removes the given actor from being handled by this AI. If its
+// action is running it's canceled so it can clean up, but it isn't given a
+// chance to finish canceling. If the world is a BlackboardProvider, the
+// actor's blackboard is discarded. Removing an actor which isn't handled by
+// this AI does nothing. Removal changes the order in which the remaining
+// actors are ticked.
 //
-// performance: O(n) where n is the number of actors
+// performance: O(1)
 func (ai *AI) RemoveActor(actor interface{}) {
-	for idx := 0; idx < len(ai.actors); idx++ {
-		if ai.actors[idx].actor == actor {
-			ai.actors = append(ai.actors[:idx], ai.actors[idx+1:]...)
-			return
-		}
+	idx, ok := ai.indices[actor]
+	if !ok {
+		return
+	}
+
+	actorAction := ai.actors[idx]
+	before := actorAction.action.State()
+	cancelRunning(actorAction.action)
+	actorAction.ctx.transition(before, actorAction.action.State())
+
+	if provider, ok := ai.world.(BlackboardProvider); ok {
+		provider.Blackboards().Remove(actor)
+	}
+
+	last := len(ai.actors) - 1
+	if idx != last {
+		ai.actors[idx] = ai.actors[last]
+		ai.indices[ai.actors[idx].actor] = idx
+	}
+	ai.actors[last] = actionActor{}
+	ai.actors = ai.actors[:last]
+	delete(ai.indices, actor)
+}
+
+// HasActor returns true if the given actor is handled by this AI
+//
+// performance: O(1)
+func (ai *AI) HasActor(actor interface{}) bool {
+	_, ok := ai.indices[actor]
+	return ok
+}
+
+// cancelRunning cancels the given action if it's requested or executing
+func cancelRunning(action Action) {
+	switch action.State() {
+	case ActionStateRequested, ActionStateExecuting:
+		action.Cancel()
 	}
 }
 
 // Tick all of the actions for actors handled by this AI, informing
 // them the given amount of time has passed.
 func (ai *AI) Tick(delta time.Duration) {
+	ai.ticks++
+	if ai.workers > 0 {
+		ai.prescoreActors(delta)
+	}
 	for _, actorAction := range ai.actors {
+		before := actorAction.action.State()
 
 		// We cut the loop off after 2 times to avoid an infinite loop,
 		// but the idea is to allow for a full cycle of
@@ -82,5 +276,7 @@ func (ai *AI) Tick(delta time.Duration) {
 				log.Panicf("action has bad State(): %v", actorAction.action.State())
 			}
 		}
+
+		actorAction.ctx.transition(before, actorAction.action.State())
 	}
 }
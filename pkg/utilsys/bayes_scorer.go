@@ -1,6 +1,9 @@
 package utilsys
 
-import "math/big"
+import (
+	"fmt"
+	"math/big"
+)
 
 // BayesFactor describes something which can alter our prediction
 // about something by a given factor. It is stateful and only
@@ -53,6 +56,14 @@ func (s *bayesScorer) Score() float64 {
 	return f
 }
 
+func (s *bayesScorer) Explain() ScoreExplanation {
+	return ScoreExplanation{
+		Kind:   "bayes",
+		Detail: fmt.Sprintf("prior %s with %d factors", s.prior.RatString(), len(s.factors)),
+		Score:  s.Score(),
+	}
+}
+
 // BayesScorer is a type of ScorerBuilder that assumes that the utility
 // of the action is 1, but it only succeeds probabilistically. It has
 // some general chance at success, such as 1 success per 4 failures. It
@@ -0,0 +1,258 @@
+package utilsys
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jakecoffman/cp"
+)
+
+// BlackboardKey identifies a value on a blackboard. Packages should declare
+// their keys as constants to avoid typos, e.g.,
+//
+//	const TargetKey utilsys.BlackboardKey = "mine.target"
+type BlackboardKey string
+
+type blackboardEntry struct {
+	value     interface{}
+	expires   bool
+	expiresAt time.Duration
+}
+
+// Blackboard is memory shared between the scorers and actions of an actor,
+// or between all actors, so that facts can be computed once and then used
+// by everything that needs them. For example, a scorer can find the nearest
+// resource and the paired action can move to it. Values may expire, after
+// which they're treated as missing. Blackboards aren't safe for concurrent
+// use, except that values may be read concurrently while nothing writes,
+// e.g., by scorers when the AI scores in parallel.
+type Blackboard struct {
+	clock   Clock
+	entries map[BlackboardKey]blackboardEntry
+}
+
+// NewBlackboard produces an empty blackboard which uses the given clock for
+// expiry, or the wall clock if clock is nil
+func NewBlackboard(clock Clock) *Blackboard {
+	if clock == nil {
+		clock = wallClock
+	}
+	return &Blackboard{clock: clock, entries: make(map[BlackboardKey]blackboardEntry)}
+}
+
+// Set sets the value for the given key, which never expires
+func (b *Blackboard) Set(key BlackboardKey, value interface{}) {
+	b.entries[key] = blackboardEntry{value: value}
+}
+
+// SetFor sets the value for the given key, which expires after ttl
+func (b *Blackboard) SetFor(key BlackboardKey, value interface{}, ttl time.Duration) {
+	b.entries[key] = blackboardEntry{value: value, expires: true, expiresAt: b.clock.Now() + ttl}
+}
+
+// Get returns the value for the given key, and false if there is no value or
+// it has expired. Expired values are only discarded by Keys, so that Get
+// never writes.
+func (b *Blackboard) Get(key BlackboardKey) (interface{}, bool) {
+	entry, ok := b.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if entry.expires && b.clock.Now() >= entry.expiresAt {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Has returns true if there's an unexpired value for the given key
+func (b *Blackboard) Has(key BlackboardKey) bool {
+	_, ok := b.Get(key)
+	return ok
+}
+
+// Delete removes the value for the given key
+func (b *Blackboard) Delete(key BlackboardKey) {
+	delete(b.entries, key)
+}
+
+// Clear removes every value
+func (b *Blackboard) Clear() {
+	b.entries = make(map[BlackboardKey]blackboardEntry)
+}
+
+// Float returns the value for the given key if it's a float64
+func (b *Blackboard) Float(key BlackboardKey) (float64, bool) {
+	value, _ := b.Get(key)
+	res, ok := value.(float64)
+	return res, ok
+}
+
+// Int returns the value for the given key if it's an int
+func (b *Blackboard) Int(key BlackboardKey) (int, bool) {
+	value, _ := b.Get(key)
+	res, ok := value.(int)
+	return res, ok
+}
+
+// Bool returns the value for the given key if it's a bool
+func (b *Blackboard) Bool(key BlackboardKey) (bool, bool) {
+	value, _ := b.Get(key)
+	res, ok := value.(bool)
+	return res, ok
+}
+
+// String returns the value for the given key if it's a string, such as the
+// uid of a target
+func (b *Blackboard) String(key BlackboardKey) (string, bool) {
+	value, _ := b.Get(key)
+	res, ok := value.(string)
+	return res, ok
+}
+
+// Vector returns the value for the given key if it's a cp.Vector, such as a
+// position to move to
+func (b *Blackboard) Vector(key BlackboardKey) (cp.Vector, bool) {
+	value, _ := b.Get(key)
+	res, ok := value.(cp.Vector)
+	return res, ok
+}
+
+// Keys returns the keys with unexpired values, sorted, discarding the
+// expired values
+func (b *Blackboard) Keys() []BlackboardKey {
+	now := b.clock.Now()
+	res := make([]BlackboardKey, 0, len(b.entries))
+	for key, entry := range b.entries {
+		if entry.expires && now >= entry.expiresAt {
+			delete(b.entries, key)
+			continue
+		}
+		res = append(res, key)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+// Dump formats the unexpired contents of the blackboard for debugging, with
+// one key per line in order, e.g.,
+//
+//	mine.target = "res-12" (expires in 1.5s)
+func (b *Blackboard) Dump() string {
+	var sb strings.Builder
+	now := b.clock.Now()
+	for _, key := range b.Keys() {
+		entry := b.entries[key]
+		fmt.Fprintf(&sb, "%s = %#v", key, entry.value)
+		if entry.expires {
+			fmt.Fprintf(&sb, " (expires in %v)", entry.expiresAt-now)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Blackboards holds a blackboard for each actor plus a shared blackboard
+// for all actors. Blackboards aren't safe for concurrent use.
+type Blackboards struct {
+	clock  Clock
+	shared *Blackboard
+	actors map[interface{}]*Blackboard
+}
+
+// NewBlackboards produces empty blackboards which use the given clock for
+// expiry, or the wall clock if clock is nil. This is usually the same clock
+// as the AI's.
+func NewBlackboards(clock Clock) *Blackboards {
+	return &Blackboards{
+		clock:  clock,
+		shared: NewBlackboard(clock),
+		actors: make(map[interface{}]*Blackboard),
+	}
+}
+
+// Shared returns the blackboard shared by all actors
+func (b *Blackboards) Shared() *Blackboard {
+	return b.shared
+}
+
+// For returns the blackboard of the given actor, creating it if necessary
+func (b *Blackboards) For(actor interface{}) *Blackboard {
+	res, ok := b.actors[actor]
+	if !ok {
+		res = NewBlackboard(b.clock)
+		b.actors[actor] = res
+	}
+	return res
+}
+
+// Remove discards the blackboard of the given actor
+func (b *Blackboards) Remove(actor interface{}) {
+	delete(b.actors, actor)
+}
+
+// Dump formats the contents of every blackboard for debugging, starting with
+// the shared blackboard and followed by each actor's, named as by
+// DefaultActorName
+func (b *Blackboards) Dump() string {
+	type namedBlackboard struct {
+		name       string
+		blackboard *Blackboard
+	}
+	actors := make([]namedBlackboard, 0, len(b.actors))
+	for actor, blackboard := range b.actors {
+		actors = append(actors, namedBlackboard{name: DefaultActorName(actor), blackboard: blackboard})
+	}
+	sort.Slice(actors, func(i, j int) bool { return actors[i].name < actors[j].name })
+
+	var sb strings.Builder
+	sb.WriteString("shared:\n")
+	writeIndented(&sb, b.shared.Dump())
+	for _, actor := range actors {
+		fmt.Fprintf(&sb, "%s:\n", actor.name)
+		writeIndented(&sb, actor.blackboard.Dump())
+	}
+	return sb.String()
+}
+
+// writeIndented writes each line of s indented by two spaces
+func writeIndented(sb *strings.Builder, s string) {
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			sb.WriteString("  " + line)
+		}
+	}
+}
+
+// BlackboardProvider is implemented by worlds which have blackboards for
+// their actors. The AI discards the blackboard of each actor it removes.
+type BlackboardProvider interface {
+	Blackboards() *Blackboards
+}
+
+// BlackboardFor returns the blackboard of the given actor within the given
+// world, or nil if the world isn't a BlackboardProvider. This is typically
+// called from Attached, e.g.,
+//
+//	func (s *nearestOreScorer) Attached(world, actor interface{}) {
+//		s.blackboard = utilsys.BlackboardFor(world, actor)
+//		...
+//	}
+func BlackboardFor(world, actor interface{}) *Blackboard {
+	provider, ok := world.(BlackboardProvider)
+	if !ok {
+		return nil
+	}
+	return provider.Blackboards().For(actor)
+}
+
+// SharedBlackboard returns the blackboard shared by all actors within the
+// given world, or nil if the world isn't a BlackboardProvider
+func SharedBlackboard(world interface{}) *Blackboard {
+	provider, ok := world.(BlackboardProvider)
+	if !ok {
+		return nil
+	}
+	return provider.Blackboards().Shared()
+}
@@ -0,0 +1,162 @@
+package utilsys_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+	"github.com/jakecoffman/cp"
+)
+
+const targetKey utilsys.BlackboardKey = "target"
+
+func TestBlackboard(t *testing.T) {
+	clock := &utilsys.ManualClock{}
+	bb := utilsys.NewBlackboard(clock)
+	bb.Set("count", 3)
+	bb.Set("pos", cp.Vector{X: 1, Y: 2})
+	bb.SetFor(targetKey, "res-12", 2*time.Second)
+
+	if v, ok := bb.Int("count"); !ok || v != 3 {
+		t.Fatalf("expected count 3, got %v, %v", v, ok)
+	}
+	if _, ok := bb.Float("count"); ok {
+		t.Fatal("expected an int not to be read as a float")
+	}
+	if v, ok := bb.Vector("pos"); !ok || v.X != 1 || v.Y != 2 {
+		t.Fatalf("expected pos (1, 2), got %v, %v", v, ok)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	expected := "count = 3\npos = cp.Vector{X:1, Y:2}\ntarget = \"res-12\" (expires in 1.5s)\n"
+	if dump := bb.Dump(); dump != expected {
+		t.Fatalf("expected dump\n%s\ngot\n%s", expected, dump)
+	}
+
+	clock.Advance(1500 * time.Millisecond)
+	if v, ok := bb.String(targetKey); ok {
+		t.Fatalf("expected target to expire, got %v", v)
+	}
+	if keys := bb.Keys(); len(keys) != 2 {
+		t.Fatalf("expected 2 keys after expiry, got %v", keys)
+	}
+
+	bb.Delete("count")
+	if bb.Has("count") {
+		t.Fatal("expected count to be deleted")
+	}
+	bb.Clear()
+	if keys := bb.Keys(); len(keys) != 0 {
+		t.Fatalf("expected no keys after clearing, got %v", keys)
+	}
+}
+
+type blackboardWorld struct {
+	blackboards *utilsys.Blackboards
+}
+
+func (w *blackboardWorld) Blackboards() *utilsys.Blackboards { return w.blackboards }
+
+// targetScorer picks a target for its actor once, scoring 1 if it found one
+type targetScorer struct {
+	blackboard *utilsys.Blackboard
+	actor      string
+	searches   *int
+}
+
+func (s *targetScorer) Attached(world, actor interface{}) {
+	s.blackboard = utilsys.BlackboardFor(world, actor)
+	s.actor = actor.(string)
+}
+
+func (s *targetScorer) Score() float64 {
+	if !s.blackboard.Has(targetKey) {
+		*s.searches++
+		s.blackboard.SetFor(targetKey, "target-of-"+s.actor, time.Minute)
+	}
+	return 1
+}
+
+type targetScorerBuilder struct {
+	searches *int
+}
+
+func (b targetScorerBuilder) Build() utilsys.Scorer {
+	return &targetScorer{searches: b.searches}
+}
+
+// consumeTargetAction succeeds once it has consumed the target
+type consumeTargetAction struct {
+	blackboard *utilsys.Blackboard
+	consumed   *[]string
+	state      utilsys.ActionState
+}
+
+func (a *consumeTargetAction) State() utilsys.ActionState { return a.state }
+
+func (a *consumeTargetAction) Attached(world, actor interface{}) {
+	a.blackboard = utilsys.BlackboardFor(world, actor)
+	a.state = utilsys.ActionStateRequested
+}
+
+func (a *consumeTargetAction) Execute(delta time.Duration) {
+	target, ok := a.blackboard.String(targetKey)
+	if !ok {
+		a.state = utilsys.ActionStateFailure
+		return
+	}
+	*a.consumed = append(*a.consumed, target)
+	a.blackboard.Delete(targetKey)
+	a.state = utilsys.ActionStateSuccess
+}
+
+func (a *consumeTargetAction) Cancel()                             { a.state = utilsys.ActionStateFailure }
+func (a *consumeTargetAction) FinishCanceling(delta time.Duration) {}
+func (a *consumeTargetAction) Reset()                              { a.state = utilsys.ActionStateRequested }
+
+type consumeTargetActionBuilder struct {
+	consumed *[]string
+}
+
+func (b consumeTargetActionBuilder) Build() utilsys.Action {
+	return &consumeTargetAction{consumed: b.consumed}
+}
+
+func TestBlackboard_sharedBetweenScorerAndAction(t *testing.T) {
+	var searches int
+	var consumed []string
+	world := &blackboardWorld{blackboards: utilsys.NewBlackboards(nil)}
+	ai := utilsys.NewAI(world, utilsys.NewHighestScoreThinker([]utilsys.ScoredActionBuilder{
+		utilsys.ScorerBuilderAndActionBuilder{
+			Action: consumeTargetActionBuilder{consumed: &consumed},
+			Scorer: targetScorerBuilder{searches: &searches},
+		},
+	}))
+	ai.AddActor("a")
+	ai.AddActor("b")
+	world.blackboards.Shared().Set("phase", "early")
+
+	for i := 0; i < 4; i++ {
+		ai.Tick(50 * time.Millisecond)
+	}
+
+	// each actor searches when the thinker selects, i.e., on attach and on
+	// each reset, and consumes the target every tick
+	if len(consumed) != 8 || searches != 8 {
+		t.Fatalf("expected 8 targets consumed from 8 searches, got %v from %d", consumed, searches)
+	}
+	if consumed[0] != "target-of-a" || consumed[1] != "target-of-b" {
+		t.Fatalf("expected each actor to have its own target, got %v", consumed)
+	}
+	if phase, _ := utilsys.SharedBlackboard(world).String("phase"); phase != "early" {
+		t.Fatalf("expected the shared blackboard to be kept, got %q", phase)
+	}
+
+	ai.RemoveActor("a")
+	utilsys.BlackboardFor(world, "b").Set("hp", 10)
+	dump := world.blackboards.Dump()
+	expected := "shared:\n  phase = \"early\"\nb:\n  hp = 10\n"
+	if dump != expected {
+		t.Fatalf("expected dump\n%s\ngot\n%s", expected, dump)
+	}
+}
@@ -0,0 +1,68 @@
+package utilsys
+
+import (
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+)
+
+// Clock tells the time for actions which measure it, such as cooldowns and
+// idling. Only the differences between times matter, so clocks may start
+// from anywhere.
+type Clock interface {
+	// Now returns the current time as the duration since the clock's epoch
+	Now() time.Duration
+}
+
+type wallClockImpl struct {
+	start time.Time
+}
+
+func (c wallClockImpl) Now() time.Duration {
+	return time.Since(c.start)
+}
+
+// wallClock is the default clock
+var wallClock Clock = NewWallClock()
+
+// NewWallClock produces a Clock which uses the local time. This is what
+// cooldowns use unless told otherwise, but it keeps running when ticks are delayed
+// and doesn't match the game during replays.
+func NewWallClock() Clock {
+	return wallClockImpl{start: time.Now()}
+}
+
+// GameTimeClock is a Clock which uses the game time of the client state, so
+// that cooldowns match those the game measures, e.g., utils.MINE_COOLDOWN,
+// and work the same during replays. It only moves when packets with the
+// game time are received.
+type GameTimeClock struct {
+	// State whose GameTime is used
+	State *client.State
+}
+
+// Now implements Clock
+func (c GameTimeClock) Now() time.Duration {
+	return time.Duration(c.State.GameTime * float64(time.Second))
+}
+
+// ManualClock is a Clock which only moves when told to, which is mostly
+// useful for tests. The zero value is at 0.
+type ManualClock struct {
+	now time.Duration
+}
+
+// Now implements Clock
+func (c *ManualClock) Now() time.Duration {
+	return c.now
+}
+
+// Set sets the time
+func (c *ManualClock) Set(now time.Duration) {
+	c.now = now
+}
+
+// Advance moves the time forward by the given amount
+func (c *ManualClock) Advance(delta time.Duration) {
+	c.now += delta
+}
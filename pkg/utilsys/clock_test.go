@@ -0,0 +1,112 @@
+package utilsys_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+)
+
+func TestCooldownQualifiedScoredAction(t *testing.T) {
+	clock := &utilsys.ManualClock{}
+	action := succeeds(1)
+	scored := utilsys.CooldownQualifiedScoredAction{
+		ScoredAction: utilsys.ScorerBuilderAndActionBuilder{
+			Action: builtAction{action},
+			Scorer: utilsys.FixedScorer{Score: 0.8},
+		},
+		MinCooldown: time.Second,
+		MaxCooldown: 3 * time.Second,
+		Clock:       clock,
+	}.Build()
+	scored.Action.Attached(nil, nil)
+	scored.Scorer.Attached(nil, nil)
+
+	if s := scored.Scorer.Score(); s != 0.8 {
+		t.Fatalf("expected no cooldown before finishing, got %v", s)
+	}
+
+	clock.Set(10 * time.Second)
+	scored.Action.Execute(50 * time.Millisecond)
+	cases := []struct {
+		elapsed  time.Duration
+		expected float64
+	}{
+		{0, 0},
+		{500 * time.Millisecond, 0},
+		{time.Second, 0},
+		{2 * time.Second, 0.4},
+		{2500 * time.Millisecond, 0.6},
+		{3 * time.Second, 0.8},
+		{time.Minute, 0.8},
+	}
+	for _, tc := range cases {
+		clock.Set(10*time.Second + tc.elapsed)
+		if s := scored.Scorer.Score(); math.Abs(s-tc.expected) > scoreEpsilon {
+			t.Errorf("after %v expected %v, got %v", tc.elapsed, tc.expected, s)
+		}
+	}
+}
+
+func TestAI_SetClock(t *testing.T) {
+	clock := &utilsys.ManualClock{}
+	ai := utilsys.NewAI(nil, utilsys.NamedAction{
+		Name:   "idle",
+		Action: utilsys.IdleAction{MinDuration: time.Second, MaxDuration: time.Second},
+	})
+	ai.SetClock(clock)
+	tracer := &recordingTracer{}
+	ai.SetTracer(tracer, nil)
+	ai.AddActor("actor")
+
+	// ticks are eaten, so the deltas don't add up to the time which passed;
+	// idling starts on the first tick and finishes a second later
+	for i := 0; i < 4; i++ {
+		clock.Advance(400 * time.Millisecond)
+		ai.Tick(time.Millisecond)
+	}
+
+	expected := []string{"init->requested", "requested->executing", "executing->success"}
+	if actual := tracer.transitionsOf("root/idle"); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected idle to finish by the clock with %v, got %v", expected, actual)
+	}
+	if last := tracer.transitions[len(tracer.transitions)-1]; last.Tick != 4 {
+		t.Fatalf("expected idle to finish on tick 4, got %+v", last)
+	}
+}
+
+func TestIdleAction_deltas(t *testing.T) {
+	ai := utilsys.NewAI(nil, utilsys.NamedAction{
+		Name:   "idle",
+		Action: utilsys.IdleAction{MinDuration: time.Second, MaxDuration: time.Second},
+	})
+	tracer := &recordingTracer{}
+	ai.SetTracer(tracer, nil)
+	ai.AddActor("actor")
+
+	// without a clock the deltas are counted down, regardless of how much
+	// time actually passes
+	for i := 0; i < 4; i++ {
+		ai.Tick(300 * time.Millisecond)
+	}
+
+	expected := []string{"init->requested", "requested->executing", "executing->success"}
+	if actual := tracer.transitionsOf("root/idle"); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected idle to finish by the deltas with %v, got %v", expected, actual)
+	}
+	if last := tracer.transitions[len(tracer.transitions)-1]; last.Tick != 4 {
+		t.Fatalf("expected idle to finish on tick 4, got %+v", last)
+	}
+}
+
+func TestGameTimeClock(t *testing.T) {
+	state := client.NewState()
+	clock := utilsys.GameTimeClock{State: state}
+	state.GameTime = 12.5
+	if now := clock.Now(); now != 12500*time.Millisecond {
+		t.Fatalf("expected 12.5s, got %v", now)
+	}
+}
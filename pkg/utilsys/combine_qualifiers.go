@@ -0,0 +1,252 @@
+package utilsys
+
+import (
+	"fmt"
+	"math"
+)
+
+// foldQualifier combines the scores of its children by folding them into an
+// accumulator, weighting each child's score
+type foldQualifier struct {
+	children []Scorer
+	weights  []float64
+
+	kind    string
+	initial float64
+	fold    func(acc, score, weight float64) float64
+	finish  func(acc, totalWeight float64, count int) float64
+}
+
+func (s *foldQualifier) Attached(world, actor interface{}) {
+	for _, child := range s.children {
+		child.Attached(world, actor)
+	}
+}
+
+func (s *foldQualifier) Score() float64 {
+	scores := make([]float64, len(s.children))
+	for idx, child := range s.children {
+		scores[idx] = child.Score()
+	}
+	return s.combine(scores)
+}
+
+func (s *foldQualifier) Explain() ScoreExplanation {
+	children := explainChildren(s.children)
+	scores := make([]float64, len(children))
+	for idx, child := range children {
+		scores[idx] = child.Score
+	}
+	res := ScoreExplanation{Kind: s.kind, Score: s.combine(scores), Children: children}
+	if s.weights != nil {
+		res.Detail = fmt.Sprintf("weights %v", s.weights)
+	}
+	return res
+}
+
+// combine folds the given scores of the children into the final score
+func (s *foldQualifier) combine(scores []float64) float64 {
+	acc := s.initial
+	totalWeight := 0.0
+	for idx, score := range scores {
+		weight := 1.0
+		if s.weights != nil {
+			weight = s.weights[idx]
+		}
+		acc = s.fold(acc, score, weight)
+		totalWeight += weight
+	}
+	return clamp01(s.finish(acc, totalWeight, len(scores)))
+}
+
+// buildScorers builds each of the given builders
+func buildScorers(builders []ScorerBuilder) []Scorer {
+	res := make([]Scorer, len(builders))
+	for idx, builder := range builders {
+		res[idx] = builder.Build()
+	}
+	return res
+}
+
+func sumFold(acc, score, weight float64) float64 { return acc + weight*score }
+func identityFinish(acc, totalWeight float64, count int) float64 {
+	return acc
+}
+
+// SumCombineQualifier produces a score from the children by adding their
+// scores together, capped at 1. This is useful when any of several reasons
+// is enough on its own.
+type SumCombineQualifier struct {
+	// Children are the children the score is built from
+	Children []ScorerBuilder
+}
+
+func (b SumCombineQualifier) Build() Scorer {
+	return &foldQualifier{
+		kind:     "sum",
+		children: buildScorers(b.Children),
+		fold:     sumFold,
+		finish:   identityFinish,
+	}
+}
+
+// AverageCombineQualifier produces a score from the children by averaging
+// their scores, or 0 if there are no children
+type AverageCombineQualifier struct {
+	// Children are the children the score is built from
+	Children []ScorerBuilder
+}
+
+func (b AverageCombineQualifier) Build() Scorer {
+	return &foldQualifier{
+		kind:     "average",
+		children: buildScorers(b.Children),
+		fold:     sumFold,
+		finish: func(acc, totalWeight float64, count int) float64 {
+			if count == 0 {
+				return 0
+			}
+			return acc / float64(count)
+		},
+	}
+}
+
+// MaxCombineQualifier produces a score from the children by taking the
+// highest of their scores, or 0 if there are no children
+type MaxCombineQualifier struct {
+	// Children are the children the score is built from
+	Children []ScorerBuilder
+}
+
+func (b MaxCombineQualifier) Build() Scorer {
+	return &foldQualifier{
+		kind:     "max",
+		children: buildScorers(b.Children),
+		initial:  math.Inf(-1),
+		fold: func(acc, score, weight float64) float64 {
+			return math.Max(acc, score)
+		},
+		finish: identityFinish,
+	}
+}
+
+// MinCombineQualifier produces a score from the children by taking the
+// lowest of their scores, or 0 if there are no children
+type MinCombineQualifier struct {
+	// Children are the children the score is built from
+	Children []ScorerBuilder
+}
+
+func (b MinCombineQualifier) Build() Scorer {
+	return &foldQualifier{
+		kind:     "min",
+		children: buildScorers(b.Children),
+		initial:  math.Inf(1),
+		fold: func(acc, score, weight float64) float64 {
+			return math.Min(acc, score)
+		},
+		finish: func(acc, totalWeight float64, count int) float64 {
+			if count == 0 {
+				return 0
+			}
+			return acc
+		},
+	}
+}
+
+// WeightedScorer is a scorer with a weight, for WeightedSumCombineQualifier
+type WeightedScorer struct {
+	// Weight is how much the scorer counts relative to the others
+	Weight float64
+
+	// Scorer is the weighted scorer
+	Scorer ScorerBuilder
+}
+
+// WeightedSumCombineQualifier produces a score from the children by adding
+// their weighted scores together and dividing by the total weight, so the
+// score stays between 0 and 1. Produces 0 if the total weight is 0.
+type WeightedSumCombineQualifier struct {
+	// Children are the weighted children the score is built from
+	Children []WeightedScorer
+}
+
+func (b WeightedSumCombineQualifier) Build() Scorer {
+	children := make([]Scorer, len(b.Children))
+	weights := make([]float64, len(b.Children))
+	for idx, child := range b.Children {
+		children[idx] = child.Scorer.Build()
+		weights[idx] = child.Weight
+	}
+	return &foldQualifier{
+		kind:     "weighted_sum",
+		children: children,
+		weights:  weights,
+		fold:     sumFold,
+		finish: func(acc, totalWeight float64, count int) float64 {
+			if totalWeight == 0 {
+				return 0
+			}
+			return acc / totalWeight
+		},
+	}
+}
+
+// GeometricMeanCombineQualifier produces a score from the children by
+// multiplying their scores together and taking the nth root, where n is the
+// number of children. Unlike MultCombineQualifier the result doesn't shrink
+// as more children are added, but any child scoring 0 still vetoes.
+type GeometricMeanCombineQualifier struct {
+	// Children are the children the score is built from
+	Children []ScorerBuilder
+}
+
+func (b GeometricMeanCombineQualifier) Build() Scorer {
+	return &foldQualifier{
+		kind:     "geometric_mean",
+		children: buildScorers(b.Children),
+		initial:  1,
+		fold: func(acc, score, weight float64) float64 {
+			return acc * clamp01(score)
+		},
+		finish: func(acc, totalWeight float64, count int) float64 {
+			if count == 0 {
+				return 0
+			}
+			return math.Pow(acc, 1/float64(count))
+		},
+	}
+}
+
+// CompensatedMultCombineQualifier produces a score from the children by
+// multiplying their scores together after compensating each for the number
+// of children, as described by Dave Mark: each score s becomes
+// s + (1-s)*(1-1/n)*s. Multiplying many scores otherwise drags the result
+// towards 0, so actions with more considerations would be unfairly
+// penalized. Any child scoring 0 still vetoes.
+type CompensatedMultCombineQualifier struct {
+	// Children are the children the score is built from
+	Children []ScorerBuilder
+}
+
+func (b CompensatedMultCombineQualifier) Build() Scorer {
+	modification := 0.0
+	if len(b.Children) > 0 {
+		modification = 1 - 1/float64(len(b.Children))
+	}
+	return &foldQualifier{
+		kind:     "compensated_mult",
+		children: buildScorers(b.Children),
+		initial:  1,
+		fold: func(acc, score, weight float64) float64 {
+			score = clamp01(score)
+			return acc * (score + (1-score)*modification*score)
+		},
+		finish: func(acc, totalWeight float64, count int) float64 {
+			if count == 0 {
+				return 0
+			}
+			return acc
+		},
+	}
+}
@@ -0,0 +1,263 @@
+package utilsys_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+)
+
+// scriptedAction finishes in result after ticks calls to Execute, and takes
+// cancelTicks calls to FinishCanceling to finish canceling, which fails it.
+// If finishOnReset is set it finishes in result as soon as it's reset.
+type scriptedAction struct {
+	ticks         int
+	result        utilsys.ActionState
+	cancelTicks   int
+	finishOnReset bool
+
+	state      utilsys.ActionState
+	remaining  int
+	canceling  int
+	executions int
+	cancels    int
+	resets     int
+
+	// misuses counts calls to Execute or Cancel while not running
+	misuses int
+}
+
+func (a *scriptedAction) State() utilsys.ActionState { return a.state }
+
+func (a *scriptedAction) Attached(world, actor interface{}) {
+	a.state = utilsys.ActionStateRequested
+	a.remaining = a.ticks
+}
+
+func (a *scriptedAction) Execute(delta time.Duration) {
+	a.checkRunning()
+	a.executions++
+	a.state = utilsys.ActionStateExecuting
+	a.remaining--
+	if a.remaining <= 0 {
+		a.state = a.result
+	}
+}
+
+func (a *scriptedAction) Cancel() {
+	a.checkRunning()
+	a.cancels++
+	a.canceling = a.cancelTicks
+	a.state = utilsys.ActionStateCanceled
+	if a.canceling <= 0 {
+		a.state = utilsys.ActionStateFailure
+	}
+}
+
+func (a *scriptedAction) FinishCanceling(delta time.Duration) {
+	a.canceling--
+	if a.canceling <= 0 {
+		a.state = utilsys.ActionStateFailure
+	}
+}
+
+func (a *scriptedAction) Reset() {
+	a.resets++
+	a.state = utilsys.ActionStateRequested
+	a.remaining = a.ticks
+	if a.finishOnReset {
+		a.state = a.result
+	}
+}
+
+// checkRunning counts a misuse unless the action is requested or executing
+func (a *scriptedAction) checkRunning() {
+	switch a.state {
+	case utilsys.ActionStateRequested, utilsys.ActionStateExecuting:
+	default:
+		a.misuses++
+	}
+}
+
+func succeeds(ticks int) *scriptedAction {
+	return &scriptedAction{ticks: ticks, result: utilsys.ActionStateSuccess}
+}
+
+func fails(ticks int) *scriptedAction {
+	return &scriptedAction{ticks: ticks, result: utilsys.ActionStateFailure}
+}
+
+// tick advances the action once the way the AI does while it's running
+func tick(t *testing.T, action utilsys.Action) {
+	t.Helper()
+	switch action.State() {
+	case utilsys.ActionStateRequested, utilsys.ActionStateExecuting:
+		action.Execute(50 * time.Millisecond)
+	case utilsys.ActionStateCanceled:
+		action.FinishCanceling(50 * time.Millisecond)
+	default:
+		t.Fatalf("ticked action in state %v", action.State())
+	}
+}
+
+// runToEnd attaches the action and ticks it until it finishes, returning the
+// final state and the number of ticks
+func runToEnd(t *testing.T, builder utilsys.ActionBuilder) (utilsys.ActionState, int) {
+	t.Helper()
+	action := builder.Build()
+	action.Attached(nil, nil)
+	for ticks := 0; ticks < 100; ticks++ {
+		if state := action.State(); state == utilsys.ActionStateSuccess || state == utilsys.ActionStateFailure {
+			return state, ticks
+		}
+		tick(t, action)
+	}
+	t.Fatalf("action did not finish")
+	return utilsys.ActionStateInit, 0
+}
+
+func expectResult(t *testing.T, builder utilsys.ActionBuilder, state utilsys.ActionState, ticks int) {
+	t.Helper()
+	actualState, actualTicks := runToEnd(t, builder)
+	if actualState != state || actualTicks != ticks {
+		t.Errorf("expected state %v after %d ticks, got %v after %d", state, ticks, actualState, actualTicks)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	first, second := succeeds(1), succeeds(2)
+	expectResult(t, utilsys.Sequence{Children: []utilsys.ActionBuilder{builtAction{first}, builtAction{second}}}, utilsys.ActionStateSuccess, 3)
+
+	failing, never := fails(1), succeeds(1)
+	expectResult(t, utilsys.Sequence{Children: []utilsys.ActionBuilder{builtAction{failing}, builtAction{never}}}, utilsys.ActionStateFailure, 1)
+	if never.executions != 0 {
+		t.Errorf("expected the sequence to stop at the first failure")
+	}
+}
+
+func TestSequence_Cancel(t *testing.T) {
+	first, second := succeeds(1), succeeds(5)
+	second.cancelTicks = 2
+	action := utilsys.Sequence{Children: []utilsys.ActionBuilder{builtAction{first}, builtAction{second}}}.Build()
+	action.Attached(nil, nil)
+	tick(t, action)
+	tick(t, action)
+
+	action.Cancel()
+	if action.State() != utilsys.ActionStateCanceled || second.cancels != 1 || first.cancels != 0 {
+		t.Fatalf("expected only the running child to be canceled, got state %v", action.State())
+	}
+	tick(t, action)
+	if action.State() != utilsys.ActionStateCanceled {
+		t.Fatalf("expected to wait for the child to finish canceling")
+	}
+	tick(t, action)
+	if action.State() != utilsys.ActionStateFailure {
+		t.Fatalf("expected failure once canceled, got %v", action.State())
+	}
+
+	action.Reset()
+	if first.resets != 1 || second.resets != 1 || action.State() != utilsys.ActionStateRequested {
+		t.Errorf("expected reset to reset the finished children")
+	}
+}
+
+func TestSelector(t *testing.T) {
+	first, second, never := fails(1), succeeds(1), succeeds(1)
+	expectResult(t, utilsys.Selector{Children: []utilsys.ActionBuilder{builtAction{first}, builtAction{second}, builtAction{never}}}, utilsys.ActionStateSuccess, 2)
+	if never.executions != 0 {
+		t.Errorf("expected the selector to stop at the first success")
+	}
+
+	expectResult(t, utilsys.Selector{Children: []utilsys.ActionBuilder{builtAction{fails(1)}, builtAction{fails(2)}}}, utilsys.ActionStateFailure, 3)
+}
+
+func TestParallel(t *testing.T) {
+	expectResult(t, utilsys.Parallel{
+		Children: []utilsys.ActionBuilder{builtAction{succeeds(1)}, builtAction{succeeds(3)}},
+	}, utilsys.ActionStateSuccess, 3)
+
+	fast, slow := succeeds(1), succeeds(5)
+	slow.cancelTicks = 1
+	expectResult(t, utilsys.Parallel{
+		Children:      []utilsys.ActionBuilder{builtAction{fast}, builtAction{slow}},
+		SuccessPolicy: utilsys.ParallelRequireOne,
+	}, utilsys.ActionStateSuccess, 2)
+	if slow.cancels != 1 || slow.executions != 1 {
+		t.Errorf("expected the slow child to be canceled after 1 execution, got %d cancels %d executions", slow.cancels, slow.executions)
+	}
+
+	expectResult(t, utilsys.Parallel{
+		Children:      []utilsys.ActionBuilder{builtAction{fails(2)}, builtAction{succeeds(5)}},
+		FailurePolicy: utilsys.ParallelRequireOne,
+	}, utilsys.ActionStateFailure, 2)
+
+	// neither policy can be met
+	expectResult(t, utilsys.Parallel{
+		Children: []utilsys.ActionBuilder{builtAction{fails(1)}, builtAction{succeeds(2)}},
+	}, utilsys.ActionStateFailure, 2)
+}
+
+func TestRepeat(t *testing.T) {
+	child := succeeds(1)
+	expectResult(t, utilsys.Repeat{Action: builtAction{child}, Times: 3}, utilsys.ActionStateSuccess, 3)
+	if child.executions != 3 {
+		t.Errorf("expected 3 executions, got %d", child.executions)
+	}
+
+	expectResult(t, utilsys.Repeat{Action: builtAction{fails(2)}}, utilsys.ActionStateFailure, 2)
+}
+
+func TestRetry(t *testing.T) {
+	child := fails(1)
+	expectResult(t, utilsys.Retry{Action: builtAction{child}, Retries: 2}, utilsys.ActionStateFailure, 3)
+	if child.executions != 3 {
+		t.Errorf("expected 3 attempts, got %d", child.executions)
+	}
+
+	expectResult(t, utilsys.Retry{Action: builtAction{succeeds(2)}, Retries: 2}, utilsys.ActionStateSuccess, 2)
+}
+
+func TestRepeatAndRetry_finishOnReset(t *testing.T) {
+	child := succeeds(1)
+	child.finishOnReset = true
+	expectResult(t, utilsys.Repeat{Action: builtAction{child}, Times: 3}, utilsys.ActionStateSuccess, 3)
+	if child.executions != 1 || child.misuses != 0 {
+		t.Errorf("expected 1 execution and no misuses, got %d executions %d misuses", child.executions, child.misuses)
+	}
+
+	child = fails(1)
+	child.finishOnReset = true
+	expectResult(t, utilsys.Retry{Action: builtAction{child}, Retries: 2}, utilsys.ActionStateFailure, 3)
+	if child.executions != 1 || child.misuses != 0 {
+		t.Errorf("expected 1 execution and no misuses, got %d executions %d misuses", child.executions, child.misuses)
+	}
+
+	child = succeeds(1)
+	child.finishOnReset = true
+	repeat := utilsys.Repeat{Action: builtAction{child}}.Build()
+	repeat.Attached(nil, nil)
+	tick(t, repeat)
+	repeat.Cancel()
+	if repeat.State() != utilsys.ActionStateSuccess || child.cancels != 0 || child.misuses != 0 {
+		t.Errorf("expected canceling to take on the finished child's success, got %v with %d cancels %d misuses",
+			repeat.State(), child.cancels, child.misuses)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	child := succeeds(10)
+	child.cancelTicks = 1
+	expectResult(t, utilsys.Timeout{Action: builtAction{child}, Duration: 100 * time.Millisecond}, utilsys.ActionStateFailure, 3)
+	if child.executions != 1 || child.cancels != 1 {
+		t.Errorf("expected 1 execution before timing out, got %d executions %d cancels", child.executions, child.cancels)
+	}
+
+	expectResult(t, utilsys.Timeout{Action: builtAction{succeeds(1)}, Duration: 100 * time.Millisecond}, utilsys.ActionStateSuccess, 1)
+}
+
+func TestInvertAndAlwaysSucceed(t *testing.T) {
+	expectResult(t, utilsys.Invert{Action: builtAction{succeeds(1)}}, utilsys.ActionStateFailure, 1)
+	expectResult(t, utilsys.Invert{Action: builtAction{fails(1)}}, utilsys.ActionStateSuccess, 1)
+	expectResult(t, utilsys.AlwaysSucceed{Action: builtAction{fails(1)}}, utilsys.ActionStateSuccess, 1)
+}
@@ -0,0 +1,59 @@
+package utilsys
+
+// contextActor is an actor within an AI, shared by the contexts of all of
+// its actions
+type contextActor struct {
+	ai    *AI
+	actor interface{}
+	name  string
+}
+
+// actionContext is the context for a single action of an actor, which gives
+// it the AI's tracer and clock. It exists whether or not the AI has a
+// tracer, so the tracer can be changed at any time. Actions which aren't
+// run by an AI have a nil context.
+type actionContext struct {
+	*contextActor
+	path string
+}
+
+// child returns the context for the named child
+func (c *actionContext) child(name string) *actionContext {
+	if c == nil {
+		return nil
+	}
+	res := *c
+	res.path = c.path + "/" + name
+	return &res
+}
+
+// clock returns the AI's clock, or the wall clock if there's no AI or it
+// has no clock
+func (c *actionContext) clock() Clock {
+	if res := c.setClock(); res != nil {
+		return res
+	}
+	return wallClock
+}
+
+// setClock returns the clock set on the AI with SetClock, or nil if there's
+// no AI or it has no clock
+func (c *actionContext) setClock() Clock {
+	if c == nil {
+		return nil
+	}
+	return c.ai.clock
+}
+
+// contextual is implemented by actions which use their context or contain
+// other actions
+type contextual interface {
+	setContext(ctx *actionContext)
+}
+
+// setContext gives the action its context if it can use it
+func setContext(action Action, ctx *actionContext) {
+	if c, ok := action.(contextual); ok {
+		c.setContext(ctx)
+	}
+}
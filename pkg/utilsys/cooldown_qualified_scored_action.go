@@ -1,34 +1,87 @@
 package utilsys
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// cooldown is shared by the scorer and action of a cooldown qualified scored
+// action to track when the action last finished
+type cooldown struct {
+	// clock is the clock given to the builder, or nil to use the AI's clock
+	clock Clock
+	ctx   *actionContext
+
+	finished       bool
+	lastFinishedAt time.Duration
+}
+
+// now returns the time according to the clock for the cooldown
+func (c *cooldown) now() time.Duration {
+	if c.clock != nil {
+		return c.clock.Now()
+	}
+	return c.ctx.clock().Now()
+}
 
 type cooldownQualifiedScorer struct {
-	scorer         Scorer
-	minCooldown    time.Duration
-	maxCooldown    time.Duration
-	lastFinishedAt *time.Time
+	scorer      Scorer
+	minCooldown time.Duration
+	maxCooldown time.Duration
+	cooldown    *cooldown
 }
 
 func (s *cooldownQualifiedScorer) Attached(world, actor interface{}) {
 	s.scorer.Attached(world, actor)
 }
 
+func (s *cooldownQualifiedScorer) nodeName() string {
+	return nodeName(s.scorer)
+}
+
 func (s *cooldownQualifiedScorer) Score() float64 {
-	timeSinceLast := time.Since(*s.lastFinishedAt)
+	progress := s.progress()
+	if progress == 0 {
+		return 0
+	}
+	return progress * s.scorer.Score()
+}
+
+func (s *cooldownQualifiedScorer) Explain() ScoreExplanation {
+	progress := s.progress()
+	res := ScoreExplanation{
+		Kind:   "cooldown",
+		Detail: fmt.Sprintf("progress %.3f", progress),
+	}
+	if progress == 0 {
+		return res
+	}
+	child := ExplainScore(s.scorer)
+	res.Score = progress * child.Score
+	res.Children = []ScoreExplanation{child}
+	return res
+}
+
+// progress returns how far through the cooldown we are, from 0 while the
+// minimum cooldown hasn't elapsed to 1 once the maximum cooldown has
+func (s *cooldownQualifiedScorer) progress() float64 {
+	if !s.cooldown.finished {
+		return 1
+	}
+	timeSinceLast := s.cooldown.now() - s.cooldown.lastFinishedAt
 
 	if timeSinceLast < s.minCooldown {
 		return 0
 	} else if timeSinceLast < s.maxCooldown {
-		progress := float64(timeSinceLast-s.minCooldown) / float64(s.maxCooldown)
-		return progress * s.scorer.Score()
+		return float64(timeSinceLast-s.minCooldown) / float64(s.maxCooldown-s.minCooldown)
 	} else {
-		return s.scorer.Score()
+		return 1
 	}
 }
 
 type cooldownQualifiedAction struct {
 	action                      Action
-	lastFinishedAt              *time.Time
+	cooldown                    *cooldown
 	cooldownSuppressedOnSuccess bool
 	cooldownSuppressedOnFailure bool
 }
@@ -62,15 +115,30 @@ func (a *cooldownQualifiedAction) Reset() {
 	a.checkIfFinished()
 }
 
+func (a *cooldownQualifiedAction) nodeName() string {
+	return nodeName(a.action)
+}
+
+func (a *cooldownQualifiedAction) setContext(ctx *actionContext) {
+	a.cooldown.ctx = ctx
+	setContext(a.action, ctx)
+}
+
+// finished starts the cooldown
+func (a *cooldownQualifiedAction) finished() {
+	a.cooldown.finished = true
+	a.cooldown.lastFinishedAt = a.cooldown.now()
+}
+
 func (a *cooldownQualifiedAction) checkIfFinished() {
 	switch a.State() {
 	case ActionStateFailure:
 		if !a.cooldownSuppressedOnFailure {
-			*a.lastFinishedAt = time.Now()
+			a.finished()
 		}
 	case ActionStateSuccess:
 		if !a.cooldownSuppressedOnSuccess {
-			*a.lastFinishedAt = time.Now()
+			a.finished()
 		}
 	}
 }
@@ -88,24 +156,28 @@ type CooldownQualifiedScoredAction struct {
 
 	CooldownSuppressedOnSuccess bool
 	CooldownSuppressedOnFailure bool
+
+	// Clock measures the cooldown. Defaults to the clock of the AI, or the
+	// wall clock for actions which aren't run by an AI.
+	Clock Clock
 }
 
 func (b CooldownQualifiedScoredAction) Build() ScoredAction {
-	var finishedAt time.Time
+	cooldown := &cooldown{clock: b.Clock}
 
 	built := b.ScoredAction.Build()
 	return ScoredAction{
 		Scorer: &cooldownQualifiedScorer{
-			scorer:         built.Scorer,
-			minCooldown:    b.MinCooldown,
-			maxCooldown:    b.MaxCooldown,
-			lastFinishedAt: &finishedAt,
+			scorer:      built.Scorer,
+			minCooldown: b.MinCooldown,
+			maxCooldown: b.MaxCooldown,
+			cooldown:    cooldown,
 		},
 		Action: &cooldownQualifiedAction{
 			action:                      built.Action,
 			cooldownSuppressedOnSuccess: b.CooldownSuppressedOnSuccess,
 			cooldownSuppressedOnFailure: b.CooldownSuppressedOnFailure,
-			lastFinishedAt:              &finishedAt,
+			cooldown:                    cooldown,
 		},
 	}
 }
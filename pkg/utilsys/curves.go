@@ -0,0 +1,197 @@
+package utilsys
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Curve is a response curve, which maps an input score to an output score.
+// Curves are used with a CurveQualifier and are typically stateless.
+type Curve interface {
+	// Evaluate the curve at the given input, which is between 0 and 1
+	Evaluate(x float64) float64
+}
+
+// clamp01 clamps the given value to between 0 and 1
+func clamp01(v float64) float64 {
+	if v < 0 || math.IsNaN(v) {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// LinearCurve is the straight line y = Slope*x + Intercept. The zero value
+// isn't useful; the identity is LinearCurve{Slope: 1}.
+type LinearCurve struct {
+	Slope     float64
+	Intercept float64
+}
+
+// Evaluate implements Curve
+func (c LinearCurve) Evaluate(x float64) float64 {
+	return c.Slope*x + c.Intercept
+}
+
+// PolynomialCurve is y = Slope*(x-XShift)^Exponent + YShift. For example an
+// Exponent of 2 with a Slope of 1 makes low scores matter much less than
+// high scores, and an Exponent of 0.5 does the opposite.
+type PolynomialCurve struct {
+	Exponent float64
+	Slope    float64
+	XShift   float64
+	YShift   float64
+}
+
+// Evaluate implements Curve
+func (c PolynomialCurve) Evaluate(x float64) float64 {
+	return c.Slope*math.Pow(x-c.XShift, c.Exponent) + c.YShift
+}
+
+// LogisticCurve is the S-shaped y = 1 / (1 + e^(-Steepness*(x-Midpoint))),
+// which is near 0 well below the midpoint and near 1 well above it
+type LogisticCurve struct {
+	// Steepness is how sharply the curve moves from 0 to 1 around the
+	// midpoint. Defaults to 10.
+	Steepness float64
+
+	// Midpoint is the input at which the output is 0.5, typically 0.5
+	Midpoint float64
+}
+
+// Evaluate implements Curve
+func (c LogisticCurve) Evaluate(x float64) float64 {
+	steepness := c.Steepness
+	if steepness == 0 {
+		steepness = 10
+	}
+	return 1 / (1 + math.Exp(-steepness*(x-c.Midpoint)))
+}
+
+// LogitCurve is the inverse of a LogisticCurve with a midpoint of 0.5, i.e.,
+// y = 0.5 + ln(x/(1-x))/Steepness, which moves quickly near 0 and 1 and
+// slowly in the middle
+type LogitCurve struct {
+	// Steepness of the logistic curve this is the inverse of, so higher
+	// values make this flatter. Defaults to 10.
+	Steepness float64
+}
+
+// Evaluate implements Curve
+func (c LogitCurve) Evaluate(x float64) float64 {
+	steepness := c.Steepness
+	if steepness == 0 {
+		steepness = 10
+	}
+
+	// the logit is infinite at 0 and 1
+	const epsilon = 1e-9
+	x = math.Min(math.Max(x, epsilon), 1-epsilon)
+	return 0.5 + math.Log(x/(1-x))/steepness
+}
+
+// ExponentialDecayCurve is y = e^(-Rate*x), which is 1 at 0 and falls off
+// faster for higher rates. It's useful for scores which should fade with,
+// e.g., distance or time.
+type ExponentialDecayCurve struct {
+	Rate float64
+}
+
+// Evaluate implements Curve
+func (c ExponentialDecayCurve) Evaluate(x float64) float64 {
+	return math.Exp(-c.Rate * x)
+}
+
+// CurvePoint is a single point on a PiecewiseLinearCurve
+type CurvePoint struct {
+	X float64
+	Y float64
+}
+
+// PiecewiseLinearCurve linearly interpolates between the given points, and
+// is flat before the first point and after the last. This allows arbitrary
+// hand-tuned curves.
+type PiecewiseLinearCurve struct {
+	// Points on the curve, which must be sorted by X. Use
+	// NewPiecewiseLinearCurve to sort them.
+	Points []CurvePoint
+}
+
+// NewPiecewiseLinearCurve returns the curve through the given points, which
+// may be in any order. The points are copied, so they may be reused.
+//
+// performance: O(n log n) where n is the number of points
+func NewPiecewiseLinearCurve(points ...CurvePoint) PiecewiseLinearCurve {
+	sorted := append([]CurvePoint(nil), points...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].X < sorted[j].X })
+	return PiecewiseLinearCurve{Points: sorted}
+}
+
+// Evaluate implements Curve
+//
+// performance: O(n) where n is the number of points
+func (c PiecewiseLinearCurve) Evaluate(x float64) float64 {
+	points := c.Points
+	if len(points) == 0 {
+		return 0
+	}
+
+	if x <= points[0].X {
+		return points[0].Y
+	}
+	for idx := 1; idx < len(points); idx++ {
+		if x <= points[idx].X {
+			prev := points[idx-1]
+			next := points[idx]
+			if next.X == prev.X {
+				return next.Y
+			}
+			return prev.Y + (next.Y-prev.Y)*(x-prev.X)/(next.X-prev.X)
+		}
+	}
+	return points[len(points)-1].Y
+}
+
+type curveQualifier struct {
+	curve  Curve
+	scorer Scorer
+}
+
+func (q *curveQualifier) Attached(world, actor interface{}) {
+	q.scorer.Attached(world, actor)
+}
+
+func (q *curveQualifier) Score() float64 {
+	return clamp01(q.curve.Evaluate(clamp01(q.scorer.Score())))
+}
+
+func (q *curveQualifier) Explain() ScoreExplanation {
+	child := ExplainScore(q.scorer)
+	return ScoreExplanation{
+		Kind:     "curve",
+		Detail:   fmt.Sprintf("%T%+v", q.curve, q.curve),
+		Score:    clamp01(q.curve.Evaluate(clamp01(child.Score))),
+		Children: []ScoreExplanation{child},
+	}
+}
+
+// CurveQualifier maps the score of the child through a response curve. The
+// child's score is clamped to 0-1 before evaluating the curve, and so is the
+// result.
+type CurveQualifier struct {
+	// Scorer is the scorer whose score is the input to the curve
+	Scorer ScorerBuilder
+
+	// Curve maps the input to the score
+	Curve Curve
+}
+
+func (b CurveQualifier) Build() Scorer {
+	return &curveQualifier{
+		curve:  b.Curve,
+		scorer: b.Scorer.Build(),
+	}
+}
@@ -0,0 +1,127 @@
+package utilsys_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+)
+
+const scoreEpsilon = 1e-9
+
+func score(builder utilsys.ScorerBuilder) float64 {
+	scorer := builder.Build()
+	scorer.Attached(nil, nil)
+	return scorer.Score()
+}
+
+func fixed(scores ...float64) []utilsys.ScorerBuilder {
+	res := make([]utilsys.ScorerBuilder, len(scores))
+	for idx, s := range scores {
+		res[idx] = utilsys.FixedScorer{Score: s}
+	}
+	return res
+}
+
+func TestCurveQualifier(t *testing.T) {
+	cases := []struct {
+		name     string
+		curve    utilsys.Curve
+		input    float64
+		expected float64
+	}{
+		{"linear", utilsys.LinearCurve{Slope: 0.5, Intercept: 0.25}, 0.5, 0.5},
+		{"linear clamped", utilsys.LinearCurve{Slope: 2}, 0.75, 1},
+		{"polynomial", utilsys.PolynomialCurve{Exponent: 2, Slope: 1}, 0.5, 0.25},
+		{"polynomial shifted", utilsys.PolynomialCurve{Exponent: 3, Slope: -1, XShift: 1, YShift: 0}, 0.5, 0.125},
+		{"logistic midpoint", utilsys.LogisticCurve{Midpoint: 0.5}, 0.5, 0.5},
+		{"logistic high", utilsys.LogisticCurve{Steepness: 20, Midpoint: 0.5}, 1, 1 / (1 + math.Exp(-10))},
+		{"logit midpoint", utilsys.LogitCurve{}, 0.5, 0.5},
+		{"logit inverts logistic", utilsys.LogitCurve{Steepness: 10}, 1 / (1 + math.Exp(-1)), 0.6},
+		{"logit at zero", utilsys.LogitCurve{}, 0, 0},
+		{"decay", utilsys.ExponentialDecayCurve{Rate: 2}, 0.5, math.Exp(-1)},
+		{"piecewise", utilsys.NewPiecewiseLinearCurve(utilsys.CurvePoint{X: 0.5, Y: 1}, utilsys.CurvePoint{X: 0, Y: 0}, utilsys.CurvePoint{X: 1, Y: 0.5}), 0.75, 0.75},
+		{"piecewise before", utilsys.PiecewiseLinearCurve{Points: []utilsys.CurvePoint{{X: 0.2, Y: 0.3}, {X: 1, Y: 1}}}, 0.1, 0.3},
+		{"input clamped", utilsys.LinearCurve{Slope: 1}, 1.5, 1},
+	}
+
+	for _, tc := range cases {
+		actual := score(utilsys.CurveQualifier{Scorer: utilsys.FixedScorer{Score: tc.input}, Curve: tc.curve})
+		if math.Abs(actual-tc.expected) > scoreEpsilon {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestCurveQualifier_range(t *testing.T) {
+	curves := []utilsys.Curve{
+		utilsys.LinearCurve{Slope: -3, Intercept: 2},
+		utilsys.PolynomialCurve{Exponent: 0.5, Slope: 2, XShift: -0.5},
+		utilsys.LogisticCurve{Steepness: -5, Midpoint: 0.3},
+		utilsys.LogitCurve{Steepness: 1},
+		utilsys.ExponentialDecayCurve{Rate: -1},
+		utilsys.PiecewiseLinearCurve{Points: []utilsys.CurvePoint{{X: 0, Y: -1}, {X: 1, Y: 2}}},
+	}
+
+	for seed := int64(0); seed < 100; seed++ {
+		rand.Seed(seed)
+		input := rand.Float64()*3 - 1
+		for _, curve := range curves {
+			actual := score(utilsys.CurveQualifier{Scorer: utilsys.FixedScorer{Score: input}, Curve: curve})
+			if actual < 0 || actual > 1 || math.IsNaN(actual) {
+				t.Fatalf("seed %d: %T at %v produced %v outside 0-1", seed, curve, input, actual)
+			}
+		}
+	}
+}
+
+func TestCombineQualifiers(t *testing.T) {
+	cases := []struct {
+		name     string
+		scorer   utilsys.ScorerBuilder
+		expected float64
+	}{
+		{"sum", utilsys.SumCombineQualifier{Children: fixed(0.2, 0.3)}, 0.5},
+		{"sum capped", utilsys.SumCombineQualifier{Children: fixed(0.8, 0.7)}, 1},
+		{"average", utilsys.AverageCombineQualifier{Children: fixed(0.2, 0.4, 0.9)}, 0.5},
+		{"average empty", utilsys.AverageCombineQualifier{}, 0},
+		{"max", utilsys.MaxCombineQualifier{Children: fixed(0.2, 0.7, 0.4)}, 0.7},
+		{"max empty", utilsys.MaxCombineQualifier{}, 0},
+		{"min", utilsys.MinCombineQualifier{Children: fixed(0.2, 0.7, 0.4)}, 0.2},
+		{"weighted sum", utilsys.WeightedSumCombineQualifier{Children: []utilsys.WeightedScorer{
+			{Weight: 3, Scorer: utilsys.FixedScorer{Score: 1}},
+			{Weight: 1, Scorer: utilsys.FixedScorer{Score: 0}},
+		}}, 0.75},
+		{"weighted sum no weight", utilsys.WeightedSumCombineQualifier{Children: []utilsys.WeightedScorer{
+			{Weight: 0, Scorer: utilsys.FixedScorer{Score: 1}},
+		}}, 0},
+		{"geometric mean", utilsys.GeometricMeanCombineQualifier{Children: fixed(0.25, 1)}, 0.5},
+		{"geometric mean veto", utilsys.GeometricMeanCombineQualifier{Children: fixed(0, 1, 1)}, 0},
+		{"compensated single", utilsys.CompensatedMultCombineQualifier{Children: fixed(0.5)}, 0.5},
+		{"compensated pair", utilsys.CompensatedMultCombineQualifier{Children: fixed(0.5, 0.5)}, 0.625 * 0.625},
+	}
+
+	for _, tc := range cases {
+		actual := score(tc.scorer)
+		if math.Abs(actual-tc.expected) > scoreEpsilon {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestCompensatedMultCombineQualifier_compensates(t *testing.T) {
+	for seed := int64(0); seed < 100; seed++ {
+		rand.Seed(seed)
+		scores := make([]float64, 2+rand.Intn(8))
+		for idx := range scores {
+			scores[idx] = rand.Float64()
+		}
+
+		plain := score(utilsys.MultCombineQualifier{Children: fixed(scores...)})
+		compensated := score(utilsys.CompensatedMultCombineQualifier{Children: fixed(scores...)})
+		if compensated < plain-scoreEpsilon || compensated > 1 {
+			t.Fatalf("seed %d: expected compensated %v to be between plain %v and 1", seed, compensated, plain)
+		}
+	}
+}
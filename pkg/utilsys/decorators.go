@@ -0,0 +1,301 @@
+package utilsys
+
+import "time"
+
+// mappedAction passes everything through to its child, but maps the state
+// the child finishes in
+type mappedAction struct {
+	action    Action
+	onSuccess ActionState
+	onFailure ActionState
+}
+
+func (a *mappedAction) setContext(ctx *actionContext) {
+	setContext(a.action, ctx)
+}
+
+func (a *mappedAction) State() ActionState {
+	switch state := a.action.State(); state {
+	case ActionStateSuccess:
+		return a.onSuccess
+	case ActionStateFailure:
+		return a.onFailure
+	default:
+		return state
+	}
+}
+
+func (a *mappedAction) Attached(world, actor interface{}) {
+	a.action.Attached(world, actor)
+}
+
+func (a *mappedAction) Execute(delta time.Duration) {
+	a.action.Execute(delta)
+}
+
+func (a *mappedAction) Cancel() {
+	a.action.Cancel()
+}
+
+func (a *mappedAction) FinishCanceling(delta time.Duration) {
+	a.action.FinishCanceling(delta)
+}
+
+func (a *mappedAction) Reset() {
+	a.action.Reset()
+}
+
+// Invert succeeds when its action fails and fails when its action succeeds
+type Invert struct {
+	Action ActionBuilder
+}
+
+// Build implements ActionBuilder
+func (b Invert) Build() Action {
+	return &mappedAction{
+		action:    b.Action.Build(),
+		onSuccess: ActionStateFailure,
+		onFailure: ActionStateSuccess,
+	}
+}
+
+// AlwaysSucceed succeeds when its action finishes, even if it failed
+type AlwaysSucceed struct {
+	Action ActionBuilder
+}
+
+// Build implements ActionBuilder
+func (b AlwaysSucceed) Build() Action {
+	return &mappedAction{
+		action:    b.Action.Build(),
+		onSuccess: ActionStateSuccess,
+		onFailure: ActionStateSuccess,
+	}
+}
+
+// loopingAction resets its child each time it finishes in the repeatOn state,
+// up to a limit
+type loopingAction struct {
+	action   Action
+	repeatOn ActionState
+
+	// limit is the number of times the child may finish in the repeatOn
+	// state before we finish, or 0 for no limit
+	limit int
+
+	state ActionState
+	count int
+}
+
+func (a *loopingAction) setContext(ctx *actionContext) {
+	setContext(a.action, ctx)
+}
+
+func (a *loopingAction) State() ActionState {
+	return a.state
+}
+
+func (a *loopingAction) Attached(world, actor interface{}) {
+	a.action.Attached(world, actor)
+	a.count = 0
+	a.state = ActionStateRequested
+	a.checkIfFinished()
+}
+
+func (a *loopingAction) Execute(delta time.Duration) {
+	a.state = ActionStateExecuting
+
+	// the child may have finished as soon as it was reset
+	if isFinished(a.action.State()) {
+		a.checkIfFinished()
+		return
+	}
+
+	a.action.Execute(delta)
+	a.checkIfFinished()
+}
+
+func (a *loopingAction) Cancel() {
+	cancelRunning(a.action)
+	a.state = ActionStateCanceled
+	a.finishCancelingIfDone()
+}
+
+func (a *loopingAction) FinishCanceling(delta time.Duration) {
+	if a.action.State() == ActionStateCanceled {
+		a.action.FinishCanceling(delta)
+	}
+	a.finishCancelingIfDone()
+}
+
+func (a *loopingAction) Reset() {
+	if isFinished(a.action.State()) {
+		a.action.Reset()
+	}
+	a.count = 0
+	a.state = ActionStateRequested
+	a.checkIfFinished()
+}
+
+// checkIfFinished handles the child finishing, resetting it to go again if
+// it finished in the repeatOn state and we haven't hit the limit. The child
+// is reset at most once per call, so a child which finishes immediately
+// every time doesn't loop forever.
+func (a *loopingAction) checkIfFinished() {
+	childState := a.action.State()
+	if !isFinished(childState) {
+		return
+	}
+	if childState != a.repeatOn {
+		a.state = childState
+		return
+	}
+
+	a.count++
+	if a.limit > 0 && a.count >= a.limit {
+		a.state = childState
+		return
+	}
+	a.action.Reset()
+}
+
+// finishCancelingIfDone takes on the result of the child once it's done
+// canceling
+func (a *loopingAction) finishCancelingIfDone() {
+	if childState := a.action.State(); isFinished(childState) {
+		a.state = childState
+	}
+}
+
+// Repeat runs its action repeatedly, resetting it each time it succeeds. It
+// fails as soon as the action fails, and succeeds once the action has
+// succeeded Times times. Canceling a Repeat cancels the action and finishes
+// the way the action does.
+type Repeat struct {
+	Action ActionBuilder
+
+	// Times is how many times the action must succeed, or 0 to repeat until
+	// the action fails or is canceled
+	Times int
+}
+
+// Build implements ActionBuilder
+func (b Repeat) Build() Action {
+	return &loopingAction{
+		action:   b.Action.Build(),
+		repeatOn: ActionStateSuccess,
+		limit:    b.Times,
+	}
+}
+
+// Retry runs its action, resetting it each time it fails until it has been
+// retried Retries times. It succeeds as soon as the action succeeds and
+// fails once it runs out of retries. Canceling a Retry cancels the action
+// and finishes the way the action does.
+type Retry struct {
+	Action ActionBuilder
+
+	// Retries is the number of times the action is retried after failing,
+	// so the action is attempted at most Retries+1 times. Negative values
+	// retry forever.
+	Retries int
+}
+
+// Build implements ActionBuilder
+func (b Retry) Build() Action {
+	limit := b.Retries + 1
+	if b.Retries < 0 {
+		limit = 0
+	}
+	return &loopingAction{
+		action:   b.Action.Build(),
+		repeatOn: ActionStateFailure,
+		limit:    limit,
+	}
+}
+
+type timeoutAction struct {
+	action   Action
+	duration time.Duration
+
+	elapsed  time.Duration
+	timedOut bool
+}
+
+func (a *timeoutAction) setContext(ctx *actionContext) {
+	setContext(a.action, ctx)
+}
+
+func (a *timeoutAction) State() ActionState {
+	childState := a.action.State()
+	if !a.timedOut {
+		return childState
+	}
+	if isFinished(childState) {
+		return ActionStateFailure
+	}
+	if childState == ActionStateCanceled {
+		// canceled by us, so we're still executing from the caller's view
+		return ActionStateExecuting
+	}
+	return childState
+}
+
+func (a *timeoutAction) Attached(world, actor interface{}) {
+	a.action.Attached(world, actor)
+	a.elapsed = 0
+	a.timedOut = false
+}
+
+func (a *timeoutAction) Execute(delta time.Duration) {
+	if a.timedOut {
+		a.action.FinishCanceling(delta)
+		return
+	}
+
+	a.elapsed += delta
+	if a.elapsed >= a.duration {
+		a.timedOut = true
+		a.action.Cancel()
+		return
+	}
+	a.action.Execute(delta)
+}
+
+func (a *timeoutAction) Cancel() {
+	if a.timedOut {
+		// the action is already canceling; from now on it's the caller's
+		// cancel so the action's result is passed through
+		a.timedOut = false
+		return
+	}
+	a.action.Cancel()
+}
+
+func (a *timeoutAction) FinishCanceling(delta time.Duration) {
+	a.action.FinishCanceling(delta)
+}
+
+func (a *timeoutAction) Reset() {
+	a.action.Reset()
+	a.elapsed = 0
+	a.timedOut = false
+}
+
+// Timeout cancels its action if it's still running after the given amount of
+// time, failing once the action finishes canceling. The time is the sum of
+// the deltas passed to Execute.
+type Timeout struct {
+	Action ActionBuilder
+
+	// Duration is how long the action may run for
+	Duration time.Duration
+}
+
+// Build implements ActionBuilder
+func (b Timeout) Build() Action {
+	return &timeoutAction{
+		action:   b.Action.Build(),
+		duration: b.Duration,
+	}
+}
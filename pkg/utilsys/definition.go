@@ -0,0 +1,675 @@
+package utilsys
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+// DefinitionFormat is the format of a declarative AI definition
+type DefinitionFormat string
+
+const (
+	// DefinitionFormatJSON is for definitions written in JSON
+	DefinitionFormatJSON DefinitionFormat = "json"
+
+	// DefinitionFormatYAML is for definitions written in YAML
+	DefinitionFormatYAML DefinitionFormat = "yaml"
+)
+
+// DefinitionProblem is a single problem with a definition
+type DefinitionProblem struct {
+	// Path to the offending value, e.g., $.children[1].scorer.threshold
+	Path string
+
+	// Message describing the problem
+	Message string
+}
+
+// DefinitionError describes every problem found with a definition
+type DefinitionError struct {
+	// Problems with the definition, in the order they were found
+	Problems []DefinitionProblem
+}
+
+// Error implements the error interface for DefinitionError
+func (e *DefinitionError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for idx, problem := range e.Problems {
+		problems[idx] = problem.Path + ": " + problem.Message
+	}
+	return "invalid AI definition: " + strings.Join(problems, "; ")
+}
+
+// ActionConstructor constructs an action from a node of the type it was
+// registered for. Problems with the node's parameters should be reported
+// with Node.Problem, or by returning an error, which is reported at the
+// node. The builder is discarded if there were any problems anywhere in
+// the definition, so it may be nil or incomplete in that case.
+type ActionConstructor func(node *Node) (ActionBuilder, error)
+
+// ScorerConstructor is like ActionConstructor, but for scorers
+type ScorerConstructor func(node *Node) (ScorerBuilder, error)
+
+// BayesFactorConstructor is like ActionConstructor, but for bayes factors
+type BayesFactorConstructor func(node *Node) (BayesFactorBuilder, error)
+
+// Registry maps the types used in declarative AI definitions to the
+// constructors which build them. Registries aren't safe to modify while
+// they're being used to load definitions.
+type Registry struct {
+	actions map[string]ActionConstructor
+	scorers map[string]ScorerConstructor
+	factors map[string]BayesFactorConstructor
+}
+
+// NewRegistry produces a registry containing the built-in types of this
+// package. The actions, scorers and bayes factors specific to your AI must
+// be registered before loading definitions which use them.
+func NewRegistry() *Registry {
+	res := &Registry{
+		actions: make(map[string]ActionConstructor),
+		scorers: make(map[string]ScorerConstructor),
+		factors: make(map[string]BayesFactorConstructor),
+	}
+	registerBuiltins(res)
+	return res
+}
+
+// RegisterAction registers the constructor for actions of the given type,
+// replacing any existing constructor for that type
+func (r *Registry) RegisterAction(typ string, constructor ActionConstructor) {
+	r.actions[typ] = constructor
+}
+
+// RegisterScorer registers the constructor for scorers of the given type,
+// replacing any existing constructor for that type
+func (r *Registry) RegisterScorer(typ string, constructor ScorerConstructor) {
+	r.scorers[typ] = constructor
+}
+
+// RegisterBayesFactor registers the constructor for bayes factors of the
+// given type, replacing any existing constructor for that type
+func (r *Registry) RegisterBayesFactor(typ string, constructor BayesFactorConstructor) {
+	r.factors[typ] = constructor
+}
+
+// LoadFile loads the definition of the core action of an AI from the JSON
+// or YAML file at the given path, where the format is chosen from the
+// extension
+func (r *Registry) LoadFile(path string) (ActionBuilder, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading AI definition: %w", err)
+	}
+
+	var format DefinitionFormat
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = DefinitionFormatYAML
+	case ".json":
+		format = DefinitionFormatJSON
+	default:
+		return nil, fmt.Errorf("unknown AI definition format %q (expected .yaml, .yml or .json)", filepath.Ext(path))
+	}
+	return r.Parse(raw, format)
+}
+
+// Parse parses the definition of the core action of an AI in the given
+// format. If the definition is invalid, the error is a *DefinitionError
+// describing every problem found.
+func (r *Registry) Parse(data []byte, format DefinitionFormat) (ActionBuilder, error) {
+	var document interface{}
+	var err error
+	switch format {
+	case DefinitionFormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&document)
+	case DefinitionFormatYAML:
+		err = yaml.Unmarshal(data, &document)
+	default:
+		return nil, fmt.Errorf("unknown AI definition format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing AI definition: %w", err)
+	}
+	return r.Build(document)
+}
+
+// Build builds the core action of an AI from an already parsed definition,
+// which is a tree of maps and slices as produced by encoding/json or
+// gopkg.in/yaml.v3. If the definition is invalid, the error is a
+// *DefinitionError describing every problem found.
+//
+// Every node describing an action, scorer or bayes factor is a map with a
+// "type", which selects the constructor in the registry, and the parameters
+// for that type. Any action or scorer may also have a "name", which wraps it
+// in a NamedAction or NamedScorer. Durations are either strings as in
+// time.ParseDuration, e.g., "1.5s", or numbers of seconds, and ratios are
+// either strings like "1/4" or numbers.
+func (r *Registry) Build(document interface{}) (ActionBuilder, error) {
+	l := &definitionLoader{registry: r}
+	res := l.action("$", document)
+	if len(l.problems) > 0 {
+		return nil, &DefinitionError{Problems: l.problems}
+	}
+	return res, nil
+}
+
+// definitionLoader tracks the problems found while loading a definition
+type definitionLoader struct {
+	registry *Registry
+	problems []DefinitionProblem
+}
+
+func (l *definitionLoader) problem(path, format string, args ...interface{}) {
+	l.problems = append(l.problems, DefinitionProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// node converts the value at the given path to a node, reporting a problem
+// and returning nil if it's not a map
+func (l *definitionLoader) node(path string, value interface{}) *Node {
+	params, ok := toStringMap(value)
+	if !ok {
+		if value == nil {
+			l.problem(path, "is required")
+		} else {
+			l.problem(path, "expected an object, got %s", describeValue(value))
+		}
+		return nil
+	}
+	return &Node{Path: path, params: params, used: make(map[string]bool), loader: l}
+}
+
+// typedNode converts the value at the given path to a node with a type and
+// an optional name
+func (l *definitionLoader) typedNode(path string, value interface{}) *Node {
+	node := l.node(path, value)
+	if node == nil {
+		return nil
+	}
+	node.Type = node.String("type", "")
+	node.Name = node.String("name", "")
+	if node.Type == "" {
+		if !node.Has("type") {
+			node.Problem("type", "is required")
+		} else if _, ok := node.params["type"].(string); ok {
+			node.Problem("type", "cannot be blank")
+		}
+		return nil
+	}
+	return node
+}
+
+// finish reports the error from a constructor and any unused parameters
+func (l *definitionLoader) finish(node *Node, err error) {
+	if err != nil {
+		l.problem(node.Path, "%s", err.Error())
+	}
+
+	var unused []string
+	for key := range node.params {
+		if !node.used[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	for _, key := range unused {
+		if node.Type == "" {
+			node.Problem(key, "unknown key")
+		} else {
+			node.Problem(key, "unknown key for type %q", node.Type)
+		}
+	}
+}
+
+func (l *definitionLoader) action(path string, value interface{}) ActionBuilder {
+	node := l.typedNode(path, value)
+	if node == nil {
+		return nil
+	}
+	constructor, ok := l.registry.actions[node.Type]
+	if !ok {
+		node.Problem("type", "unknown action type %q", node.Type)
+		return nil
+	}
+
+	res, err := constructor(node)
+	l.finish(node, err)
+	if node.Name != "" && res != nil {
+		res = NamedAction{Name: node.Name, Action: res}
+	}
+	return res
+}
+
+func (l *definitionLoader) scorer(path string, value interface{}) ScorerBuilder {
+	node := l.typedNode(path, value)
+	if node == nil {
+		return nil
+	}
+	constructor, ok := l.registry.scorers[node.Type]
+	if !ok {
+		node.Problem("type", "unknown scorer type %q", node.Type)
+		return nil
+	}
+
+	res, err := constructor(node)
+	l.finish(node, err)
+	if node.Name != "" && res != nil {
+		res = NamedScorer{Name: node.Name, Scorer: res}
+	}
+	return res
+}
+
+func (l *definitionLoader) factor(path string, value interface{}) BayesFactorBuilder {
+	node := l.typedNode(path, value)
+	if node == nil {
+		return nil
+	}
+	if node.Name != "" {
+		node.Problem("name", "bayes factors cannot be named")
+	}
+	constructor, ok := l.registry.factors[node.Type]
+	if !ok {
+		node.Problem("type", "unknown bayes factor type %q", node.Type)
+		return nil
+	}
+
+	res, err := constructor(node)
+	l.finish(node, err)
+	return res
+}
+
+// Node is a single node within a declarative AI definition, which is passed
+// to the constructor registered for its type. The accessors for parameters
+// report a problem at the parameter's path and return the default if the
+// parameter has the wrong type, so constructors can read every parameter
+// and then return without checking each one. Parameters which are never
+// read are reported as unknown.
+type Node struct {
+	// Path to the node within the definition, e.g., $.children[1].action
+	Path string
+
+	// Type of the node, or blank for untyped nodes such as the children of
+	// thinkers
+	Type string
+
+	// Name of the node, or blank if it's unnamed
+	Name string
+
+	params map[string]interface{}
+	used   map[string]bool
+	loader *definitionLoader
+}
+
+// Problem reports a problem with the parameter with the given key, or with
+// the node itself if key is blank
+func (n *Node) Problem(key, format string, args ...interface{}) {
+	n.loader.problem(n.keyPath(key), format, args...)
+}
+
+// Has returns true if the node has the parameter with the given key
+func (n *Node) Has(key string) bool {
+	_, ok := n.params[key]
+	return ok
+}
+
+// Require reports a problem for each of the given keys the node doesn't
+// have, returning true if it has all of them
+func (n *Node) Require(keys ...string) bool {
+	res := true
+	for _, key := range keys {
+		if !n.Has(key) {
+			n.Problem(key, "is required")
+			res = false
+		}
+	}
+	return res
+}
+
+// Float returns the parameter with the given key as a number
+func (n *Node) Float(key string, def float64) float64 {
+	value, ok := n.get(key)
+	if !ok {
+		return def
+	}
+	res, ok := toFloat(value)
+	if !ok {
+		n.Problem(key, "expected a number, got %s", describeValue(value))
+		return def
+	}
+	return res
+}
+
+// Int returns the parameter with the given key as an integer
+func (n *Node) Int(key string, def int) int {
+	value, ok := n.get(key)
+	if !ok {
+		return def
+	}
+	res, ok := toFloat(value)
+	if !ok || res != float64(int(res)) {
+		n.Problem(key, "expected an integer, got %s", describeValue(value))
+		return def
+	}
+	return int(res)
+}
+
+// Bool returns the parameter with the given key as a boolean
+func (n *Node) Bool(key string, def bool) bool {
+	value, ok := n.get(key)
+	if !ok {
+		return def
+	}
+	res, ok := value.(bool)
+	if !ok {
+		n.Problem(key, "expected a boolean, got %s", describeValue(value))
+		return def
+	}
+	return res
+}
+
+// String returns the parameter with the given key as a string
+func (n *Node) String(key string, def string) string {
+	value, ok := n.get(key)
+	if !ok {
+		return def
+	}
+	res, ok := value.(string)
+	if !ok {
+		n.Problem(key, "expected a string, got %s", describeValue(value))
+		return def
+	}
+	return res
+}
+
+// Duration returns the parameter with the given key as a duration, which is
+// either a string as in time.ParseDuration or a number of seconds
+func (n *Node) Duration(key string, def time.Duration) time.Duration {
+	value, ok := n.get(key)
+	if !ok {
+		return def
+	}
+	if str, ok := value.(string); ok {
+		res, err := time.ParseDuration(str)
+		if err != nil {
+			n.Problem(key, "invalid duration %q", str)
+			return def
+		}
+		return res
+	}
+	seconds, ok := toFloat(value)
+	if !ok {
+		n.Problem(key, "expected a duration, got %s", describeValue(value))
+		return def
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Ratio returns the parameter with the given key as a ratio, which is either
+// a string like "1/4" or a number. Returns nil if the key is missing or the
+// ratio is invalid.
+func (n *Node) Ratio(key string) *big.Rat {
+	value, ok := n.get(key)
+	if !ok {
+		return nil
+	}
+	switch v := value.(type) {
+	case string:
+		res, ok := new(big.Rat).SetString(v)
+		if !ok {
+			n.Problem(key, "invalid ratio %q", v)
+			return nil
+		}
+		return res
+	case json.Number:
+		res, ok := new(big.Rat).SetString(v.String())
+		if !ok {
+			n.Problem(key, "invalid ratio %q", v.String())
+			return nil
+		}
+		return res
+	}
+	f, ok := toFloat(value)
+	if !ok {
+		n.Problem(key, "expected a ratio, got %s", describeValue(value))
+		return nil
+	}
+	return new(big.Rat).SetFloat64(f)
+}
+
+// Decode decodes all of the parameters other than type and name into the
+// given pointer to a struct, using mapstructure tags. Durations are decoded
+// as in Duration.
+func (n *Node) Decode(out interface{}) error {
+	params := make(map[string]interface{}, len(n.params))
+	for key, value := range n.params {
+		n.used[key] = true
+		if key != "type" && key != "name" {
+			params[key] = value
+		}
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       durationHook,
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+		Result:           out,
+	})
+	if err != nil {
+		return fmt.Errorf("constructing decoder: %w", err)
+	}
+	return decoder.Decode(params)
+}
+
+// Object returns the parameter with the given key as an untyped node, or
+// nil if it's missing or not an object
+func (n *Node) Object(key string) *Node {
+	value, ok := n.get(key)
+	if !ok {
+		return nil
+	}
+	return n.loader.node(n.keyPath(key), value)
+}
+
+// Objects returns the parameter with the given key as a list of untyped
+// nodes, reporting a problem if it's missing or empty
+func (n *Node) Objects(key string) []*Node {
+	var res []*Node
+	n.each(key, func(path string, value interface{}) {
+		res = append(res, n.loader.node(path, value))
+	})
+	return res
+}
+
+// Action builds the action in the parameter with the given key, reporting a
+// problem if it's missing
+func (n *Node) Action(key string) ActionBuilder {
+	value, _ := n.get(key)
+	return n.loader.action(n.keyPath(key), value)
+}
+
+// Actions builds the list of actions in the parameter with the given key,
+// reporting a problem if it's missing or empty
+func (n *Node) Actions(key string) []ActionBuilder {
+	var res []ActionBuilder
+	n.each(key, func(path string, value interface{}) {
+		res = append(res, n.loader.action(path, value))
+	})
+	return res
+}
+
+// Scorer builds the scorer in the parameter with the given key, reporting a
+// problem if it's missing
+func (n *Node) Scorer(key string) ScorerBuilder {
+	value, _ := n.get(key)
+	return n.loader.scorer(n.keyPath(key), value)
+}
+
+// Scorers builds the list of scorers in the parameter with the given key,
+// reporting a problem if it's missing or empty
+func (n *Node) Scorers(key string) []ScorerBuilder {
+	var res []ScorerBuilder
+	n.each(key, func(path string, value interface{}) {
+		res = append(res, n.loader.scorer(path, value))
+	})
+	return res
+}
+
+// BayesFactors builds the list of bayes factors in the parameter with the
+// given key, reporting a problem if it's missing or empty
+func (n *Node) BayesFactors(key string) []BayesFactorBuilder {
+	var res []BayesFactorBuilder
+	n.each(key, func(path string, value interface{}) {
+		res = append(res, n.loader.factor(path, value))
+	})
+	return res
+}
+
+// ScoredActions builds the list of scored actions in the parameter with the
+// given key, reporting a problem if it's missing or empty. Each scored
+// action is an object with an "action" and a "scorer", and optionally a
+// "cooldown" with "min" and "max" durations and "suppressed_on_success"
+// and "suppressed_on_failure" booleans, which makes it a
+// CooldownQualifiedScoredAction.
+func (n *Node) ScoredActions(key string) []ScoredActionBuilder {
+	var res []ScoredActionBuilder
+	for _, child := range n.Objects(key) {
+		if child == nil {
+			continue
+		}
+		res = append(res, child.scoredAction())
+	}
+	return res
+}
+
+func (n *Node) scoredAction() ScoredActionBuilder {
+	var res ScoredActionBuilder = ScorerBuilderAndActionBuilder{
+		Action: n.Action("action"),
+		Scorer: n.Scorer("scorer"),
+	}
+
+	if cooldown := n.Object("cooldown"); cooldown != nil {
+		cooldown.Require("min", "max")
+		built := CooldownQualifiedScoredAction{
+			ScoredAction:                res,
+			MinCooldown:                 cooldown.Duration("min", 0),
+			MaxCooldown:                 cooldown.Duration("max", 0),
+			CooldownSuppressedOnSuccess: cooldown.Bool("suppressed_on_success", false),
+			CooldownSuppressedOnFailure: cooldown.Bool("suppressed_on_failure", false),
+		}
+		if built.MinCooldown < 0 {
+			cooldown.Problem("min", "cannot be negative")
+		}
+		if built.MaxCooldown < built.MinCooldown {
+			cooldown.Problem("max", "cannot be less than min")
+		}
+		cooldown.loader.finish(cooldown, nil)
+		res = built
+	}
+
+	n.loader.finish(n, nil)
+	return res
+}
+
+// get returns the parameter with the given key, marking it used
+func (n *Node) get(key string) (interface{}, bool) {
+	value, ok := n.params[key]
+	n.used[key] = true
+	return value, ok
+}
+
+// each calls fn with the path and value of each element of the non-empty
+// list in the parameter with the given key
+func (n *Node) each(key string, fn func(path string, value interface{})) {
+	value, ok := n.get(key)
+	if !ok {
+		n.Problem(key, "is required")
+		return
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		n.Problem(key, "expected a list, got %s", describeValue(value))
+		return
+	}
+	if len(list) == 0 {
+		n.Problem(key, "cannot be empty")
+		return
+	}
+	for idx, elem := range list {
+		fn(fmt.Sprintf("%s[%d]", n.keyPath(key), idx), elem)
+	}
+}
+
+// keyPath returns the path to the parameter with the given key
+func (n *Node) keyPath(key string) string {
+	if key == "" {
+		return n.Path
+	}
+	return n.Path + "." + key
+}
+
+// toStringMap converts a parsed object to a map with string keys
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			res[fmt.Sprint(key)] = val
+		}
+		return res, true
+	}
+	return nil, false
+}
+
+// toFloat converts a parsed number to a float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		res, err := v.Float64()
+		return res, err == nil
+	}
+	return 0, false
+}
+
+// describeValue describes the type of a parsed value for problems
+func describeValue(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "an object"
+	}
+	if _, ok := toFloat(value); ok {
+		return "a number"
+	}
+	return fmt.Sprintf("%T", value)
+}
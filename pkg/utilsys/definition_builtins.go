@@ -0,0 +1,268 @@
+package utilsys
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// registerBuiltins registers the types of this package on the registry
+func registerBuiltins(r *Registry) {
+	r.RegisterAction("highest_score_thinker", thinkerConstructor(func(node *Node) Thinker {
+		return highestScoreThinker{}
+	}))
+	r.RegisterAction("first_to_score_thinker", thinkerConstructor(func(node *Node) Thinker {
+		node.Require("threshold")
+		return firstToScoreThinker{threshold: node.Float("threshold", 0), fallback: highestScoreThinker{}}
+	}))
+	r.RegisterAction("linear_probabilistic_thinker", thinkerConstructor(func(node *Node) Thinker {
+		return linearProbabilisticThinker{threshold: node.Float("threshold", 0)}
+	}))
+	r.RegisterAction("soft_max_probabilistic_thinker", thinkerConstructor(func(node *Node) Thinker {
+		node.Require("factor")
+		factor := node.Float("factor", 0)
+		if factor < 0 {
+			node.Problem("factor", "cannot be negative")
+		}
+		return softMaxProbabilisticThinker{scoreFactor: factor, threshold: node.Float("threshold", 0)}
+	}))
+	r.RegisterAction("idle", buildIdleAction)
+	r.RegisterAction("sequence", func(node *Node) (ActionBuilder, error) {
+		return Sequence{Children: node.Actions("children")}, nil
+	})
+	r.RegisterAction("selector", func(node *Node) (ActionBuilder, error) {
+		return Selector{Children: node.Actions("children")}, nil
+	})
+	r.RegisterAction("parallel", buildParallel)
+	r.RegisterAction("invert", func(node *Node) (ActionBuilder, error) {
+		return Invert{Action: node.Action("action")}, nil
+	})
+	r.RegisterAction("always_succeed", func(node *Node) (ActionBuilder, error) {
+		return AlwaysSucceed{Action: node.Action("action")}, nil
+	})
+	r.RegisterAction("repeat", func(node *Node) (ActionBuilder, error) {
+		times := node.Int("times", 0)
+		if times < 0 {
+			node.Problem("times", "cannot be negative")
+		}
+		return Repeat{Action: node.Action("action"), Times: times}, nil
+	})
+	r.RegisterAction("retry", func(node *Node) (ActionBuilder, error) {
+		return Retry{Action: node.Action("action"), Retries: node.Int("retries", 0)}, nil
+	})
+	r.RegisterAction("timeout", func(node *Node) (ActionBuilder, error) {
+		node.Require("duration")
+		duration := node.Duration("duration", 0)
+		if duration <= 0 && node.Has("duration") {
+			node.Problem("duration", "must be positive")
+		}
+		return Timeout{Action: node.Action("action"), Duration: duration}, nil
+	})
+
+	r.RegisterScorer("fixed", func(node *Node) (ScorerBuilder, error) {
+		node.Require("score")
+		return FixedScorer{Score: node.Float("score", 0)}, nil
+	})
+	r.RegisterScorer("factor", func(node *Node) (ScorerBuilder, error) {
+		node.Require("factor")
+		return NewFactorQualifier(node.Float("factor", 0), node.Scorer("scorer")), nil
+	})
+	r.RegisterScorer("inverter", func(node *Node) (ScorerBuilder, error) {
+		return InverterQualifier{Scorer: node.Scorer("scorer")}, nil
+	})
+	r.RegisterScorer("curve", func(node *Node) (ScorerBuilder, error) {
+		return CurveQualifier{Scorer: node.Scorer("scorer"), Curve: buildCurve(node)}, nil
+	})
+	r.RegisterScorer("mult", func(node *Node) (ScorerBuilder, error) {
+		return MultCombineQualifier{Children: node.Scorers("children")}, nil
+	})
+	r.RegisterScorer("sum", func(node *Node) (ScorerBuilder, error) {
+		return SumCombineQualifier{Children: node.Scorers("children")}, nil
+	})
+	r.RegisterScorer("average", func(node *Node) (ScorerBuilder, error) {
+		return AverageCombineQualifier{Children: node.Scorers("children")}, nil
+	})
+	r.RegisterScorer("max", func(node *Node) (ScorerBuilder, error) {
+		return MaxCombineQualifier{Children: node.Scorers("children")}, nil
+	})
+	r.RegisterScorer("min", func(node *Node) (ScorerBuilder, error) {
+		return MinCombineQualifier{Children: node.Scorers("children")}, nil
+	})
+	r.RegisterScorer("geometric_mean", func(node *Node) (ScorerBuilder, error) {
+		return GeometricMeanCombineQualifier{Children: node.Scorers("children")}, nil
+	})
+	r.RegisterScorer("compensated_mult", func(node *Node) (ScorerBuilder, error) {
+		return CompensatedMultCombineQualifier{Children: node.Scorers("children")}, nil
+	})
+	r.RegisterScorer("weighted_sum", buildWeightedSum)
+	r.RegisterScorer("bayes", buildBayesScorer)
+}
+
+// thinkerConstructor produces the constructor for a thinker type, where the
+// Thinker is produced from the node's parameters by makeThinker. Thinkers
+// have a list of scored action "children" and may have "interrupt" with an
+// "interval", "hysteresis" and "commitment_bonus" to make them
+// interruptible.
+func thinkerConstructor(makeThinker func(node *Node) Thinker) ActionConstructor {
+	return func(node *Node) (ActionBuilder, error) {
+		thinker := makeThinker(node)
+		children := node.ScoredActions("children")
+
+		var opts *InterruptOptions
+		if interrupt := node.Object("interrupt"); interrupt != nil {
+			opts = &InterruptOptions{
+				Interval:        interrupt.Duration("interval", 0),
+				Hysteresis:      interrupt.Float("hysteresis", 0),
+				CommitmentBonus: interrupt.Float("commitment_bonus", 0),
+			}
+			if opts.Interval < 0 {
+				interrupt.Problem("interval", "cannot be negative")
+			}
+			interrupt.loader.finish(interrupt, nil)
+		}
+
+		if len(children) == 0 {
+			return nil, nil
+		}
+		if opts != nil {
+			return NewInterruptibleThinkerBuilder(thinker, children, *opts), nil
+		}
+		return NewThinkerBuilder(thinker, children), nil
+	}
+}
+
+func buildIdleAction(node *Node) (ActionBuilder, error) {
+	node.Require("min_duration", "max_duration")
+	res := IdleAction{
+		MinDuration: node.Duration("min_duration", 0),
+		MaxDuration: node.Duration("max_duration", 0),
+	}
+	if res.MinDuration < 0 {
+		node.Problem("min_duration", "cannot be negative")
+	}
+	if res.MaxDuration < res.MinDuration {
+		node.Problem("max_duration", "cannot be less than min_duration")
+	}
+	return res, nil
+}
+
+// parallelPolicy reads the policy with the given key, which is "all" or "one"
+func parallelPolicy(node *Node, key string) ParallelPolicy {
+	switch policy := node.String(key, "all"); policy {
+	case "all":
+		return ParallelRequireAll
+	case "one":
+		return ParallelRequireOne
+	default:
+		node.Problem(key, "expected \"all\" or \"one\", got %q", policy)
+		return ParallelRequireAll
+	}
+}
+
+func buildParallel(node *Node) (ActionBuilder, error) {
+	return Parallel{
+		Children:      node.Actions("children"),
+		SuccessPolicy: parallelPolicy(node, "success_policy"),
+		FailurePolicy: parallelPolicy(node, "failure_policy"),
+	}, nil
+}
+
+func buildWeightedSum(node *Node) (ScorerBuilder, error) {
+	var children []WeightedScorer
+	for _, child := range node.Objects("children") {
+		if child == nil {
+			continue
+		}
+		child.Require("weight")
+		weight := child.Float("weight", 0)
+		if weight < 0 {
+			child.Problem("weight", "cannot be negative")
+		}
+		children = append(children, WeightedScorer{Weight: weight, Scorer: child.Scorer("scorer")})
+		child.loader.finish(child, nil)
+	}
+	return WeightedSumCombineQualifier{Children: children}, nil
+}
+
+func buildBayesScorer(node *Node) (ScorerBuilder, error) {
+	node.Require("prior")
+	prior := node.Ratio("prior")
+	factors := node.BayesFactors("factors")
+	if prior == nil || len(factors) == 0 {
+		return nil, nil
+	}
+	if prior.Sign() <= 0 {
+		node.Problem("prior", "must be positive")
+		return nil, nil
+	}
+	return NewBayesScorer(prior, factors), nil
+}
+
+// buildCurve builds the curve in the "curve" parameter of the node
+func buildCurve(node *Node) Curve {
+	value, _ := node.get("curve")
+	curveNode := node.loader.typedNode(node.keyPath("curve"), value)
+	if curveNode == nil {
+		return nil
+	}
+	if curveNode.Name != "" {
+		curveNode.Problem("name", "curves cannot be named")
+	}
+
+	var res Curve
+	switch curveNode.Type {
+	case "linear":
+		res = LinearCurve{
+			Slope:     curveNode.Float("slope", 1),
+			Intercept: curveNode.Float("intercept", 0),
+		}
+	case "polynomial":
+		curveNode.Require("exponent")
+		res = PolynomialCurve{
+			Exponent: curveNode.Float("exponent", 1),
+			Slope:    curveNode.Float("slope", 1),
+			XShift:   curveNode.Float("x_shift", 0),
+			YShift:   curveNode.Float("y_shift", 0),
+		}
+	case "logistic":
+		res = LogisticCurve{
+			Steepness: curveNode.Float("steepness", 0),
+			Midpoint:  curveNode.Float("midpoint", 0.5),
+		}
+	case "logit":
+		res = LogitCurve{Steepness: curveNode.Float("steepness", 0)}
+	case "exponential_decay":
+		curveNode.Require("rate")
+		res = ExponentialDecayCurve{Rate: curveNode.Float("rate", 0)}
+	case "piecewise_linear":
+		var points []CurvePoint
+		for _, point := range curveNode.Objects("points") {
+			if point == nil {
+				continue
+			}
+			point.Require("x", "y")
+			points = append(points, CurvePoint{X: point.Float("x", 0), Y: point.Float("y", 0)})
+			point.loader.finish(point, nil)
+		}
+		res = NewPiecewiseLinearCurve(points...)
+	default:
+		curveNode.Problem("type", "unknown curve type %q", curveNode.Type)
+		return nil
+	}
+	curveNode.loader.finish(curveNode, nil)
+	return res
+}
+
+// durationHook decodes durations for Node.Decode as in Node.Duration
+func durationHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(time.Duration(0)) {
+		return data, nil
+	}
+	if str, ok := data.(string); ok {
+		return time.ParseDuration(str)
+	}
+	if seconds, ok := toFloat(data); ok {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return nil, fmt.Errorf("expected a duration, got %s", describeValue(data))
+}
@@ -0,0 +1,322 @@
+package utilsys_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+)
+
+type fixedFactor struct {
+	factor *big.Rat
+}
+
+func (f fixedFactor) Attached(world, actor interface{}) {}
+func (f fixedFactor) Factor() *big.Rat                  { return f.factor }
+func (f fixedFactor) Build() utilsys.BayesFactor        { return f }
+
+// testRegistry registers "succeed", which succeeds after the given number
+// of ticks, and "odds", a bayes factor with a fixed ratio
+func testRegistry() *utilsys.Registry {
+	registry := utilsys.NewRegistry()
+	registry.RegisterAction("succeed", func(node *utilsys.Node) (utilsys.ActionBuilder, error) {
+		var params struct {
+			Ticks int `mapstructure:"ticks"`
+		}
+		if err := node.Decode(&params); err != nil {
+			return nil, err
+		}
+		if params.Ticks <= 0 {
+			return nil, errors.New("ticks must be positive")
+		}
+		return builtAction{succeeds(params.Ticks)}, nil
+	})
+	registry.RegisterBayesFactor("odds", func(node *utilsys.Node) (utilsys.BayesFactorBuilder, error) {
+		node.Require("ratio")
+		return fixedFactor{node.Ratio("ratio")}, nil
+	})
+	return registry
+}
+
+const testDefinitionYAML = `
+type: highest_score_thinker
+interrupt:
+  interval: 0.5
+  hysteresis: 0.1
+children:
+  - action:
+      type: idle
+      name: rest
+      min_duration: 1s
+      max_duration: 2s
+    scorer: {type: fixed, score: 0.1}
+  - action: {type: succeed, name: mine, ticks: 2}
+    scorer:
+      type: mult
+      name: worth mining
+      children:
+        - type: curve
+          scorer: {type: fixed, score: 0.5}
+          curve: {type: linear, slope: 2}
+        - type: bayes
+          prior: 1/4
+          factors: [{type: odds, ratio: 4}]
+    cooldown: {min: 1s, max: 3s}
+  - action:
+      type: sequence
+      children:
+        - {type: timeout, duration: 5s, action: {type: succeed, ticks: 1}}
+        - {type: retry, retries: 2, action: {type: invert, action: {type: succeed, ticks: 1}}}
+    scorer:
+      type: weighted_sum
+      children:
+        - {weight: 1, scorer: {type: fixed, score: 0.2}}
+`
+
+const testDefinitionJSON = `{
+  "type": "first_to_score_thinker",
+  "threshold": 0.5,
+  "children": [
+    {"action": {"type": "succeed", "name": "mine", "ticks": 1}, "scorer": {"type": "fixed", "score": 0.4}},
+    {"action": {"type": "parallel", "success_policy": "one", "children": [{"type": "succeed", "ticks": 1}]},
+     "scorer": {"type": "fixed", "score": 0.6}}
+  ]
+}`
+
+func TestRegistry_Parse(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   utilsys.DefinitionFormat
+		data     string
+		children []string
+		selected int
+	}{
+		{"yaml", utilsys.DefinitionFormatYAML, testDefinitionYAML, []string{"rest", "mine", "#2"}, 1},
+		{"json", utilsys.DefinitionFormatJSON, testDefinitionJSON, []string{"mine", "#1"}, 1},
+	}
+
+	for _, tc := range cases {
+		builder, err := testRegistry().Parse([]byte(tc.data), tc.format)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		tracer := &recordingTracer{}
+		ai := utilsys.NewAI(nil, builder)
+		ai.SetTracer(tracer, nil)
+		ai.AddActor("actor")
+		for i := 0; i < 5; i++ {
+			ai.Tick(50 * time.Millisecond)
+		}
+
+		decision := tracer.decisions[0]
+		names := make([]string, len(decision.Children))
+		for idx, child := range decision.Children {
+			names[idx] = child.Name
+		}
+		if !reflect.DeepEqual(names, tc.children) || decision.Selected != tc.selected {
+			t.Fatalf("%s: expected children %v selecting %d, got %v selecting %d", tc.name, tc.children, tc.selected, names, decision.Selected)
+		}
+	}
+}
+
+func TestRegistry_Parse_scores(t *testing.T) {
+	builder, err := testRegistry().Parse([]byte(testDefinitionYAML), utilsys.DefinitionFormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer := &recordingTracer{}
+	ai := utilsys.NewAI(nil, builder)
+	ai.SetTracer(tracer, nil)
+	ai.AddActor("actor")
+
+	// the curve doubles 0.5 to 1, and 1/4 odds improved 4 times are 1/1
+	cooldown := tracer.decisions[0].Children[1].Explanation
+	if cooldown.Kind != "cooldown" || len(cooldown.Children) != 1 {
+		t.Fatalf("unexpected explanation %+v", cooldown)
+	}
+	mine := cooldown.Children[0]
+	if mine.Name != "worth mining" || mine.Kind != "mult" || len(mine.Children) != 2 ||
+		mine.Children[0].Score != 1 || mine.Children[1].Score != 0.5 {
+		t.Fatalf("unexpected explanation %+v", mine)
+	}
+}
+
+func TestRegistry_Parse_piecewiseLinear(t *testing.T) {
+	definition := `
+type: highest_score_thinker
+children:
+  - action: {type: succeed, ticks: 1}
+    scorer:
+      type: curve
+      scorer: {type: fixed, score: 0.75}
+      curve:
+        type: piecewise_linear
+        points: [{x: 0.5, y: 1}, {x: 0, y: 0}, {x: 1, y: 0.5}]
+`
+	builder, err := testRegistry().Parse([]byte(definition), utilsys.DefinitionFormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer := &recordingTracer{}
+	ai := utilsys.NewAI(nil, builder)
+	ai.SetTracer(tracer, nil)
+	ai.AddActor("actor")
+
+	// the points are sorted when loaded, so 0.75 is between the last two
+	if curve := tracer.decisions[0].Children[0].Explanation; curve.Kind != "curve" || curve.Score != 0.75 {
+		t.Fatalf("unexpected explanation %+v", curve)
+	}
+}
+
+func TestRegistry_Parse_problems(t *testing.T) {
+	definition := `
+type: soft_max_probabilistic_thinker
+factor: -1
+children:
+  - action: {type: idle, min_duration: 2s, max_duration: 1s}
+    scorer: {type: fixed}
+  - action: {type: unknown}
+    scorer: {type: curve, scorer: {type: fixed, score: 1}, curve: {type: logistic, midpont: 0.5}}
+    cooldown: {min: soon, max: 1s}
+  - action: {type: succeed, ticks: 0}
+    scorer: {type: bayes, prior: "1/0x", factors: []}
+    extra: true
+  - action: {type: repeat, times: 1.5, action: [1]}
+`
+	_, err := testRegistry().Parse([]byte(definition), utilsys.DefinitionFormatYAML)
+	var defErr *utilsys.DefinitionError
+	if !errors.As(err, &defErr) {
+		t.Fatalf("expected a DefinitionError, got %v", err)
+	}
+
+	expected := []utilsys.DefinitionProblem{
+		{Path: "$.factor", Message: "cannot be negative"},
+		{Path: "$.children[0].action.max_duration", Message: "cannot be less than min_duration"},
+		{Path: "$.children[0].scorer.score", Message: "is required"},
+		{Path: "$.children[1].action.type", Message: `unknown action type "unknown"`},
+		{Path: "$.children[1].scorer.curve.midpont", Message: `unknown key for type "logistic"`},
+		{Path: "$.children[1].cooldown.min", Message: `invalid duration "soon"`},
+		{Path: "$.children[2].action", Message: "ticks must be positive"},
+		{Path: "$.children[2].scorer.prior", Message: `invalid ratio "1/0x"`},
+		{Path: "$.children[2].scorer.factors", Message: "cannot be empty"},
+		{Path: "$.children[2].extra", Message: "unknown key"},
+		{Path: "$.children[3].action.times", Message: "expected an integer, got a number"},
+		{Path: "$.children[3].action.action", Message: "expected an object, got a list"},
+		{Path: "$.children[3].scorer", Message: "is required"},
+	}
+	if !reflect.DeepEqual(defErr.Problems, expected) {
+		t.Fatalf("expected problems\n%v\ngot\n%v", expected, defErr.Problems)
+	}
+}
+
+func TestHotReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ai.json")
+	write := func(definition string, modTime time.Time) {
+		if err := ioutil.WriteFile(path, []byte(definition), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write(`{"type": "highest_score_thinker", "children": [
+		{"action": {"type": "succeed", "name": "first", "ticks": 3}, "scorer": {"type": "fixed", "score": 1}}]}`, start)
+
+	reloader, err := utilsys.NewHotReloader(testRegistry(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := &recordingTracer{}
+	ai := utilsys.NewAI(nil, reloader)
+	ai.SetTracer(tracer, nil)
+	reloader.Attach(ai)
+	ai.AddActor("actor")
+	ai.Tick(50 * time.Millisecond)
+
+	if reloaded, err := reloader.Reload(); reloaded || err != nil {
+		t.Fatalf("expected no reload without a change, got %v, %v", reloaded, err)
+	}
+
+	write(`{"type": "highest_score_thinker", "children": [
+		{"action": {"type": "succeed", "name": "second", "ticks": 3}, "scorer": {"type": "fixed", "score": 1}}]}`, start.Add(time.Minute))
+	if reloaded, err := reloader.Reload(); !reloaded || err != nil {
+		t.Fatalf("expected a reload, got %v, %v", reloaded, err)
+	}
+
+	expected := []string{"init->requested", "requested->executing", "executing->failure"}
+	if actual := tracer.transitionsOf("root/first"); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected first to be canceled with transitions %v, got %v", expected, actual)
+	}
+	if actual := tracer.transitionsOf("root/second"); !reflect.DeepEqual(actual, []string{"init->requested"}) {
+		t.Fatalf("expected second to be attached, got %v", actual)
+	}
+
+	write(`{"type": "highest_score_thinker"}`, start.Add(2*time.Minute))
+	if reloaded, err := reloader.Reload(); reloaded || err == nil {
+		t.Fatalf("expected an invalid definition to fail, got %v, %v", reloaded, err)
+	}
+	ai.Tick(50 * time.Millisecond)
+	if actual := tracer.transitionsOf("root/second"); len(actual) != 2 {
+		t.Fatalf("expected the previous definition to keep running, got %v", actual)
+	}
+}
+
+func TestHotReloader_stateBinder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "worker.json")
+	write := func(definition string, modTime time.Time) {
+		if err := ioutil.WriteFile(path, []byte(definition), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write(`{"type": "succeed", "name": "first", "ticks": 3}`, start)
+
+	reloader, err := utilsys.NewHotReloader(testRegistry(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	tracer := &recordingTracer{}
+	ai := utilsys.NewAI(nil, boundActionBuilder{kind: "core", events: &events})
+	ai.SetTracer(tracer, nil)
+	reloader.Attach(ai)
+
+	state := client.NewState()
+	state.HandleMessage(gameSync())
+	utilsys.BindState(ai, state, nil, map[string]utilsys.ActionBuilder{
+		"worker": reloader,
+		"tower":  boundActionBuilder{kind: "tower", events: &events},
+	})
+	ai.Tick(50 * time.Millisecond)
+	takeEvents(&events)
+
+	write(`{"type": "succeed", "name": "second", "ticks": 3}`, start.Add(time.Minute))
+	if reloaded, err := reloader.Reload(); !reloaded || err != nil {
+		t.Fatalf("expected a reload, got %v, %v", reloaded, err)
+	}
+
+	expected := []string{"init->requested", "requested->executing", "executing->failure"}
+	if actual := tracer.transitionsOf("root/first"); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected the bound worker's action to be canceled with transitions %v, got %v", expected, actual)
+	}
+	if actual := tracer.transitionsOf("root/second"); !reflect.DeepEqual(actual, []string{"init->requested"}) {
+		t.Fatalf("expected the bound worker's action to be rebuilt, got %v", actual)
+	}
+	if got := takeEvents(&events); len(got) != 0 {
+		t.Fatalf("expected actors which don't use the reloader to be left alone, got %v", got)
+	}
+}
@@ -0,0 +1,52 @@
+package utilsys
+
+import "fmt"
+
+// ScoreExplanation breaks a score down through the qualifier tree which
+// produced it
+type ScoreExplanation struct {
+	// Name of the scorer, if it was named with a NamedScorer
+	Name string `json:"name,omitempty"`
+
+	// Kind of scorer, e.g., "mult" or "curve", or its type for scorers which
+	// can't explain themselves
+	Kind string `json:"kind"`
+
+	// Detail about the scorer, e.g., the curve used
+	Detail string `json:"detail,omitempty"`
+
+	// Score the scorer produced
+	Score float64 `json:"score"`
+
+	// Children are the explanations of the scorers this score was built from
+	Children []ScoreExplanation `json:"children,omitempty"`
+}
+
+// ExplainableScorer is a Scorer which can break down its score. The score in
+// the explanation must be what Score would have returned, and it should be
+// computed from the scores in the children's explanations rather than by
+// scoring them again, so that each scorer is only evaluated once.
+type ExplainableScorer interface {
+	Scorer
+
+	// Explain scores and returns the breakdown
+	Explain() ScoreExplanation
+}
+
+// ExplainScore scores the given scorer and explains the score if the scorer
+// supports it. Otherwise the explanation just contains the score.
+func ExplainScore(scorer Scorer) ScoreExplanation {
+	if explainable, ok := scorer.(ExplainableScorer); ok {
+		return explainable.Explain()
+	}
+	return ScoreExplanation{Kind: fmt.Sprintf("%T", scorer), Score: scorer.Score()}
+}
+
+// explainChildren explains each of the given scorers
+func explainChildren(scorers []Scorer) []ScoreExplanation {
+	res := make([]ScoreExplanation, len(scorers))
+	for idx, scorer := range scorers {
+		res[idx] = ExplainScore(scorer)
+	}
+	return res
+}
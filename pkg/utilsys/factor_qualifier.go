@@ -1,5 +1,7 @@
 package utilsys
 
+import "fmt"
+
 type factorQualifier struct {
 	factor float64
 	scorer Scorer
@@ -13,6 +15,16 @@ func (q *factorQualifier) Score() float64 {
 	return q.factor * q.scorer.Score()
 }
 
+func (q *factorQualifier) Explain() ScoreExplanation {
+	child := ExplainScore(q.scorer)
+	return ScoreExplanation{
+		Kind:     "factor",
+		Detail:   fmt.Sprintf("x%v", q.factor),
+		Score:    q.factor * child.Score,
+		Children: []ScoreExplanation{child},
+	}
+}
+
 type factorQualifierBuilder struct {
 	factor float64
 	scorer ScorerBuilder
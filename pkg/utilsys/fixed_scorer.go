@@ -6,6 +6,9 @@ type fixedScorer struct {
 
 func (s *fixedScorer) Attached(world, actor interface{}) {}
 func (s *fixedScorer) Score() float64                    { return s.score }
+func (s *fixedScorer) Explain() ScoreExplanation {
+	return ScoreExplanation{Kind: "fixed", Score: s.score}
+}
 
 // FixedScorer is the simplest type of scorer which always returns
 // the same value
@@ -0,0 +1,87 @@
+package utilsys
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// HotReloader reloads a declarative AI definition whenever the file changes,
+// rebuilding the actions it built for the actors in the AIs it's attached
+// to. It's
+// not safe for concurrent use, so Reload should be called from the same
+// goroutine which ticks the AIs.
+type HotReloader struct {
+	registry *Registry
+	path     string
+
+	modTime time.Time
+	size    int64
+	current ActionBuilder
+	ais     []*AI
+}
+
+// NewHotReloader loads the definition at the given path with the given
+// registry, as in Registry.LoadFile. The returned reloader is the
+// ActionBuilder for the definition, so it can be passed straight to NewAI.
+func NewHotReloader(registry *Registry, path string) (*HotReloader, error) {
+	res := &HotReloader{registry: registry, path: path}
+	_, err := res.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Build implements ActionBuilder using the most recently loaded definition
+func (r *HotReloader) Build() Action {
+	return r.current.Build()
+}
+
+// Attach makes the reloader rebuild the actions of the actors in the given
+// AI which use the reloader whenever the definition is reloaded. Actors use
+// the reloader if it's the AI's core action or they were added with it,
+// e.g., through a StateBinder, so one reloader may be used per unit type.
+func (r *HotReloader) Attach(ai *AI) {
+	r.ais = append(r.ais, ai)
+}
+
+// Detach stops the reloader rebuilding the actions of the given AI
+func (r *HotReloader) Detach(ai *AI) {
+	for idx, other := range r.ais {
+		if other == ai {
+			r.ais = append(r.ais[:idx], r.ais[idx+1:]...)
+			return
+		}
+	}
+}
+
+// Reload reloads the definition if the file has changed since it was last
+// loaded, returning true if it was reloaded. If the new definition is
+// invalid the error is returned and the previous definition is kept, so
+// designers can fix mistakes without the AI stopping. This only stats the
+// file unless it changed, so it's cheap enough to call every few ticks.
+func (r *HotReloader) Reload() (bool, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, fmt.Errorf("checking AI definition: %w", err)
+	}
+	if r.current != nil && info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return false, nil
+	}
+
+	builder, err := r.registry.LoadFile(r.path)
+
+	// even if it's invalid we don't want to try again until it changes
+	r.modTime = info.ModTime()
+	r.size = info.Size()
+	if err != nil {
+		return false, err
+	}
+
+	r.current = builder
+	for _, ai := range r.ais {
+		ai.RebuildActors(r)
+	}
+	return true, nil
+}
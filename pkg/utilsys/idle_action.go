@@ -8,9 +8,32 @@ import (
 type idleAction struct {
 	minDuration time.Duration
 	maxDuration time.Duration
+	clock       Clock
+	ctx         *actionContext
 
-	state             ActionState
-	durationRemaining time.Duration
+	state    ActionState
+	duration time.Duration
+
+	// startedAt is when we started idling according to the clock, if
+	// there's a clock
+	startedAt time.Duration
+
+	// elapsed is the sum of the deltas since we started idling, which is
+	// used when there's no clock
+	elapsed time.Duration
+}
+
+func (a *idleAction) setContext(ctx *actionContext) {
+	a.ctx = ctx
+}
+
+// getClock returns the clock for the action, or nil if neither the action
+// nor the AI were given one
+func (a *idleAction) getClock() Clock {
+	if a.clock != nil {
+		return a.clock
+	}
+	return a.ctx.setClock()
 }
 
 func (a *idleAction) State() ActionState {
@@ -23,12 +46,26 @@ func (a *idleAction) Attached(world, actor interface{}) {
 
 func (a *idleAction) Execute(delta time.Duration) {
 	if a.state == ActionStateRequested {
-		a.durationRemaining = a.minDuration + time.Duration(rand.Int63n(int64(a.maxDuration-a.minDuration)))
+		a.duration = a.minDuration
+		if a.maxDuration > a.minDuration {
+			a.duration += time.Duration(rand.Int63n(int64(a.maxDuration - a.minDuration)))
+		}
+		if clock := a.getClock(); clock != nil {
+			a.startedAt = clock.Now()
+		}
+		a.elapsed = 0
 		a.state = ActionStateExecuting
 	}
 
-	a.durationRemaining -= delta
-	if a.durationRemaining < 0 {
+	if clock := a.getClock(); clock != nil {
+		if clock.Now()-a.startedAt >= a.duration {
+			a.state = ActionStateSuccess
+		}
+		return
+	}
+
+	a.elapsed += delta
+	if a.elapsed > a.duration {
 		a.state = ActionStateSuccess
 	}
 }
@@ -53,11 +90,17 @@ type IdleAction struct {
 
 	// MaxDuration is the maximum duration to idle for.
 	MaxDuration time.Duration
+
+	// Clock measures how long we've idled for. Defaults to the clock of the
+	// AI if it was given one, and otherwise the deltas given to Execute are
+	// counted down.
+	Clock Clock
 }
 
 func (b IdleAction) Build() Action {
 	return &idleAction{
 		minDuration: b.MinDuration,
 		maxDuration: b.MaxDuration,
+		clock:       b.Clock,
 	}
 }
@@ -0,0 +1,175 @@
+package utilsys
+
+import "time"
+
+// InterruptOptions configures how an interruptible thinker re-evaluates its
+// children while one of them is running.
+type InterruptOptions struct {
+	// Interval is how often the children are re-scored while one is running.
+	// Zero re-scores them on every Execute.
+	Interval time.Duration
+
+	// Hysteresis is how much higher the newly selected child's score must be
+	// than the running child's, including the CommitmentBonus, for the
+	// running child to be interrupted.
+	Hysteresis float64
+
+	// CommitmentBonus is added to the running child's score before selecting,
+	// so that probabilistic thinkers also favor continuing what they started
+	CommitmentBonus float64
+}
+
+type interruptibleThinker struct {
+	thinker
+
+	opts            InterruptOptions
+	sinceEvaluation time.Duration
+
+	// switchingTo is the index of the child to switch to once the current
+	// child finishes canceling, or -1 if we aren't switching
+	switchingTo int
+}
+
+func (t *interruptibleThinker) State() ActionState {
+	if t.switchingTo != -1 {
+		return ActionStateExecuting
+	}
+	return t.thinker.State()
+}
+
+func (t *interruptibleThinker) Attached(world, actor interface{}) {
+	t.thinker.Attached(world, actor)
+	t.sinceEvaluation = 0
+}
+
+func (t *interruptibleThinker) Execute(delta time.Duration) {
+	if t.switchingTo != -1 {
+		t.continueSwitching(delta)
+		return
+	}
+
+	t.sinceEvaluation += delta
+	if t.sinceEvaluation >= t.opts.Interval {
+		t.sinceEvaluation = 0
+		if t.reevaluate() {
+			return
+		}
+	}
+
+	t.thinker.Execute(delta)
+}
+
+func (t *interruptibleThinker) Cancel() {
+	// if we were interrupting the current child it is already canceling,
+	// but it should no longer be replaced when it finishes
+	if t.switchingTo != -1 {
+		t.switchingTo = -1
+		return
+	}
+	t.thinker.Cancel()
+}
+
+func (t *interruptibleThinker) Reset() {
+	t.thinker.Reset()
+	t.sinceEvaluation = 0
+}
+
+// reevaluate scores the children and, if another child is sufficiently
+// better than the current child, cancels the current child so we can switch
+// to it. Returns true if the current child was canceled.
+func (t *interruptibleThinker) reevaluate() bool {
+	// the evaluated children have fixed scorers, so scoring them is cheap
+	var evaluated []ScoredAction
+	var children []ChildScore
+	tracing := t.ctx.tracing()
+	if tracing {
+		evaluated, children = t.explainChildren(t.currentIndex, t.opts.CommitmentBonus)
+	} else {
+		evaluated = t.scoreChildren(t.currentIndex, t.opts.CommitmentBonus)
+	}
+
+	selected := t.thinker.thinker.Select(evaluated)
+	interrupt := selected != t.currentIndex &&
+		evaluated[selected].Scorer.Score() > evaluated[t.currentIndex].Scorer.Score()+t.opts.Hysteresis
+	if tracing {
+		t.ctx.decision(&Decision{
+			Reason:      "reevaluate",
+			Children:    children,
+			Selected:    selected,
+			Interrupted: interrupt,
+		})
+	}
+	if !interrupt {
+		return false
+	}
+
+	t.switchingTo = selected
+	t.scoredActions[t.currentIndex].Action.Cancel()
+	t.finishSwitchIfCanceled()
+	return true
+}
+
+// continueSwitching drives the current child through canceling, switching
+// once it's done
+func (t *interruptibleThinker) continueSwitching(delta time.Duration) {
+	if t.scoredActions[t.currentIndex].Action.State() == ActionStateCanceled {
+		t.scoredActions[t.currentIndex].Action.FinishCanceling(delta)
+	}
+	t.finishSwitchIfCanceled()
+}
+
+// finishSwitchIfCanceled switches to the child we're switching to if the
+// current child has finished canceling
+func (t *interruptibleThinker) finishSwitchIfCanceled() {
+	switch t.scoredActions[t.currentIndex].Action.State() {
+	case ActionStateSuccess, ActionStateFailure:
+	default:
+		return
+	}
+
+	t.currentIndex = t.switchingTo
+	t.switchingTo = -1
+	t.sinceEvaluation = 0
+
+	// the new child may have finished the last time it was selected
+	resetIfFinished(t.scoredActions[t.currentIndex].Action)
+}
+
+type interruptibleThinkerBuilder struct {
+	thinkerBuilder
+	opts InterruptOptions
+}
+
+func (b interruptibleThinkerBuilder) Build() Action {
+	return &interruptibleThinker{
+		thinker:     *b.thinkerBuilder.Build().(*thinker),
+		opts:        b.opts,
+		switchingTo: -1,
+	}
+}
+
+// NewInterruptibleThinkerBuilder is like NewThinkerBuilder, except that the
+// children are re-scored while one is running according to the given options.
+// If the Thinker selects a different child whose score beats the running
+// child's by more than the hysteresis, the running child is canceled and, once
+// it finishes canceling, the selected child is run instead.
+func NewInterruptibleThinkerBuilder(thinker Thinker, actions []ScoredActionBuilder, opts InterruptOptions) ActionBuilder {
+	if len(actions) == 0 {
+		panic("error: actions cannot be empty")
+	}
+	return interruptibleThinkerBuilder{
+		thinkerBuilder: thinkerBuilder{
+			thinker: thinker,
+			actions: actions,
+		},
+		opts: opts,
+	}
+}
+
+// NewInterruptibleHighestScoreThinker produces a Thinker like
+// NewHighestScoreThinker, except that the running action is interrupted when
+// another action becomes sufficiently better, as in
+// NewInterruptibleThinkerBuilder.
+func NewInterruptibleHighestScoreThinker(actions []ScoredActionBuilder, opts InterruptOptions) ActionBuilder {
+	return NewInterruptibleThinkerBuilder(highestScoreThinker{}, actions, opts)
+}
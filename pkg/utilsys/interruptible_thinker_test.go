@@ -0,0 +1,167 @@
+package utilsys_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+)
+
+// variableScorer returns whatever score is currently in the pointer
+type variableScorer struct {
+	score *float64
+}
+
+func (s variableScorer) Attached(world, actor interface{}) {}
+func (s variableScorer) Score() float64                    { return *s.score }
+func (s variableScorer) Build() utilsys.Scorer             { return s }
+
+// recordingAction runs forever, recording how often it's executed, and takes
+// cancelTicks calls to FinishCanceling to finish canceling
+type recordingAction struct {
+	state       utilsys.ActionState
+	cancelTicks int
+
+	executions int
+	cancels    int
+}
+
+func (a *recordingAction) State() utilsys.ActionState { return a.state }
+func (a *recordingAction) Attached(world, actor interface{}) {
+	a.state = utilsys.ActionStateRequested
+}
+func (a *recordingAction) Execute(delta time.Duration) {
+	a.executions++
+	a.state = utilsys.ActionStateExecuting
+}
+func (a *recordingAction) Cancel() {
+	a.cancels++
+	a.state = utilsys.ActionStateCanceled
+	if a.cancelTicks == 0 {
+		a.state = utilsys.ActionStateFailure
+	}
+}
+func (a *recordingAction) FinishCanceling(delta time.Duration) {
+	a.cancelTicks--
+	if a.cancelTicks <= 0 {
+		a.state = utilsys.ActionStateFailure
+	}
+}
+func (a *recordingAction) Reset() {
+	a.state = utilsys.ActionStateRequested
+}
+
+// builtAction builds an already constructed action, so tests can inspect it
+type builtAction struct {
+	action utilsys.Action
+}
+
+func (b builtAction) Build() utilsys.Action { return b.action }
+
+func TestInterruptibleThinker(t *testing.T) {
+	walkScore, defendScore := 0.6, 0.1
+	walk := &recordingAction{cancelTicks: 2}
+	defend := &recordingAction{}
+
+	ai := utilsys.NewAI(nil, utilsys.NewInterruptibleHighestScoreThinker(
+		[]utilsys.ScoredActionBuilder{
+			utilsys.ScorerBuilderAndActionBuilder{
+				Action: builtAction{walk},
+				Scorer: variableScorer{&walkScore},
+			},
+			utilsys.ScorerBuilderAndActionBuilder{
+				Action: builtAction{defend},
+				Scorer: variableScorer{&defendScore},
+			},
+		},
+		utilsys.InterruptOptions{
+			Interval:        100 * time.Millisecond,
+			Hysteresis:      0.1,
+			CommitmentBonus: 0.1,
+		},
+	))
+	ai.AddActor("actor")
+
+	tick := func() { ai.Tick(50 * time.Millisecond) }
+
+	tick()
+	tick()
+	if walk.executions != 2 || defend.executions != 0 {
+		t.Fatalf("expected to walk, got walk %d defend %d", walk.executions, defend.executions)
+	}
+
+	// within the commitment bonus and hysteresis: keep walking
+	defendScore = 0.75
+	tick()
+	tick()
+	if walk.cancels != 0 || walk.executions != 4 {
+		t.Fatalf("expected to keep walking, got %d cancels and %d executions", walk.cancels, walk.executions)
+	}
+
+	// far more urgent: interrupt at the next evaluation, then wait for the
+	// walk to finish canceling before defending
+	defendScore = 1
+	tick()
+	if walk.cancels != 0 {
+		t.Fatalf("expected to wait for the interval before re-evaluating")
+	}
+	tick()
+	if walk.cancels != 1 || walk.State() != utilsys.ActionStateCanceled {
+		t.Fatalf("expected the walk to be canceled, got %d cancels in state %v", walk.cancels, walk.State())
+	}
+	tick()
+	tick()
+	if walk.State() != utilsys.ActionStateFailure || defend.executions != 0 {
+		t.Fatalf("expected the walk to finish canceling before defending, got state %v and %d defends", walk.State(), defend.executions)
+	}
+	tick()
+	if defend.executions != 1 || walk.executions != 5 {
+		t.Fatalf("expected to defend, got walk %d defend %d", walk.executions, defend.executions)
+	}
+}
+
+func TestInterruptibleThinker_reselectInterrupted(t *testing.T) {
+	walkScore, defendScore := 0.6, 0.1
+	walk := &recordingAction{}
+	defend := succeeds(1)
+
+	ai := utilsys.NewAI(nil, utilsys.Repeat{Action: utilsys.NewInterruptibleHighestScoreThinker(
+		[]utilsys.ScoredActionBuilder{
+			utilsys.ScorerBuilderAndActionBuilder{
+				Action: builtAction{walk},
+				Scorer: variableScorer{&walkScore},
+			},
+			utilsys.ScorerBuilderAndActionBuilder{
+				Action: builtAction{defend},
+				Scorer: variableScorer{&defendScore},
+			},
+		},
+		utilsys.InterruptOptions{
+			Interval:   100 * time.Millisecond,
+			Hysteresis: 0.1,
+		},
+	)})
+	ai.AddActor("actor")
+
+	tick := func() { ai.Tick(50 * time.Millisecond) }
+
+	// interrupt the walk, which leaves it failed, to defend
+	tick()
+	defendScore = 1
+	tick()
+	if walk.cancels != 1 || walk.State() != utilsys.ActionStateFailure {
+		t.Fatalf("expected the walk to be interrupted, got %d cancels in state %v", walk.cancels, walk.State())
+	}
+
+	// the defend finishes normally and the walk is selected again, which
+	// must start it over rather than report its old failure
+	defendScore = 0.1
+	tick()
+	if defend.executions != 1 || defend.State() != utilsys.ActionStateRequested {
+		t.Fatalf("expected to defend once, got %d executions in state %v", defend.executions, defend.State())
+	}
+	tick()
+	if walk.executions != 2 || walk.State() != utilsys.ActionStateExecuting {
+		t.Fatalf("expected to walk again, got %d executions in state %v", walk.executions, walk.State())
+	}
+}
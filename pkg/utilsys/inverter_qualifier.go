@@ -12,6 +12,15 @@ func (s *inverterQualifier) Score() float64 {
 	return 1 - s.scorer.Score()
 }
 
+func (s *inverterQualifier) Explain() ScoreExplanation {
+	child := ExplainScore(s.scorer)
+	return ScoreExplanation{
+		Kind:     "inverter",
+		Score:    1 - child.Score,
+		Children: []ScoreExplanation{child},
+	}
+}
+
 // InverterQualifier inverts the score of the child, i.e., returns
 // 1 - Scorer.Score()
 type InverterQualifier struct {
@@ -18,6 +18,15 @@ func (s *multCombineQualifier) Score() float64 {
 	return res
 }
 
+func (s *multCombineQualifier) Explain() ScoreExplanation {
+	children := explainChildren(s.children)
+	res := 1.0
+	for _, child := range children {
+		res *= child.Score
+	}
+	return ScoreExplanation{Kind: "mult", Score: res, Children: children}
+}
+
 // MultCombineQualifier produces a score from the children by multiplying
 // their scores together.
 type MultCombineQualifier struct {
@@ -0,0 +1,187 @@
+package utilsys
+
+import "time"
+
+// ParallelPolicy is how many children of a Parallel must finish in a state
+// for the Parallel to finish in that state
+type ParallelPolicy int
+
+const (
+	// ParallelRequireAll requires every child to finish in the state
+	ParallelRequireAll ParallelPolicy = 0
+
+	// ParallelRequireOne requires any one child to finish in the state
+	ParallelRequireOne ParallelPolicy = 1
+)
+
+// met returns true if the policy is met by count of total children
+func (p ParallelPolicy) met(count, total int) bool {
+	if p == ParallelRequireOne {
+		return count > 0
+	}
+	return count == total
+}
+
+type parallelAction struct {
+	children      []Action
+	successPolicy ParallelPolicy
+	failurePolicy ParallelPolicy
+
+	state ActionState
+
+	// result is the state to finish in once the children which were still
+	// running when a policy was met finish canceling, or Init if no policy
+	// has been met
+	result ActionState
+}
+
+func (a *parallelAction) setContext(ctx *actionContext) {
+	for _, child := range a.children {
+		setContext(child, ctx)
+	}
+}
+
+func (a *parallelAction) State() ActionState {
+	return a.state
+}
+
+func (a *parallelAction) Attached(world, actor interface{}) {
+	for _, child := range a.children {
+		child.Attached(world, actor)
+	}
+	a.state = ActionStateRequested
+	a.result = ActionStateInit
+	a.checkPolicies()
+}
+
+func (a *parallelAction) Execute(delta time.Duration) {
+	a.state = ActionStateExecuting
+	if a.result != ActionStateInit {
+		a.finishCanceling(delta)
+		return
+	}
+
+	for _, child := range a.children {
+		switch child.State() {
+		case ActionStateRequested, ActionStateExecuting:
+			child.Execute(delta)
+		}
+	}
+	a.checkPolicies()
+}
+
+func (a *parallelAction) Cancel() {
+	a.cancelRunning()
+	a.state = ActionStateCanceled
+	a.result = ActionStateFailure
+	a.finishIfDone()
+}
+
+func (a *parallelAction) FinishCanceling(delta time.Duration) {
+	a.finishCanceling(delta)
+}
+
+func (a *parallelAction) Reset() {
+	for _, child := range a.children {
+		if isFinished(child.State()) {
+			child.Reset()
+		}
+	}
+	a.state = ActionStateRequested
+	a.result = ActionStateInit
+	a.checkPolicies()
+}
+
+// checkPolicies decides the result if either policy is met, canceling the
+// children which are still running. Failure wins if both are met at once.
+func (a *parallelAction) checkPolicies() {
+	var successes, failures, running int
+	for _, child := range a.children {
+		switch child.State() {
+		case ActionStateSuccess:
+			successes++
+		case ActionStateFailure:
+			failures++
+		default:
+			running++
+		}
+	}
+
+	total := len(a.children)
+	switch {
+	case a.failurePolicy.met(failures, total):
+		a.result = ActionStateFailure
+	case a.successPolicy.met(successes, total):
+		a.result = ActionStateSuccess
+	case running == 0:
+		// neither policy can be met anymore
+		a.result = ActionStateFailure
+	default:
+		return
+	}
+
+	a.cancelRunning()
+	a.finishIfDone()
+}
+
+// cancelRunning cancels the children which are requested or executing
+func (a *parallelAction) cancelRunning() {
+	for _, child := range a.children {
+		switch child.State() {
+		case ActionStateRequested, ActionStateExecuting:
+			child.Cancel()
+		}
+	}
+}
+
+// finishCanceling drives the canceled children towards finishing
+func (a *parallelAction) finishCanceling(delta time.Duration) {
+	for _, child := range a.children {
+		if child.State() == ActionStateCanceled {
+			child.FinishCanceling(delta)
+		}
+	}
+	a.finishIfDone()
+}
+
+// finishIfDone moves to the result once every child has finished
+func (a *parallelAction) finishIfDone() {
+	for _, child := range a.children {
+		if !isFinished(child.State()) {
+			return
+		}
+	}
+	a.state = a.result
+}
+
+// Parallel runs all of its children every tick until its success or failure
+// policy is met. The children which are still running at that point are
+// canceled, and the Parallel finishes once they finish canceling. If both
+// policies are met at once, or all children finish without either policy
+// being met, the Parallel fails. Canceling a Parallel cancels all of its
+// running children and fails once they finish canceling.
+type Parallel struct {
+	// Children are the non-empty list of actions to run at the same time
+	Children []ActionBuilder
+
+	// SuccessPolicy is how many children must succeed for the Parallel to
+	// succeed. Defaults to ParallelRequireAll.
+	SuccessPolicy ParallelPolicy
+
+	// FailurePolicy is how many children must fail for the Parallel to fail.
+	// Defaults to ParallelRequireAll; use ParallelRequireOne to fail as soon
+	// as any child fails.
+	FailurePolicy ParallelPolicy
+}
+
+// Build implements ActionBuilder
+func (b Parallel) Build() Action {
+	if len(b.Children) == 0 {
+		panic("error: children cannot be empty")
+	}
+	return &parallelAction{
+		children:      buildChildren(b.Children),
+		successPolicy: b.SuccessPolicy,
+		failurePolicy: b.FailurePolicy,
+	}
+}
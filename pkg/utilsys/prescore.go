@@ -0,0 +1,121 @@
+package utilsys
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// prescorer is implemented by actions which can score ahead of time what
+// they would otherwise score while executing or resetting. It's called
+// concurrently for different actors, so it must only score and remember
+// the scores, never change what the action is doing.
+type prescorer interface {
+	// prescore scores what the next Reset will if resetting is true, or
+	// otherwise what the next Execute with the given delta will
+	prescore(delta time.Duration, resetting bool)
+}
+
+// prescore scores ahead of time for the given action if it can
+func prescore(action Action, delta time.Duration, resetting bool) {
+	if p, ok := action.(prescorer); ok {
+		p.prescore(delta, resetting)
+	}
+}
+
+// prescoreActors scores ahead of time for every actor across the AI's
+// workers, ahead of the actions being executed for this tick. This starts a
+// new goroutine per worker on every call rather than keeping a pool, which
+// is simpler since nothing needs stopping when the AI is dropped.
+func (ai *AI) prescoreActors(delta time.Duration) {
+	workers := ai.workers
+	if workers > len(ai.actors) {
+		workers = len(ai.actors)
+	}
+
+	var next int64 = -1
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for worker := 0; worker < workers; worker++ {
+		go func() {
+			defer wg.Done()
+			for {
+				idx := int(atomic.AddInt64(&next, 1))
+				if idx >= len(ai.actors) {
+					return
+				}
+
+				action := ai.actors[idx].action
+				switch action.State() {
+				case ActionStateRequested, ActionStateExecuting:
+					prescore(action, delta, false)
+				case ActionStateSuccess, ActionStateFailure:
+					prescore(action, delta, true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// scoreAhead scores the children for use by the next selection this tick,
+// explaining the scores if tracing is enabled
+func (t *thinker) scoreAhead() {
+	if t.ctx == nil {
+		return
+	}
+
+	if t.prescored == nil {
+		t.prescored = make([]ScoreExplanation, len(t.scoredActions))
+	}
+	explain := t.ctx.tracing()
+	for idx, child := range t.scoredActions {
+		if explain {
+			t.prescored[idx] = ExplainScore(child.Scorer)
+		} else {
+			t.prescored[idx] = ScoreExplanation{Score: child.Scorer.Score()}
+		}
+	}
+	t.prescoredAt = t.ctx.ai.ticks
+}
+
+// prescoredScores returns the scores from scoreAhead if they're for this
+// tick, otherwise nil
+func (t *thinker) prescoredScores() []ScoreExplanation {
+	if t.prescored == nil || t.ctx == nil || t.prescoredAt != t.ctx.ai.ticks {
+		return nil
+	}
+	return t.prescored
+}
+
+func (t *thinker) prescore(delta time.Duration, resetting bool) {
+	if resetting {
+		t.scoreAhead()
+		return
+	}
+	prescore(t.scoredActions[t.currentIndex].Action, delta, false)
+}
+
+func (t *interruptibleThinker) prescore(delta time.Duration, resetting bool) {
+	if resetting {
+		// the children selected on reset may be re-evaluated straight away,
+		// which uses the same scores
+		t.scoreAhead()
+		return
+	}
+	if t.switchingTo != -1 {
+		return
+	}
+	if t.sinceEvaluation+delta >= t.opts.Interval {
+		t.scoreAhead()
+	}
+	prescore(t.scoredActions[t.currentIndex].Action, delta, false)
+}
+
+func (a *namedAction) prescore(delta time.Duration, resetting bool) {
+	prescore(a.action, delta, resetting)
+}
+
+func (a *cooldownQualifiedAction) prescore(delta time.Duration, resetting bool) {
+	prescore(a.action, delta, resetting)
+}
@@ -0,0 +1,189 @@
+package utilsys_test
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+	"github.com/jakecoffman/cp"
+)
+
+// roundWorld changes between ticks the way the client state does as packets
+// are received
+type roundWorld struct {
+	round int
+	ore   []cp.Vector
+
+	// executions records each execution of a roundAction
+	executions []string
+}
+
+// roundScorer scores based on the round, the actor, which is an int, and
+// which child it's for, without ties between children
+type roundScorer struct {
+	child int
+	world *roundWorld
+	actor int
+}
+
+func (s *roundScorer) Attached(world, actor interface{}) {
+	s.world = world.(*roundWorld)
+	s.actor = actor.(int)
+}
+
+func (s *roundScorer) Score() float64 {
+	return float64((s.actor*31+s.world.round*17+s.child*7)%97) / 97
+}
+
+type roundScorerBuilder struct {
+	child int
+}
+
+func (b roundScorerBuilder) Build() utilsys.Scorer { return &roundScorer{child: b.child} }
+
+type scriptedActionBuilder struct {
+	ticks int
+}
+
+func (b scriptedActionBuilder) Build() utilsys.Action { return succeeds(b.ticks) }
+
+// roundAction is a scriptedAction which records its executions in the world
+type roundAction struct {
+	*scriptedAction
+	child int
+	world *roundWorld
+	actor int
+}
+
+func (a *roundAction) Attached(world, actor interface{}) {
+	a.scriptedAction.Attached(world, actor)
+	a.world = world.(*roundWorld)
+	a.actor = actor.(int)
+}
+
+func (a *roundAction) Execute(delta time.Duration) {
+	a.scriptedAction.Execute(delta)
+	a.world.executions = append(a.world.executions, fmt.Sprintf("%d: %d %d", a.world.round, a.actor, a.child))
+}
+
+type roundActionBuilder struct {
+	child int
+}
+
+func (b roundActionBuilder) Build() utilsys.Action {
+	return &roundAction{scriptedAction: succeeds(b.child + 1), child: b.child}
+}
+
+func roundThinker() utilsys.ActionBuilder {
+	children := make([]utilsys.ScoredActionBuilder, 3)
+	for idx := range children {
+		children[idx] = utilsys.ScorerBuilderAndActionBuilder{
+			Action: utilsys.NamedAction{Name: fmt.Sprintf("child-%d", idx), Action: roundActionBuilder{child: idx}},
+			Scorer: roundScorerBuilder{child: idx},
+		}
+	}
+	return utilsys.NewInterruptibleHighestScoreThinker(children, utilsys.InterruptOptions{
+		Interval:   100 * time.Millisecond,
+		Hysteresis: 0.1,
+	})
+}
+
+// runRounds runs an AI with the given number of workers over the rounds,
+// tracing if requested, and returns the executions and the trace
+func runRounds(workers int, tracing bool) ([]string, string) {
+	world := &roundWorld{}
+	ai := utilsys.NewAI(world, roundThinker())
+	ai.SetParallelScoring(workers)
+
+	var sb strings.Builder
+	if tracing {
+		ai.SetTracer(utilsys.NewTreeTracer(&sb), nil)
+	}
+	for actor := 0; actor < 50; actor++ {
+		ai.AddActor(actor)
+	}
+	for world.round = 0; world.round < 20; world.round++ {
+		ai.Tick(50 * time.Millisecond)
+	}
+	return world.executions, sb.String()
+}
+
+func TestAI_SetParallelScoring(t *testing.T) {
+	executions, trace := runRounds(0, true)
+	if !strings.Contains(trace, "reevaluate") || !strings.Contains(trace, "(interrupting)") {
+		t.Fatalf("expected the children to be re-evaluated and interrupted, got\n%s", trace)
+	}
+
+	for _, workers := range []int{0, 2, 3, 16} {
+		parallelExecutions, parallelTrace := runRounds(workers, true)
+		if parallelTrace != trace || !reflect.DeepEqual(parallelExecutions, executions) {
+			t.Fatalf("expected the same trace and executions with %d workers as without", workers)
+		}
+
+		parallelExecutions, _ = runRounds(workers, false)
+		if !reflect.DeepEqual(parallelExecutions, executions) {
+			t.Fatalf("expected the same executions with %d workers without tracing", workers)
+		}
+	}
+}
+
+// oreScorer scores by the distance to the nearest ore, which is typical of
+// the scorers which make up most of the time spent ticking
+type oreScorer struct {
+	world *roundWorld
+	pos   cp.Vector
+}
+
+func (s *oreScorer) Attached(world, actor interface{}) {
+	s.world = world.(*roundWorld)
+	s.pos = cp.Vector{X: float64(actor.(int) % 100), Y: float64(actor.(int) / 100)}
+}
+
+func (s *oreScorer) Score() float64 {
+	nearest := math.Inf(1)
+	for _, ore := range s.world.ore {
+		nearest = math.Min(nearest, s.pos.Distance(ore))
+	}
+	return 1 / (1 + nearest)
+}
+
+type oreScorerBuilder struct{}
+
+func (b oreScorerBuilder) Build() utilsys.Scorer { return &oreScorer{} }
+
+func benchmarkTick(b *testing.B, workers int) {
+	world := &roundWorld{ore: make([]cp.Vector, 500)}
+	for idx := range world.ore {
+		world.ore[idx] = cp.Vector{X: float64(idx*37%100) + 0.5, Y: float64(idx*53%100) + 0.5}
+	}
+
+	children := make([]utilsys.ScoredActionBuilder, 4)
+	for idx := range children {
+		children[idx] = utilsys.ScorerBuilderAndActionBuilder{
+			Action: scriptedActionBuilder{ticks: 1},
+			Scorer: oreScorerBuilder{},
+		}
+	}
+	ai := utilsys.NewAI(world, utilsys.NewHighestScoreThinker(children))
+	ai.SetParallelScoring(workers)
+	for actor := 0; actor < 500; actor++ {
+		ai.AddActor(actor)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ai.Tick(50 * time.Millisecond)
+	}
+}
+
+// BenchmarkAI_Tick compares ticking with and without parallel scoring. The
+// worker count is fixed since SetParallelScoring disables itself for fewer
+// than 2 workers; run with -cpu 4 or more to see the speedup.
+func BenchmarkAI_Tick(b *testing.B) {
+	b.Run("sequential", func(b *testing.B) { benchmarkTick(b, 0) })
+	b.Run("parallel", func(b *testing.B) { benchmarkTick(b, 4) })
+}
@@ -0,0 +1,147 @@
+package utilsys
+
+import "time"
+
+// isFinished returns true if the given state is Success or Failure
+func isFinished(state ActionState) bool {
+	return state == ActionStateSuccess || state == ActionStateFailure
+}
+
+// sequentialAction runs its children in order for as long as they finish in
+// the continueOn state, which is Success for sequences and Failure for
+// selectors
+type sequentialAction struct {
+	children   []Action
+	continueOn ActionState
+
+	state ActionState
+	index int
+}
+
+func (a *sequentialAction) setContext(ctx *actionContext) {
+	for _, child := range a.children {
+		setContext(child, ctx)
+	}
+}
+
+func (a *sequentialAction) State() ActionState {
+	return a.state
+}
+
+func (a *sequentialAction) Attached(world, actor interface{}) {
+	for _, child := range a.children {
+		child.Attached(world, actor)
+	}
+	a.index = 0
+	a.state = ActionStateRequested
+	a.advance()
+}
+
+func (a *sequentialAction) Execute(delta time.Duration) {
+	a.state = ActionStateExecuting
+	a.children[a.index].Execute(delta)
+	a.advance()
+}
+
+func (a *sequentialAction) Cancel() {
+	child := a.children[a.index]
+	if !isFinished(child.State()) {
+		child.Cancel()
+	}
+	a.state = ActionStateCanceled
+	a.finishCancelingIfDone()
+}
+
+func (a *sequentialAction) FinishCanceling(delta time.Duration) {
+	child := a.children[a.index]
+	if child.State() == ActionStateCanceled {
+		child.FinishCanceling(delta)
+	}
+	a.finishCancelingIfDone()
+}
+
+func (a *sequentialAction) Reset() {
+	for _, child := range a.children {
+		if isFinished(child.State()) {
+			child.Reset()
+		}
+	}
+	a.index = 0
+	a.state = ActionStateRequested
+	a.advance()
+}
+
+// advance past the children which finished in the continueOn state,
+// finishing once a child finishes otherwise or there are no children left
+func (a *sequentialAction) advance() {
+	for a.index < len(a.children) {
+		childState := a.children[a.index].State()
+		if !isFinished(childState) {
+			return
+		}
+		if childState != a.continueOn {
+			a.state = childState
+			return
+		}
+		if a.index == len(a.children)-1 {
+			a.state = childState
+			return
+		}
+		a.index++
+	}
+}
+
+// finishCancelingIfDone fails once the running child has finished canceling
+func (a *sequentialAction) finishCancelingIfDone() {
+	if isFinished(a.children[a.index].State()) {
+		a.state = ActionStateFailure
+	}
+}
+
+// buildChildren builds each of the given builders
+func buildChildren(builders []ActionBuilder) []Action {
+	res := make([]Action, len(builders))
+	for idx, builder := range builders {
+		res[idx] = builder.Build()
+	}
+	return res
+}
+
+// Sequence runs its children in order, one per tick at most, succeeding
+// once they all succeed and failing as soon as one fails. Canceling a
+// sequence cancels the running child and fails once it finishes canceling.
+type Sequence struct {
+	// Children are the non-empty list of actions to run in order
+	Children []ActionBuilder
+}
+
+// Build implements ActionBuilder
+func (b Sequence) Build() Action {
+	if len(b.Children) == 0 {
+		panic("error: children cannot be empty")
+	}
+	return &sequentialAction{
+		children:   buildChildren(b.Children),
+		continueOn: ActionStateSuccess,
+	}
+}
+
+// Selector, also known as a fallback, runs its children in order until one
+// succeeds, succeeding if any succeeds and failing if they all fail.
+// Canceling a selector cancels the running child and fails once it finishes
+// canceling.
+type Selector struct {
+	// Children are the non-empty list of actions to try in order
+	Children []ActionBuilder
+}
+
+// Build implements ActionBuilder
+func (b Selector) Build() Action {
+	if len(b.Children) == 0 {
+		panic("error: children cannot be empty")
+	}
+	return &sequentialAction{
+		children:   buildChildren(b.Children),
+		continueOn: ActionStateFailure,
+	}
+}
@@ -0,0 +1,112 @@
+package utilsys
+
+import "github.com/calamity-of-subterfuge/cos/pkg/client"
+
+// StateBinder keeps the actors of an AI in sync with a client.State: our own
+// player and the smart objects we control are added as actors when they're
+// loaded and removed when they're lost. Actors are the *client.Player and
+// *client.SmartObject from the state.
+//
+// A game sync loses every object and then loads them all again as new
+// objects, so each actor is replaced by its new object and its action is
+// rebuilt, since the old action refers to the old object.
+type StateBinder struct {
+	ai        *AI
+	player    ActionBuilder
+	unitTypes map[string]ActionBuilder
+
+	// actors by uid
+	actors  map[string]interface{}
+	unbound bool
+}
+
+// BindState binds the given AI to the given state. Our own player uses the
+// action from the player builder, or isn't controlled by the AI if it's nil.
+// Controllable smart objects use the action from the builder for their unit
+// type, and aren't controlled by the AI if their unit type isn't in
+// unitTypes. Objects which are already loaded are added immediately.
+//
+// The binder registers listeners on the state, so it should be bound before
+// packets are handled and never rebound; use Unbind to stop it.
+func BindState(ai *AI, state *client.State, player ActionBuilder, unitTypes map[string]ActionBuilder) *StateBinder {
+	res := &StateBinder{
+		ai:        ai,
+		player:    player,
+		unitTypes: unitTypes,
+		actors:    make(map[string]interface{}),
+	}
+
+	if me, found := state.PlayersByUID[state.MyUID]; found && state.MyUID != "" {
+		res.playerLoaded(me)
+	}
+	for _, so := range state.SmartObjectsByUID {
+		if so.ControllingTeam == state.MyTeam && so.ControllingRole == state.MyRole {
+			res.smartObjectLoaded(so)
+		}
+	}
+
+	state.OnSelfLoaded(res.playerLoaded)
+	state.OnSelfLost(func(plyr *client.Player) { res.lost(plyr.GameObject.UID) })
+	state.OnControllableSmartObjectLoaded(res.smartObjectLoaded)
+	state.OnControllableSmartObjectLost(func(so *client.SmartObject) { res.lost(so.GameObject.UID) })
+	return res
+}
+
+// Actor returns the actor for the object with the given uid, or false if
+// the object isn't controlled by the AI
+func (b *StateBinder) Actor(uid string) (interface{}, bool) {
+	res, ok := b.actors[uid]
+	return res, ok
+}
+
+// Len returns the number of actors added by this binder
+func (b *StateBinder) Len() int {
+	return len(b.actors)
+}
+
+// Unbind removes every actor added by this binder from the AI, canceling
+// their actions, and ignores the state from now on
+//
+// performance: O(n) where n is the number of actors added by this binder
+func (b *StateBinder) Unbind() {
+	for uid := range b.actors {
+		b.lost(uid)
+	}
+	b.unbound = true
+}
+
+func (b *StateBinder) playerLoaded(plyr *client.Player) {
+	if b.player != nil {
+		b.loaded(plyr.GameObject.UID, plyr, b.player)
+	}
+}
+
+func (b *StateBinder) smartObjectLoaded(so *client.SmartObject) {
+	if builder, ok := b.unitTypes[so.UnitType]; ok {
+		b.loaded(so.GameObject.UID, so, builder)
+	}
+}
+
+// loaded adds the given actor, replacing the actor with the same uid if
+// there is one
+func (b *StateBinder) loaded(uid string, actor interface{}, builder ActionBuilder) {
+	if b.unbound {
+		return
+	}
+	if old, found := b.actors[uid]; found {
+		b.ai.RemoveActor(old)
+	}
+	b.actors[uid] = actor
+	b.ai.AddActorWithAction(actor, builder)
+}
+
+// lost removes the actor with the given uid, if there is one
+func (b *StateBinder) lost(uid string) {
+	if b.unbound {
+		return
+	}
+	if actor, found := b.actors[uid]; found {
+		delete(b.actors, uid)
+		b.ai.RemoveActor(actor)
+	}
+}
@@ -0,0 +1,181 @@
+package utilsys_test
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+)
+
+// boundAction executes forever, recording when it's attached and canceled
+type boundAction struct {
+	kind   string
+	events *[]string
+	name   string
+	state  utilsys.ActionState
+}
+
+func (a *boundAction) State() utilsys.ActionState { return a.state }
+
+func (a *boundAction) Attached(world, actor interface{}) {
+	switch v := actor.(type) {
+	case *client.Player:
+		a.name = v.GameObject.UID
+	case *client.SmartObject:
+		a.name = v.GameObject.UID
+	default:
+		a.name = fmt.Sprint(actor)
+	}
+	a.state = utilsys.ActionStateRequested
+	*a.events = append(*a.events, fmt.Sprintf("attach %s %s", a.kind, a.name))
+}
+
+func (a *boundAction) Execute(delta time.Duration) { a.state = utilsys.ActionStateExecuting }
+
+func (a *boundAction) Cancel() {
+	a.state = utilsys.ActionStateCanceled
+	*a.events = append(*a.events, fmt.Sprintf("cancel %s %s", a.kind, a.name))
+}
+
+func (a *boundAction) FinishCanceling(delta time.Duration) { a.state = utilsys.ActionStateFailure }
+func (a *boundAction) Reset()                              { a.state = utilsys.ActionStateRequested }
+
+type boundActionBuilder struct {
+	kind   string
+	events *[]string
+}
+
+func (b boundActionBuilder) Build() utilsys.Action {
+	return &boundAction{kind: b.kind, events: b.events}
+}
+
+func smartObjectSync(uid, unitType string, team int) srvpkts.SmartObjectSync {
+	return srvpkts.SmartObjectSync{
+		GameObjectSync:  srvpkts.GameObjectSync{UID: uid},
+		UnitType:        unitType,
+		ControllingTeam: team,
+		ControllingRole: "economy",
+	}
+}
+
+func gameSync() *srvpkts.GameSyncPacket {
+	return &srvpkts.GameSyncPacket{
+		Player: srvpkts.GameSyncPacketPlayer{UID: "me", Team: 1, Role: "economy"},
+		Players: map[string]srvpkts.PlayerSync{
+			"me":   {GameObjectSync: srvpkts.GameObjectSync{UID: "me"}, Team: 1, Role: "economy"},
+			"them": {GameObjectSync: srvpkts.GameObjectSync{UID: "them"}, Team: 2, Role: "economy"},
+		},
+		SmartObjects: map[string]srvpkts.SmartObjectSync{
+			"worker-1": smartObjectSync("worker-1", "worker", 1),
+			"worker-2": smartObjectSync("worker-2", "worker", 2),
+			"tower-1":  smartObjectSync("tower-1", "tower", 1),
+			"wall-1":   smartObjectSync("wall-1", "wall", 1),
+		},
+	}
+}
+
+// takeEvents returns the recorded events sorted, and clears them
+func takeEvents(events *[]string) []string {
+	res := *events
+	*events = nil
+	sort.Strings(res)
+	return res
+}
+
+func TestAI_RemoveActor(t *testing.T) {
+	var events []string
+	ai := utilsys.NewAI(nil, boundActionBuilder{kind: "core", events: &events})
+	for _, actor := range []string{"a", "b", "c", "d"} {
+		ai.AddActor(actor)
+	}
+	ai.Tick(50 * time.Millisecond)
+	takeEvents(&events)
+
+	ai.RemoveActor("b")
+	ai.RemoveActor("b")
+	ai.RemoveActor("d")
+	if expected := []string{"cancel core b", "cancel core d"}; !reflect.DeepEqual(events, expected) {
+		t.Fatalf("expected %v, got %v", expected, events)
+	}
+	for _, actor := range []string{"a", "c"} {
+		if !ai.HasActor(actor) {
+			t.Fatalf("expected %s to still be handled", actor)
+		}
+	}
+	if ai.HasActor("b") || ai.HasActor("d") {
+		t.Fatal("expected b and d to be removed")
+	}
+
+	ai.AddActor("b")
+	ai.AddActorWithAction("c", boundActionBuilder{kind: "own", events: &events})
+	takeEvents(&events)
+	ai.SetCoreAction(boundActionBuilder{kind: "core2", events: &events})
+	expected := []string{"attach core2 a", "attach core2 b", "cancel core a", "cancel core b"}
+	if got := takeEvents(&events); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected only actors using the core action to be rebuilt, got %v", got)
+	}
+}
+
+func TestBindState(t *testing.T) {
+	var events []string
+	ai := utilsys.NewAI(nil, boundActionBuilder{kind: "core", events: &events})
+	state := client.NewState()
+	state.HandleMessage(gameSync())
+
+	binder := utilsys.BindState(ai, state, boundActionBuilder{kind: "player", events: &events}, map[string]utilsys.ActionBuilder{
+		"worker": boundActionBuilder{kind: "worker", events: &events},
+		"tower":  boundActionBuilder{kind: "tower", events: &events},
+	})
+	expected := []string{"attach player me", "attach tower tower-1", "attach worker worker-1"}
+	if got := takeEvents(&events); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected the loaded objects to be bound as %v, got %v", expected, got)
+	}
+	if binder.Len() != 3 {
+		t.Fatalf("expected 3 actors, got %d", binder.Len())
+	}
+	ai.Tick(50 * time.Millisecond)
+
+	state.HandleMessage(&srvpkts.GameObjectRemovedPacket{UID: "worker-1"})
+	if got := takeEvents(&events); !reflect.DeepEqual(got, []string{"cancel worker worker-1"}) {
+		t.Fatalf("expected the lost worker to be canceled, got %v", got)
+	}
+	if _, found := binder.Actor("worker-1"); found {
+		t.Fatal("expected the lost worker to be unbound")
+	}
+
+	state.HandleMessage(&srvpkts.SmartObjectAddedPacket{Object: smartObjectSync("worker-3", "worker", 1)})
+	if got := takeEvents(&events); !reflect.DeepEqual(got, []string{"attach worker worker-3"}) {
+		t.Fatalf("expected the new worker to be bound, got %v", got)
+	}
+	ai.Tick(50 * time.Millisecond)
+
+	// a game sync loses everything and then loads everything again as new
+	// objects, so every action is canceled and rebuilt for the new objects
+	oldMe, _ := binder.Actor("me")
+	state.HandleMessage(gameSync())
+	expected = []string{
+		"attach player me", "attach tower tower-1", "attach worker worker-1",
+		"cancel player me", "cancel tower tower-1", "cancel worker worker-3",
+	}
+	if got := takeEvents(&events); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v after the game sync, got %v", expected, got)
+	}
+	newMe, _ := binder.Actor("me")
+	if newMe != state.PlayersByUID["me"] || ai.HasActor(oldMe) || !ai.HasActor(newMe) {
+		t.Fatal("expected the old player to be replaced by the new player")
+	}
+	if binder.Len() != 3 {
+		t.Fatalf("expected 3 actors after the game sync, got %d", binder.Len())
+	}
+
+	binder.Unbind()
+	state.HandleMessage(&srvpkts.SmartObjectAddedPacket{Object: smartObjectSync("worker-4", "worker", 1)})
+	if got := takeEvents(&events); len(got) != 3 || binder.Len() != 0 || ai.HasActor(newMe) {
+		t.Fatalf("expected unbinding to remove every actor and ignore new objects, got %v", got)
+	}
+}
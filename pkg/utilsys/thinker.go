@@ -1,6 +1,9 @@
 package utilsys
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // Thinker describes the standard Thinker interface which can be wrapped
 // with NewThinkerBuilder to produce an ActionBuilder.
@@ -21,6 +24,116 @@ type thinker struct {
 	thinker       Thinker
 	scoredActions []ScoredAction
 	currentIndex  int
+	ctx           *actionContext
+
+	// prescored are the scores of the children from scoreAhead, which are
+	// used instead of scoring the children again during the tick they were
+	// scored on
+	prescored   []ScoreExplanation
+	prescoredAt uint64
+
+	// fixedScores and fixedChildren are reused by scoreChildren so that
+	// selecting from fixed scores doesn't allocate
+	fixedScores   []fixedScorer
+	fixedChildren []ScoredAction
+}
+
+func (t *thinker) setContext(ctx *actionContext) {
+	t.ctx = ctx
+	for _, scoredAction := range t.scoredActions {
+		setContext(scoredAction.Action, ctx)
+	}
+}
+
+// selectChild selects the child to run, tracing the decision if tracing is
+// enabled
+func (t *thinker) selectChild() int {
+	tracing := t.ctx.tracing()
+	if !tracing {
+		if t.prescoredScores() == nil {
+			return t.thinker.Select(t.scoredActions)
+		}
+		return t.thinker.Select(t.scoreChildren(-1, 0))
+	}
+
+	evaluated, scores := t.explainChildren(-1, 0)
+	selected := t.thinker.Select(evaluated)
+	if tracing {
+		t.ctx.decision(&Decision{Reason: "select", Children: scores, Selected: selected})
+	}
+	return selected
+}
+
+// explainChildren scores and explains each child for tracing, adding the
+// bonus to the child at bonusIndex. The returned scored actions have fixed scorers with
+// the explained scores, so that the thinker selects based on exactly the
+// scores which were explained. The children are only scored if they weren't
+// already scored ahead of time for this tick.
+func (t *thinker) explainChildren(bonusIndex int, bonus float64) ([]ScoredAction, []ChildScore) {
+	prescored := t.prescoredScores()
+	evaluated := make([]ScoredAction, len(t.scoredActions))
+	scores := make([]ChildScore, len(t.scoredActions))
+	for idx, child := range t.scoredActions {
+		var explanation ScoreExplanation
+		if prescored != nil {
+			explanation = prescored[idx]
+		} else {
+			explanation = ExplainScore(child.Scorer)
+		}
+		score := ChildScore{
+			Name:        t.childName(idx),
+			Score:       explanation.Score,
+			Explanation: explanation,
+		}
+		if idx == bonusIndex {
+			score.Bonus = bonus
+			score.Score += bonus
+		}
+		scores[idx] = score
+		evaluated[idx] = ScoredAction{Action: child.Action, Scorer: &fixedScorer{score: score.Score}}
+	}
+	return evaluated, scores
+}
+
+// scoreChildren scores each child, adding the bonus to the child at
+// bonusIndex, without explaining the scores. The returned scored actions
+// have fixed scorers with the scores, which are only valid until the next
+// call. The children are only scored if they weren't already scored ahead
+// of time for this tick.
+func (t *thinker) scoreChildren(bonusIndex int, bonus float64) []ScoredAction {
+	if t.fixedChildren == nil {
+		t.fixedScores = make([]fixedScorer, len(t.scoredActions))
+		t.fixedChildren = make([]ScoredAction, len(t.scoredActions))
+		for idx, child := range t.scoredActions {
+			t.fixedChildren[idx] = ScoredAction{Action: child.Action, Scorer: &t.fixedScores[idx]}
+		}
+	}
+
+	prescored := t.prescoredScores()
+	for idx, child := range t.scoredActions {
+		var score float64
+		if prescored != nil {
+			score = prescored[idx].Score
+		} else {
+			score = child.Scorer.Score()
+		}
+		if idx == bonusIndex {
+			score += bonus
+		}
+		t.fixedScores[idx].score = score
+	}
+	return t.fixedChildren
+}
+
+// childName returns the name of the child at the given index in traces
+func (t *thinker) childName(idx int) string {
+	if name := nodeName(t.scoredActions[idx].Action); name != "" {
+		return name
+	}
+	if name := nodeName(t.scoredActions[idx].Scorer); name != "" {
+		return name
+	}
+	return fmt.Sprintf("#%d", idx)
 }
 
 func (t *thinker) State() ActionState {
@@ -37,7 +150,7 @@ func (t *thinker) Attached(world, actor interface{}) {
 		scoredAction.Scorer.Attached(world, actor)
 	}
 
-	t.currentIndex = t.thinker.Select(t.scoredActions)
+	t.currentIndex = t.selectChild()
 }
 
 func (t *thinker) Execute(delta time.Duration) {
@@ -54,7 +167,19 @@ func (t *thinker) FinishCanceling(delta time.Duration) {
 
 func (t *thinker) Reset() {
 	t.scoredActions[t.currentIndex].Action.Reset()
-	t.currentIndex = t.thinker.Select(t.scoredActions)
+	t.currentIndex = t.selectChild()
+
+	// the new child may have been interrupted the last time it was selected,
+	// which leaves it finished
+	resetIfFinished(t.scoredActions[t.currentIndex].Action)
+}
+
+// resetIfFinished resets the given action if it's finished, so that it can
+// run again
+func resetIfFinished(action Action) {
+	if isFinished(action.State()) {
+		action.Reset()
+	}
 }
 
 type thinkerBuilder struct {
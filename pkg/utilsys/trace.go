@@ -0,0 +1,250 @@
+package utilsys
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// RootNode is the name of the node for the core action of each actor in
+// traces
+const RootNode = "root"
+
+// Tracer receives a record of the decisions an AI makes, for working out
+// why an actor did what it did. Tracers are called synchronously from the
+// AI, so they should be fast.
+type Tracer interface {
+	// Decision is called whenever a thinker selects a child
+	Decision(decision *Decision)
+
+	// Transition is called whenever the state of a traced action changes.
+	// The core action of each actor is traced, as are NamedActions.
+	Transition(transition *Transition)
+}
+
+// ChildScore is the score of a single child of a thinker during a decision
+type ChildScore struct {
+	// Name of the child, from a NamedAction or NamedScorer, or its index
+	// prefixed with # if it's unnamed
+	Name string `json:"name"`
+
+	// Score the thinker used for the child, including Bonus
+	Score float64 `json:"score"`
+
+	// Bonus is the commitment bonus given to the running child when an
+	// interruptible thinker re-evaluates
+	Bonus float64 `json:"bonus,omitempty"`
+
+	// Explanation breaks the score down through the qualifier tree
+	Explanation ScoreExplanation `json:"explanation"`
+}
+
+// Decision records a thinker selecting one of its children
+type Decision struct {
+	// Tick is the number of times the AI has ticked
+	Tick uint64 `json:"tick"`
+
+	// Actor the decision was made for
+	Actor interface{} `json:"-"`
+
+	// ActorName is the name of the actor, as from the AI's actor namer
+	ActorName string `json:"actor"`
+
+	// Thinker is the path to the thinker, i.e., RootNode followed by the
+	// names of the NamedActions it's within, separated by slashes
+	Thinker string `json:"thinker"`
+
+	// Reason is "select" when the thinker is choosing what to do next and
+	// "reevaluate" when an interruptible thinker is checking whether to
+	// interrupt its running child
+	Reason string `json:"reason"`
+
+	// Children are the scores of each child, in order
+	Children []ChildScore `json:"children"`
+
+	// Selected is the index of the selected child
+	Selected int `json:"selected"`
+
+	// Interrupted is true if the running child is being canceled so that
+	// the selected child can run
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+// Transition records the state of an action changing
+type Transition struct {
+	// Tick is the number of times the AI has ticked
+	Tick uint64 `json:"tick"`
+
+	// Actor the action is for
+	Actor interface{} `json:"-"`
+
+	// ActorName is the name of the actor, as from the AI's actor namer
+	ActorName string `json:"actor"`
+
+	// Node is the path to the action, as in Decision.Thinker
+	Node string `json:"node"`
+
+	// From is the state before the transition
+	From ActionState `json:"from"`
+
+	// To is the state after the transition
+	To ActionState `json:"to"`
+}
+
+// DefaultActorName names actors in traces when the AI wasn't given a way to
+// name them. It uses String if the actor has it, and otherwise the type and
+// address for pointers or the value for everything else.
+func DefaultActorName(actor interface{}) string {
+	if stringer, ok := actor.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	if actor != nil && reflect.TypeOf(actor).Kind() == reflect.Ptr {
+		return fmt.Sprintf("%T@%p", actor, actor)
+	}
+	return fmt.Sprintf("%v", actor)
+}
+
+// tracing returns true if there's a tracer to record to
+func (t *actionContext) tracing() bool {
+	return t != nil && t.ai.tracer != nil
+}
+
+// decision fills in the common fields of the decision and records it
+func (t *actionContext) decision(decision *Decision) {
+	decision.Tick = t.ai.ticks
+	decision.Actor = t.actor
+	decision.ActorName = t.name
+	decision.Thinker = t.path
+	t.ai.tracer.Decision(decision)
+}
+
+// transition records a change of state if there was one
+func (t *actionContext) transition(from, to ActionState) {
+	if from == to || !t.tracing() {
+		return
+	}
+	t.ai.tracer.Transition(&Transition{
+		Tick:      t.ai.ticks,
+		Actor:     t.actor,
+		ActorName: t.name,
+		Node:      t.path,
+		From:      from,
+		To:        to,
+	})
+}
+
+// namedNode is implemented by actions and scorers which have a name in traces
+type namedNode interface {
+	nodeName() string
+}
+
+// nodeName returns the name of the given action or scorer, or a blank
+// string if it's unnamed
+func nodeName(node interface{}) string {
+	if named, ok := node.(namedNode); ok {
+		return named.nodeName()
+	}
+	return ""
+}
+
+type namedAction struct {
+	name   string
+	action Action
+	ctx    *actionContext
+}
+
+func (a *namedAction) nodeName() string {
+	return a.name
+}
+
+func (a *namedAction) setContext(ctx *actionContext) {
+	a.ctx = ctx.child(a.name)
+	setContext(a.action, a.ctx)
+}
+
+func (a *namedAction) State() ActionState {
+	return a.action.State()
+}
+
+func (a *namedAction) Attached(world, actor interface{}) {
+	a.action.Attached(world, actor)
+	a.ctx.transition(ActionStateInit, a.action.State())
+}
+
+func (a *namedAction) Execute(delta time.Duration) {
+	before := a.action.State()
+	a.action.Execute(delta)
+	a.ctx.transition(before, a.action.State())
+}
+
+func (a *namedAction) Cancel() {
+	before := a.action.State()
+	a.action.Cancel()
+	a.ctx.transition(before, a.action.State())
+}
+
+func (a *namedAction) FinishCanceling(delta time.Duration) {
+	before := a.action.State()
+	a.action.FinishCanceling(delta)
+	a.ctx.transition(before, a.action.State())
+}
+
+func (a *namedAction) Reset() {
+	before := a.action.State()
+	a.action.Reset()
+	a.ctx.transition(before, a.action.State())
+}
+
+// NamedAction gives an action a name in traces. Its state transitions are
+// traced, thinkers use the name for it in decisions, and thinkers within it
+// are traced under the name.
+type NamedAction struct {
+	// Name of the action, which shouldn't contain slashes
+	Name string
+
+	// Action being named
+	Action ActionBuilder
+}
+
+// Build implements ActionBuilder
+func (b NamedAction) Build() Action {
+	return &namedAction{name: b.Name, action: b.Action.Build()}
+}
+
+type namedScorer struct {
+	name   string
+	scorer Scorer
+}
+
+func (s *namedScorer) nodeName() string {
+	return s.name
+}
+
+func (s *namedScorer) Attached(world, actor interface{}) {
+	s.scorer.Attached(world, actor)
+}
+
+func (s *namedScorer) Score() float64 {
+	return s.scorer.Score()
+}
+
+func (s *namedScorer) Explain() ScoreExplanation {
+	res := ExplainScore(s.scorer)
+	res.Name = s.name
+	return res
+}
+
+// NamedScorer gives a scorer a name in explanations. If a thinker's child
+// action is unnamed, the name of its scorer is used for it in decisions.
+type NamedScorer struct {
+	// Name of the scorer
+	Name string
+
+	// Scorer being named
+	Scorer ScorerBuilder
+}
+
+// Build implements ScorerBuilder
+func (b NamedScorer) Build() Scorer {
+	return &namedScorer{name: b.Name, scorer: b.Scorer.Build()}
+}
@@ -0,0 +1,173 @@
+package utilsys
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// JSONLinesTracer is a Tracer which writes each decision and transition to
+// the writer as a single line of JSON. Each line has a "type" of either
+// "decision" or "transition" alongside the fields of the record. It's safe
+// to share between AIs.
+type JSONLinesTracer struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+// NewJSONLinesTracer produces a tracer which writes JSON lines to the
+// given writer
+func NewJSONLinesTracer(w io.Writer) *JSONLinesTracer {
+	return &JSONLinesTracer{encoder: json.NewEncoder(w)}
+}
+
+type jsonDecision struct {
+	Type string `json:"type"`
+	*Decision
+}
+
+type jsonTransition struct {
+	Type string `json:"type"`
+	*Transition
+}
+
+// Decision implements Tracer
+func (t *JSONLinesTracer) Decision(decision *Decision) {
+	t.write(jsonDecision{Type: "decision", Decision: decision})
+}
+
+// Transition implements Tracer
+func (t *JSONLinesTracer) Transition(transition *Transition) {
+	t.write(jsonTransition{Type: "transition", Transition: transition})
+}
+
+// Err returns the first error encountered while writing, after which
+// nothing more is written
+func (t *JSONLinesTracer) Err() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.err
+}
+
+func (t *JSONLinesTracer) write(v interface{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.err != nil {
+		return
+	}
+	t.err = t.encoder.Encode(v)
+}
+
+// TreeTracer is a Tracer which writes each decision as a human-readable tree
+// from FormatDecision and each transition as a single line. It's safe to
+// share between AIs.
+type TreeTracer struct {
+	mutex sync.Mutex
+	w     io.Writer
+	err   error
+}
+
+// NewTreeTracer produces a tracer which writes trees to the given writer
+func NewTreeTracer(w io.Writer) *TreeTracer {
+	return &TreeTracer{w: w}
+}
+
+// Decision implements Tracer
+func (t *TreeTracer) Decision(decision *Decision) {
+	t.write(FormatDecision(decision))
+}
+
+// Transition implements Tracer
+func (t *TreeTracer) Transition(transition *Transition) {
+	t.write(FormatTransition(transition))
+}
+
+// Err returns the first error encountered while writing, after which
+// nothing more is written
+func (t *TreeTracer) Err() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.err
+}
+
+func (t *TreeTracer) write(s string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.err != nil {
+		return
+	}
+	_, t.err = io.WriteString(t.w, s)
+}
+
+// FormatTransition formats the transition as a single line, e.g.,
+//
+//	[tick 3] villager-1 root/gather: executing -> success
+func FormatTransition(transition *Transition) string {
+	return fmt.Sprintf(
+		"[tick %d] %s %s: %s -> %s\n",
+		transition.Tick, transition.ActorName, transition.Node,
+		transition.From, transition.To,
+	)
+}
+
+// FormatDecision formats the decision as a tree, with a line for each child
+// followed by the breakdown of its score, e.g.,
+//
+//	[tick 3] villager-1 root select -> gather
+//	    #0 idle 0.100
+//	        fixed 0.100
+//	  * #1 gather 0.720
+//	        mult 0.720
+//	            curve utilsys.LinearCurve{Slope:0.8 Intercept:0} 0.800
+//	                fixed 1.000
+//	            fixed 0.900
+func FormatDecision(decision *Decision) string {
+	var sb strings.Builder
+	selected := "?"
+	if decision.Selected >= 0 && decision.Selected < len(decision.Children) {
+		selected = decision.Children[decision.Selected].Name
+	}
+	fmt.Fprintf(&sb, "[tick %d] %s %s %s -> %s", decision.Tick, decision.ActorName, decision.Thinker, decision.Reason, selected)
+	if decision.Interrupted {
+		sb.WriteString(" (interrupting)")
+	}
+	sb.WriteString("\n")
+
+	for idx, child := range decision.Children {
+		marker := "   "
+		if idx == decision.Selected {
+			marker = "  *"
+		}
+		fmt.Fprintf(&sb, "%s #%d", marker, idx)
+		if child.Name != fmt.Sprintf("#%d", idx) {
+			fmt.Fprintf(&sb, " %s", child.Name)
+		}
+		fmt.Fprintf(&sb, " %.3f", child.Score)
+		if child.Bonus != 0 {
+			fmt.Fprintf(&sb, " (bonus %+.3f)", child.Bonus)
+		}
+		sb.WriteString("\n")
+		writeExplanation(&sb, &child.Explanation, 2)
+	}
+	return sb.String()
+}
+
+// writeExplanation writes the explanation and its children at the given
+// depth, indenting four spaces per level
+func writeExplanation(sb *strings.Builder, explanation *ScoreExplanation, depth int) {
+	sb.WriteString(strings.Repeat("    ", depth))
+	if explanation.Name != "" {
+		fmt.Fprintf(sb, "%s: ", explanation.Name)
+	}
+	sb.WriteString(explanation.Kind)
+	if explanation.Detail != "" {
+		fmt.Fprintf(sb, " %s", explanation.Detail)
+	}
+	fmt.Fprintf(sb, " %.3f\n", explanation.Score)
+	for idx := range explanation.Children {
+		writeExplanation(sb, &explanation.Children[idx], depth+1)
+	}
+}
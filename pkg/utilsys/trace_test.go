@@ -0,0 +1,204 @@
+package utilsys_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+)
+
+type recordingTracer struct {
+	decisions   []*utilsys.Decision
+	transitions []*utilsys.Transition
+}
+
+func (t *recordingTracer) Decision(decision *utilsys.Decision) {
+	t.decisions = append(t.decisions, decision)
+}
+
+func (t *recordingTracer) Transition(transition *utilsys.Transition) {
+	t.transitions = append(t.transitions, transition)
+}
+
+func (t *recordingTracer) transitionsOf(node string) []string {
+	var res []string
+	for _, transition := range t.transitions {
+		if transition.Node == node {
+			res = append(res, transition.From.String()+"->"+transition.To.String())
+		}
+	}
+	return res
+}
+
+func tracedChildren() []utilsys.ScoredActionBuilder {
+	return []utilsys.ScoredActionBuilder{
+		utilsys.ScorerBuilderAndActionBuilder{
+			Action: utilsys.NamedAction{Name: "idle", Action: builtAction{succeeds(1)}},
+			Scorer: utilsys.FixedScorer{Score: 0.1},
+		},
+		utilsys.ScorerBuilderAndActionBuilder{
+			Action: builtAction{succeeds(2)},
+			Scorer: utilsys.NamedScorer{
+				Name:   "gather",
+				Scorer: utilsys.MultCombineQualifier{Children: fixed(0.8, 0.9)},
+			},
+		},
+	}
+}
+
+func TestAI_tracing(t *testing.T) {
+	tracer := &recordingTracer{}
+	ai := utilsys.NewAI(nil, utilsys.NewHighestScoreThinker(tracedChildren()))
+	ai.SetTracer(tracer, nil)
+	ai.AddActor("villager")
+
+	if len(tracer.decisions) != 1 {
+		t.Fatalf("expected 1 decision after adding the actor, got %d", len(tracer.decisions))
+	}
+	decision := tracer.decisions[0]
+	if decision.ActorName != "villager" || decision.Thinker != utilsys.RootNode || decision.Reason != "select" {
+		t.Fatalf("unexpected decision %+v", decision)
+	}
+	if decision.Selected != 1 || decision.Children[0].Name != "idle" || decision.Children[1].Name != "gather" {
+		t.Fatalf("unexpected children %+v selected %d", decision.Children, decision.Selected)
+	}
+	explanation := decision.Children[1].Explanation
+	if explanation.Kind != "mult" || explanation.Name != "gather" || len(explanation.Children) != 2 {
+		t.Fatalf("unexpected explanation %+v", explanation)
+	}
+	if math.Abs(explanation.Score-0.72) > scoreEpsilon || math.Abs(decision.Children[1].Score-0.72) > scoreEpsilon {
+		t.Fatalf("expected gather to score 0.72, got %+v", decision.Children[1])
+	}
+
+	for i := 0; i < 3; i++ {
+		ai.Tick(50 * time.Millisecond)
+	}
+
+	if len(tracer.decisions) != 2 || tracer.decisions[1].Tick != 3 {
+		t.Fatalf("expected a second decision on tick 3, got %d decisions", len(tracer.decisions))
+	}
+	expected := []string{"init->requested", "requested->executing", "executing->success", "success->executing"}
+	if actual := tracer.transitionsOf(utilsys.RootNode); strings.Join(actual, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected root transitions %v, got %v", expected, actual)
+	}
+	if actual := tracer.transitionsOf("root/idle"); len(actual) != 1 || actual[0] != "init->requested" {
+		t.Fatalf("expected idle to be attached, got %v", actual)
+	}
+
+	ai.SetTracer(nil, nil)
+	recorded := len(tracer.decisions) + len(tracer.transitions)
+	for i := 0; i < 4; i++ {
+		ai.Tick(50 * time.Millisecond)
+	}
+	if len(tracer.decisions)+len(tracer.transitions) != recorded {
+		t.Fatal("expected nothing to be recorded once tracing is disabled")
+	}
+}
+
+func TestThinkers_tracing(t *testing.T) {
+	thinkers := map[string]utilsys.ActionBuilder{
+		"highest":    utilsys.NewHighestScoreThinker(tracedChildren()),
+		"first":      utilsys.NewFirstToScoreThinker(0.5, tracedChildren()),
+		"linear":     utilsys.NewLinearProbabilisticThinker(0.5, tracedChildren()),
+		"softmax":    utilsys.NewSoftMaxProbabilisticThinker(0.5, 1, tracedChildren()),
+		"nested":     utilsys.NamedAction{Name: "work", Action: utilsys.NewHighestScoreThinker(tracedChildren())},
+		"interrupts": utilsys.NewInterruptibleHighestScoreThinker(tracedChildren(), utilsys.InterruptOptions{CommitmentBonus: 0.1}),
+	}
+
+	for name, builder := range thinkers {
+		tracer := &recordingTracer{}
+		ai := utilsys.NewAI(nil, builder)
+		ai.SetTracer(tracer, nil)
+		ai.AddActor("actor")
+		ai.Tick(50 * time.Millisecond)
+
+		if len(tracer.decisions) == 0 {
+			t.Fatalf("%s: expected a decision", name)
+		}
+		for _, decision := range tracer.decisions {
+			if decision.Selected != 1 || decision.Children[1].Explanation.Kind != "mult" {
+				t.Fatalf("%s: unexpected decision %+v", name, decision)
+			}
+		}
+		if name == "nested" && tracer.decisions[0].Thinker != "root/work" {
+			t.Fatalf("nested: expected thinker root/work, got %s", tracer.decisions[0].Thinker)
+		}
+		if name == "interrupts" {
+			last := tracer.decisions[len(tracer.decisions)-1]
+			if last.Reason != "reevaluate" || last.Children[1].Bonus != 0.1 || last.Interrupted {
+				t.Fatalf("interrupts: unexpected reevaluation %+v", last)
+			}
+		}
+	}
+}
+
+func TestJSONLinesTracer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := utilsys.NewJSONLinesTracer(&buf)
+	ai := utilsys.NewAI(nil, utilsys.NewHighestScoreThinker(tracedChildren()))
+	ai.SetTracer(tracer, nil)
+	ai.AddActor("villager")
+	ai.Tick(50 * time.Millisecond)
+	if err := tracer.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line struct {
+			Type     string `json:"type"`
+			Actor    string `json:"actor"`
+			From     string `json:"from"`
+			Selected int    `json:"selected"`
+			Children []struct {
+				Name        string                   `json:"name"`
+				Explanation utilsys.ScoreExplanation `json:"explanation"`
+			} `json:"children"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		if line.Actor != "villager" {
+			t.Fatalf("expected actor villager in %q", scanner.Text())
+		}
+		if line.Type == "decision" && (line.Children[1].Name != "gather" || len(line.Children[1].Explanation.Children) != 2) {
+			t.Fatalf("unexpected decision %q", scanner.Text())
+		}
+		types = append(types, line.Type+":"+line.From)
+	}
+
+	expected := "transition:init decision: transition:init transition:requested"
+	if strings.Join(types, " ") != expected {
+		t.Fatalf("expected %s, got %s", expected, strings.Join(types, " "))
+	}
+}
+
+func TestFormatDecision(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := utilsys.NewTreeTracer(&buf)
+	ai := utilsys.NewAI(nil, utilsys.NewHighestScoreThinker(tracedChildren()))
+	ai.SetTracer(tracer, func(actor interface{}) string { return "actor-" + actor.(string) })
+	ai.AddActor("1")
+
+	expected := strings.Join([]string{
+		"[tick 0] actor-1 root/idle: init -> requested",
+		"[tick 0] actor-1 root select -> gather",
+		"    #0 idle 0.100",
+		"        fixed 0.100",
+		"  * #1 gather 0.720",
+		"        gather: mult 0.720",
+		"            fixed 0.800",
+		"            fixed 0.900",
+		"[tick 0] actor-1 root: init -> requested",
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
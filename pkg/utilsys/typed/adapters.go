@@ -0,0 +1,192 @@
+package typed
+
+import (
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+)
+
+// untypedAction adapts a typed action to utilsys.Action
+type untypedAction[W, A any] struct {
+	action Action[W, A]
+}
+
+func (a untypedAction[W, A]) State() utilsys.ActionState {
+	return a.action.State()
+}
+
+func (a untypedAction[W, A]) Attached(world, actor interface{}) {
+	a.action.Attached(cast[W](world, "world"), cast[A](actor, "actor"))
+}
+
+func (a untypedAction[W, A]) Execute(delta time.Duration) {
+	a.action.Execute(delta)
+}
+
+func (a untypedAction[W, A]) Cancel() {
+	a.action.Cancel()
+}
+
+func (a untypedAction[W, A]) FinishCanceling(delta time.Duration) {
+	a.action.FinishCanceling(delta)
+}
+
+func (a untypedAction[W, A]) Reset() {
+	a.action.Reset()
+}
+
+// typedAction adapts a utilsys.Action to a typed action
+type typedAction[W, A any] struct {
+	action utilsys.Action
+}
+
+func (a typedAction[W, A]) State() utilsys.ActionState {
+	return a.action.State()
+}
+
+func (a typedAction[W, A]) Attached(world W, actor A) {
+	a.action.Attached(world, actor)
+}
+
+func (a typedAction[W, A]) Execute(delta time.Duration) {
+	a.action.Execute(delta)
+}
+
+func (a typedAction[W, A]) Cancel() {
+	a.action.Cancel()
+}
+
+func (a typedAction[W, A]) FinishCanceling(delta time.Duration) {
+	a.action.FinishCanceling(delta)
+}
+
+func (a typedAction[W, A]) Reset() {
+	a.action.Reset()
+}
+
+// ToAction adapts a typed action to utilsys.Action. Attached panics if it's
+// given a world or actor of the wrong type.
+func ToAction[W, A any](action Action[W, A]) utilsys.Action {
+	if typed, ok := action.(typedAction[W, A]); ok {
+		return typed.action
+	}
+	return untypedAction[W, A]{action: action}
+}
+
+// FromAction adapts a utilsys.Action to a typed action
+func FromAction[W, A any](action utilsys.Action) Action[W, A] {
+	if untyped, ok := action.(untypedAction[W, A]); ok {
+		return untyped.action
+	}
+	return typedAction[W, A]{action: action}
+}
+
+type untypedActionBuilder[W, A any] struct {
+	builder ActionBuilder[W, A]
+}
+
+func (b untypedActionBuilder[W, A]) Build() utilsys.Action {
+	return ToAction(b.builder.Build())
+}
+
+type typedActionBuilder[W, A any] struct {
+	builder utilsys.ActionBuilder
+}
+
+func (b typedActionBuilder[W, A]) Build() Action[W, A] {
+	return FromAction[W, A](b.builder.Build())
+}
+
+// ToActionBuilder adapts a typed action builder to utilsys.ActionBuilder, e.g.,
+// to use it within a utilsys.Sequence
+func ToActionBuilder[W, A any](builder ActionBuilder[W, A]) utilsys.ActionBuilder {
+	if typed, ok := builder.(typedActionBuilder[W, A]); ok {
+		return typed.builder
+	}
+	return untypedActionBuilder[W, A]{builder: builder}
+}
+
+// FromActionBuilder adapts a utilsys.ActionBuilder to a typed action builder,
+// e.g., to use a thinker from utilsys as the core action of an AI. The
+// untyped actions must accept worlds of type W and actors of type A.
+func FromActionBuilder[W, A any](builder utilsys.ActionBuilder) ActionBuilder[W, A] {
+	if untyped, ok := builder.(untypedActionBuilder[W, A]); ok {
+		return untyped.builder
+	}
+	return typedActionBuilder[W, A]{builder: builder}
+}
+
+type untypedScorer[W, A any] struct {
+	scorer Scorer[W, A]
+}
+
+func (s untypedScorer[W, A]) Attached(world, actor interface{}) {
+	s.scorer.Attached(cast[W](world, "world"), cast[A](actor, "actor"))
+}
+
+func (s untypedScorer[W, A]) Score() float64 {
+	return s.scorer.Score()
+}
+
+type typedScorer[W, A any] struct {
+	scorer utilsys.Scorer
+}
+
+func (s typedScorer[W, A]) Attached(world W, actor A) {
+	s.scorer.Attached(world, actor)
+}
+
+func (s typedScorer[W, A]) Score() float64 {
+	return s.scorer.Score()
+}
+
+// ToScorer adapts a typed scorer to utilsys.Scorer. Attached panics if it's
+// given a world or actor of the wrong type.
+func ToScorer[W, A any](scorer Scorer[W, A]) utilsys.Scorer {
+	if typed, ok := scorer.(typedScorer[W, A]); ok {
+		return typed.scorer
+	}
+	return untypedScorer[W, A]{scorer: scorer}
+}
+
+// FromScorer adapts a utilsys.Scorer to a typed scorer
+func FromScorer[W, A any](scorer utilsys.Scorer) Scorer[W, A] {
+	if untyped, ok := scorer.(untypedScorer[W, A]); ok {
+		return untyped.scorer
+	}
+	return typedScorer[W, A]{scorer: scorer}
+}
+
+type untypedScorerBuilder[W, A any] struct {
+	builder ScorerBuilder[W, A]
+}
+
+func (b untypedScorerBuilder[W, A]) Build() utilsys.Scorer {
+	return ToScorer(b.builder.Build())
+}
+
+type typedScorerBuilder[W, A any] struct {
+	builder utilsys.ScorerBuilder
+}
+
+func (b typedScorerBuilder[W, A]) Build() Scorer[W, A] {
+	return FromScorer[W, A](b.builder.Build())
+}
+
+// ToScorerBuilder adapts a typed scorer builder to utilsys.ScorerBuilder,
+// e.g., to qualify it with a utilsys.CurveQualifier
+func ToScorerBuilder[W, A any](builder ScorerBuilder[W, A]) utilsys.ScorerBuilder {
+	if typed, ok := builder.(typedScorerBuilder[W, A]); ok {
+		return typed.builder
+	}
+	return untypedScorerBuilder[W, A]{builder: builder}
+}
+
+// FromScorerBuilder adapts a utilsys.ScorerBuilder to a typed scorer builder.
+// The untyped scorers must accept worlds of type W and actors of type A.
+func FromScorerBuilder[W, A any](builder utilsys.ScorerBuilder) ScorerBuilder[W, A] {
+	if untyped, ok := builder.(untypedScorerBuilder[W, A]); ok {
+		return untyped.builder
+	}
+	return typedScorerBuilder[W, A]{builder: builder}
+}
@@ -0,0 +1,91 @@
+package typed
+
+import (
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+)
+
+// AI is utilsys.AI for the world W and actors A
+type AI[W, A any] struct {
+	ai *utilsys.AI
+}
+
+// NewAI constructs a new AI within the given world, which uses the given
+// coreAction for all actors, as in utilsys.NewAI
+func NewAI[W, A any](world W, coreAction ActionBuilder[W, A]) *AI[W, A] {
+	return &AI[W, A]{ai: utilsys.NewAI(world, ToActionBuilder(coreAction))}
+}
+
+// Untyped returns the underlying utilsys.AI
+func (ai *AI[W, A]) Untyped() *utilsys.AI {
+	return ai.ai
+}
+
+// AddActor adds the given actor to be handled by this AI.
+//
+// performance: O(1) amortized
+func (ai *AI[W, A]) AddActor(actor A) {
+	ai.ai.AddActor(actor)
+}
+
+// AddActorWithAction adds the given actor to be handled by this AI using
+// an action built from the given builder, as in
+// utilsys.AI.AddActorWithAction
+//
+// performance: O(1) amortized
+func (ai *AI[W, A]) AddActorWithAction(actor A, builder ActionBuilder[W, A]) {
+	ai.ai.AddActorWithAction(actor, ToActionBuilder(builder))
+}
+
+// RemoveActor removes the given actor from being handled by this AI,
+// canceling its action if it's running.
+//
+// performance: O(1)
+func (ai *AI[W, A]) RemoveActor(actor A) {
+	ai.ai.RemoveActor(actor)
+}
+
+// HasActor returns true if the given actor is handled by this AI
+//
+// performance: O(1)
+func (ai *AI[W, A]) HasActor(actor A) bool {
+	return ai.ai.HasActor(actor)
+}
+
+// Tick all of the actions for actors handled by this AI, informing them
+// the given amount of time has passed
+func (ai *AI[W, A]) Tick(delta time.Duration) {
+	ai.ai.Tick(delta)
+}
+
+// SetCoreAction replaces the core action used for all actors, as in
+// utilsys.AI.SetCoreAction
+func (ai *AI[W, A]) SetCoreAction(coreAction ActionBuilder[W, A]) {
+	ai.ai.SetCoreAction(ToActionBuilder(coreAction))
+}
+
+// SetClock sets the clock used by actions which measure time, as in
+// utilsys.AI.SetClock
+func (ai *AI[W, A]) SetClock(clock utilsys.Clock) {
+	ai.ai.SetClock(clock)
+}
+
+// SetParallelScoring enables scoring across the given number of worker
+// goroutines, as in utilsys.AI.SetParallelScoring
+func (ai *AI[W, A]) SetParallelScoring(workers int) {
+	ai.ai.SetParallelScoring(workers)
+}
+
+// SetTracer sets the tracer which records decisions, as in
+// utilsys.AI.SetTracer. The actorName function may be nil to use
+// utilsys.DefaultActorName.
+func (ai *AI[W, A]) SetTracer(tracer utilsys.Tracer, actorName func(actor A) string) {
+	if actorName == nil {
+		ai.ai.SetTracer(tracer, nil)
+		return
+	}
+	ai.ai.SetTracer(tracer, func(actor interface{}) string {
+		return actorName(actor.(A))
+	})
+}
@@ -0,0 +1,127 @@
+// Package typed is a type-safe API for utilsys using generics. Actions,
+// scorers and the AI are parameterized by the type of the world W and of
+// the actors A, so passing the wrong kind of actor is a compile error
+// rather than a failed type assertion. Adapters convert to and from the
+// interfaces in utilsys, so the built-in thinkers, composites and
+// qualifiers can be used with typed actions and scorers.
+package typed
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+)
+
+// Action is utilsys.Action for the world W and actors A
+type Action[W, A any] interface {
+	// State returns the state of this action, as in utilsys.Action
+	State() utilsys.ActionState
+
+	// Attached is called when the action is in the state Init to let it
+	// know which world and actor it's acting upon, as in utilsys.Action
+	Attached(world W, actor A)
+
+	// Execute the action, as in utilsys.Action
+	Execute(delta time.Duration)
+
+	// Cancel the action, as in utilsys.Action
+	Cancel()
+
+	// FinishCanceling continues canceling the action, as in utilsys.Action
+	FinishCanceling(delta time.Duration)
+
+	// Reset the action after it finished, as in utilsys.Action
+	Reset()
+}
+
+// ActionBuilder is utilsys.ActionBuilder for the world W and actors A
+type ActionBuilder[W, A any] interface {
+	// Build a new action which isn't attached yet
+	Build() Action[W, A]
+}
+
+// Scorer is utilsys.Scorer for the world W and actors A
+type Scorer[W, A any] interface {
+	// Attached is called once to tell the scorer the world and actor it's
+	// scoring for
+	Attached(world W, actor A)
+
+	// Score returns the current score, typically between 0 and 1
+	Score() float64
+}
+
+// ScorerBuilder is utilsys.ScorerBuilder for the world W and actors A
+type ScorerBuilder[W, A any] interface {
+	// Build a new scorer which isn't attached yet
+	Build() Scorer[W, A]
+}
+
+// ActionBuilderFunc is an ActionBuilder which calls the function to build
+// each action
+type ActionBuilderFunc[W, A any] func() Action[W, A]
+
+// Build implements ActionBuilder
+func (f ActionBuilderFunc[W, A]) Build() Action[W, A] {
+	return f()
+}
+
+type funcScorer[W, A any] struct {
+	score func(world W, actor A) float64
+	world W
+	actor A
+}
+
+func (s *funcScorer[W, A]) Attached(world W, actor A) {
+	s.world = world
+	s.actor = actor
+}
+
+func (s *funcScorer[W, A]) Score() float64 {
+	return s.score(s.world, s.actor)
+}
+
+// ScorerFunc is a ScorerBuilder for stateless scorers, which scores by
+// calling the function with the world and actor
+type ScorerFunc[W, A any] func(world W, actor A) float64
+
+// Build implements ScorerBuilder
+func (f ScorerFunc[W, A]) Build() Scorer[W, A] {
+	return &funcScorer[W, A]{score: f}
+}
+
+// Scored pairs a typed action and scorer which are independent of each other,
+// like utilsys.ScorerBuilderAndActionBuilder. It implements
+// utilsys.ScoredActionBuilder, so it can be a child of the thinkers in
+// utilsys.
+type Scored[W, A any] struct {
+	// Action builds actions
+	Action ActionBuilder[W, A]
+
+	// Scorer builds scorers
+	Scorer ScorerBuilder[W, A]
+}
+
+// Build implements utilsys.ScoredActionBuilder
+func (b Scored[W, A]) Build() utilsys.ScoredAction {
+	return utilsys.ScoredAction{
+		Action: ToAction(b.Action.Build()),
+		Scorer: ToScorer(b.Scorer.Build()),
+	}
+}
+
+// cast converts the world or actor passed to an untyped Attached to the
+// type it should be, panicking with a descriptive message if it isn't. Nil
+// is converted to the zero value.
+func cast[T any](value interface{}, what string) T {
+	if value == nil {
+		var zero T
+		return zero
+	}
+	res, ok := value.(T)
+	if !ok {
+		var zero T
+		panic(fmt.Sprintf("utilsys/typed: expected %s of type %T, got %T", what, zero, value))
+	}
+	return res
+}
@@ -0,0 +1,149 @@
+package typed_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys"
+	"github.com/calamity-of-subterfuge/cos/pkg/utilsys/typed"
+)
+
+type world struct {
+	hungry map[string]bool
+}
+
+type villager struct {
+	name  string
+	meals int
+	naps  int
+}
+
+// eatAction eats a meal, succeeding immediately
+type eatAction struct {
+	world    *world
+	villager *villager
+	state    utilsys.ActionState
+}
+
+func (a *eatAction) State() utilsys.ActionState { return a.state }
+
+func (a *eatAction) Attached(w *world, v *villager) {
+	a.world = w
+	a.villager = v
+	a.state = utilsys.ActionStateRequested
+}
+
+func (a *eatAction) Execute(delta time.Duration) {
+	a.villager.meals++
+	a.world.hungry[a.villager.name] = false
+	a.state = utilsys.ActionStateSuccess
+}
+
+func (a *eatAction) Cancel()                             { a.state = utilsys.ActionStateFailure }
+func (a *eatAction) FinishCanceling(delta time.Duration) {}
+func (a *eatAction) Reset()                              { a.state = utilsys.ActionStateRequested }
+
+// napAction naps, succeeding immediately
+type napAction struct {
+	villager *villager
+	state    utilsys.ActionState
+}
+
+func (a *napAction) State() utilsys.ActionState { return a.state }
+
+func (a *napAction) Attached(w *world, v *villager) {
+	a.villager = v
+	a.state = utilsys.ActionStateRequested
+}
+
+func (a *napAction) Execute(delta time.Duration) {
+	a.villager.naps++
+	a.state = utilsys.ActionStateSuccess
+}
+
+func (a *napAction) Cancel()                             { a.state = utilsys.ActionStateFailure }
+func (a *napAction) FinishCanceling(delta time.Duration) {}
+func (a *napAction) Reset()                              { a.state = utilsys.ActionStateRequested }
+
+func hunger(w *world, v *villager) float64 {
+	if w.hungry[v.name] {
+		return 1
+	}
+	return 0
+}
+
+func villagerThinker() typed.ActionBuilder[*world, *villager] {
+	return typed.FromActionBuilder[*world, *villager](utilsys.NewHighestScoreThinker([]utilsys.ScoredActionBuilder{
+		typed.Scored[*world, *villager]{
+			Action: typed.ActionBuilderFunc[*world, *villager](func() typed.Action[*world, *villager] { return &eatAction{} }),
+			Scorer: typed.ScorerFunc[*world, *villager](hunger),
+		},
+		typed.Scored[*world, *villager]{
+			Action: typed.ActionBuilderFunc[*world, *villager](func() typed.Action[*world, *villager] { return &napAction{} }),
+			Scorer: typed.FromScorerBuilder[*world, *villager](utilsys.FixedScorer{Score: 0.5}),
+		},
+	}))
+}
+
+func TestAI(t *testing.T) {
+	w := &world{hungry: map[string]bool{"ann": true}}
+	ann := &villager{name: "ann"}
+	bob := &villager{name: "bob"}
+
+	ai := typed.NewAI(w, villagerThinker())
+	ai.AddActor(ann)
+	ai.AddActor(bob)
+	for i := 0; i < 3; i++ {
+		ai.Tick(50 * time.Millisecond)
+	}
+
+	if ann.meals != 1 || ann.naps != 2 {
+		t.Fatalf("expected ann to eat once and then nap, got %+v", ann)
+	}
+	if bob.meals != 0 || bob.naps != 3 {
+		t.Fatalf("expected bob to only nap, got %+v", bob)
+	}
+
+	ai.RemoveActor(bob)
+	ai.Tick(50 * time.Millisecond)
+	if bob.naps != 3 {
+		t.Fatalf("expected bob to stop once removed, got %+v", bob)
+	}
+}
+
+func TestAI_SetTracer(t *testing.T) {
+	w := &world{hungry: map[string]bool{}}
+	ai := typed.NewAI(w, villagerThinker())
+
+	var sb strings.Builder
+	ai.SetTracer(utilsys.NewTreeTracer(&sb), func(v *villager) string { return v.name })
+	ai.AddActor(&villager{name: "ann"})
+	if !strings.HasPrefix(sb.String(), "[tick 0] ann root select -> #1") {
+		t.Fatalf("expected a decision for ann, got\n%s", sb.String())
+	}
+}
+
+func TestAdapters_roundTrip(t *testing.T) {
+	action := &napAction{}
+	if typed.FromAction[*world, *villager](typed.ToAction[*world, *villager](action)) != typed.Action[*world, *villager](action) {
+		t.Fatal("expected converting an action back and forth to unwrap it")
+	}
+
+	untyped := utilsys.FixedScorer{Score: 0.5}
+	if typed.ToScorerBuilder(typed.FromScorerBuilder[*world, *villager](untyped)) != utilsys.ScorerBuilder(untyped) {
+		t.Fatal("expected converting a scorer builder back and forth to unwrap it")
+	}
+}
+
+func TestToAction_wrongActor(t *testing.T) {
+	action := typed.ToAction[*world, *villager](&napAction{})
+
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, "expected actor of type *typed_test.villager, got string") {
+			t.Fatalf("expected a descriptive panic, got %q", msg)
+		}
+	}()
+	action.Attached(&world{}, "bob")
+}
@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+)
+
+// ConfigError describes every problem found with a Config
+type ConfigError struct {
+	// Problems with the config, one per invalid field
+	Problems []string
+}
+
+// Error implements the error interface for ConfigError
+func (e *ConfigError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// semverRegex matches semantic versions, see https://semver.org
+var semverRegex = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// ValidateConfig checks that the given config can be used with Play,
+// returning a *ConfigError describing every problem if it can't. The
+// credentials are not required if the config has an AuthManager.
+func ValidateConfig(cfg *Config) error {
+	if cfg == nil {
+		return &ConfigError{Problems: []string{"config is nil"}}
+	}
+
+	var problems []string
+	if cfg.Auth == nil {
+		if cfg.Email == "" {
+			problems = append(problems, "email is required")
+		}
+		if cfg.GrantIden == "" {
+			problems = append(problems, "grant_iden is required")
+		}
+		if cfg.Secret == "" {
+			problems = append(problems, "secret is required")
+		}
+	}
+
+	if cfg.AIConfig == nil {
+		problems = append(problems, "ai config is required")
+	} else {
+		problems = append(problems, aiConfigProblems(cfg.AIConfig)...)
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// aiConfigProblems returns the problems with the given AIConfig
+func aiConfigProblems(cfg *AIConfig) []string {
+	var problems []string
+	if cfg.AIName == "" {
+		problems = append(problems, "ai_name is required")
+	}
+	if cfg.AIUID == "" {
+		problems = append(problems, "ai_uid is required")
+	}
+	if !semverRegex.MatchString(cfg.Version) {
+		problems = append(problems, fmt.Sprintf("version %q is not a semantic version", cfg.Version))
+	}
+	switch cfg.Role {
+	case utils.RoleEconomyAI, utils.RoleMilitaryAI, utils.RoleScienceAI:
+	default:
+		problems = append(problems, fmt.Sprintf("role %q is not an AI role (economy, military or science)", cfg.Role.Name()))
+	}
+	if cfg.MaxConcurrentInstances < 1 {
+		problems = append(problems, "max_concurrent_instances must be at least 1")
+	}
+	return problems
+}
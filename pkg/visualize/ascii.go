@@ -0,0 +1,274 @@
+package visualize
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/jakecoffman/cp"
+)
+
+// DefaultASCIIWidth and DefaultASCIIHeight are the size of text renders in
+// characters when not specified. Terminal characters are about twice as tall
+// as they are wide, so these render a square view.
+const (
+	DefaultASCIIWidth  = 80
+	DefaultASCIIHeight = 40
+)
+
+// ASCIIOptions configures text renders
+type ASCIIOptions struct {
+	Options
+
+	// Width and Height of the grid in characters, defaulting to
+	// DefaultASCIIWidth and DefaultASCIIHeight
+	Width  int
+	Height int
+
+	// Color uses ANSI escape codes to color players and smart objects by team
+	Color bool
+
+	// HideLegend disables the list of what's drawn below the grid
+	HideLegend bool
+}
+
+// ansiTeamColors are the ANSI foreground colors matching TeamColors
+var ansiTeamColors = []int{37, 31, 34, 32, 33, 35}
+
+// asciiCell is a single character in a text render
+type asciiCell struct {
+	char rune
+	team int
+}
+
+// RenderASCII renders the state as text. Walls are drawn with '#', generic
+// objects with '%', our vision square with '.', our player with '@', other
+// players with their team number and smart objects with the first letter of
+// their unit type, uppercase if we control them. Unless disabled, a legend
+// labeling the players, smart objects and overlays follows the grid.
+func RenderASCII(state *client.State, opts *ASCIIOptions) string {
+	var sb strings.Builder
+	WriteASCII(&sb, state, opts)
+	return sb.String()
+}
+
+// WriteASCII writes the text render of the state to the given writer, as in
+// RenderASCII
+func WriteASCII(w io.Writer, state *client.State, opts *ASCIIOptions) error {
+	if opts == nil {
+		opts = &ASCIIOptions{}
+	}
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultASCIIWidth
+	}
+	height := opts.Height
+	if height <= 0 {
+		height = DefaultASCIIHeight
+	}
+
+	s := buildScene(state, &opts.Options)
+	cellW := (s.max.X - s.min.X) / float64(width)
+	cellH := (s.max.Y - s.min.Y) / float64(height)
+	tolerance := 0.5 * cellW
+	if cellH < cellW {
+		tolerance = 0.5 * cellH
+	}
+
+	grid := make([][]asciiCell, height)
+	for row := range grid {
+		grid[row] = make([]asciiCell, width)
+		for col := range grid[row] {
+			grid[row][col] = asciiCell{char: ' ', team: -1}
+		}
+	}
+
+	cellOf := func(p cp.Vector) (int, int) {
+		return int(math.Floor((p.Y - s.min.Y) / cellH)), int(math.Floor((p.X - s.min.X) / cellW))
+	}
+	setCell := func(row, col int, cell asciiCell) {
+		if row >= 0 && row < height && col >= 0 && col < width {
+			grid[row][col] = cell
+		}
+	}
+	set := func(p cp.Vector, cell asciiCell) {
+		row, col := cellOf(p)
+		setCell(row, col, cell)
+	}
+	line := func(a, b cp.Vector, cell asciiCell) {
+		steps := int(a.Distance(b)/tolerance) + 1
+		for i := 0; i <= steps; i++ {
+			set(a.Lerp(b, float64(i)/float64(steps)), cell)
+		}
+	}
+
+	if s.vision != nil {
+		// drawn by cell rather than as lines since the edges often fall
+		// exactly on cell boundaries
+		cell := asciiCell{char: '.', team: -1}
+		top, left := cellOf(cp.Vector{X: s.vision.L, Y: s.vision.B})
+		bottom, right := cellOf(cp.Vector{X: s.vision.R, Y: s.vision.T})
+		for col := left; col <= right; col++ {
+			setCell(top, col, cell)
+			setCell(bottom, col, cell)
+		}
+		for row := top; row <= bottom; row++ {
+			setCell(row, left, cell)
+			setCell(row, right, cell)
+		}
+	}
+
+	for _, item := range s.items {
+		if item.kind != kindWall && item.kind != kindGeneric {
+			continue
+		}
+
+		cell := asciiCell{char: '#', team: -1}
+		if item.kind == kindGeneric {
+			cell.char = '%'
+		}
+		for row := range grid {
+			for col := range grid[row] {
+				p := cp.Vector{
+					X: s.min.X + (float64(col)+0.5)*cellW,
+					Y: s.min.Y + (float64(row)+0.5)*cellH,
+				}
+				if containsPoint(item.shapes, p, tolerance) {
+					grid[row][col] = cell
+				}
+			}
+		}
+		if item.kind == kindGeneric {
+			set(item.position, cell)
+		}
+	}
+
+	var legend []string
+	for _, item := range s.items {
+		if item.kind != kindSmartObject && item.kind != kindPlayer {
+			continue
+		}
+		if !s.inView(item.position) {
+			continue
+		}
+
+		cell := asciiCell{char: asciiChar(&item), team: item.team}
+		set(item.position, cell)
+		legend = append(legend, fmt.Sprintf(
+			"%c %s %s (team %d) at (%.1f, %.1f)",
+			cell.char, item.uid, item.label, item.team, item.position.X, item.position.Y,
+		))
+	}
+
+	for _, overlay := range s.overlays {
+		cell := asciiCell{char: overlay.Rune, team: -1}
+		if cell.char == 0 {
+			cell.char = '*'
+		}
+		for i, p := range overlay.Points {
+			if i > 0 {
+				line(overlay.Points[i-1], p, cell)
+			} else {
+				set(p, cell)
+			}
+		}
+		if overlay.Closed && len(overlay.Points) > 2 {
+			line(overlay.Points[len(overlay.Points)-1], overlay.Points[0], cell)
+		}
+		if overlay.Label != "" {
+			legend = append(legend, fmt.Sprintf("%c %s", cell.char, overlay.Label))
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, row := range grid {
+		for _, cell := range row {
+			if opts.Color && cell.team >= 0 {
+				fmt.Fprintf(bw, "\x1b[1;%dm%c\x1b[0m", ansiTeamColor(cell.team), cell.char)
+			} else {
+				bw.WriteRune(cell.char)
+			}
+		}
+		bw.WriteByte('\n')
+	}
+
+	if !opts.HideLegend {
+		for _, entry := range legend {
+			bw.WriteString(entry)
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// asciiChar returns the character used for the given player or smart object
+func asciiChar(item *sceneItem) rune {
+	if item.kind == kindPlayer {
+		if item.mine {
+			return '@'
+		}
+		if item.team >= 0 && item.team <= 9 {
+			return rune('0' + item.team)
+		}
+		return 'P'
+	}
+
+	char := '?'
+	for _, r := range item.label {
+		char = r
+		break
+	}
+	if item.mine {
+		return unicode.ToUpper(char)
+	}
+	return unicode.ToLower(char)
+}
+
+// ansiTeamColor returns the ANSI foreground color for the given team
+func ansiTeamColor(team int) int {
+	if team >= len(ansiTeamColors) {
+		return ansiTeamColors[len(ansiTeamColors)-1]
+	}
+	return ansiTeamColors[team]
+}
+
+// Terminal is a live view of the state in a terminal, which redraws in
+// place each time Draw is called
+type Terminal struct {
+	out  io.Writer
+	opts ASCIIOptions
+}
+
+// NewTerminal creates a live view writing to the given terminal, usually
+// os.Stdout
+func NewTerminal(out io.Writer, opts *ASCIIOptions) *Terminal {
+	res := &Terminal{out: out}
+	if opts != nil {
+		res.opts = *opts
+	}
+	return res
+}
+
+// Options returns the options used for drawing, which may be modified
+// between draws, e.g., to update overlays
+func (t *Terminal) Options() *ASCIIOptions {
+	return &t.opts
+}
+
+// Draw clears the terminal and draws the given state
+func (t *Terminal) Draw(state *client.State) error {
+	var sb strings.Builder
+	sb.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&sb, "game time %.3f\n", state.GameTime)
+	err := WriteASCII(&sb, state, &t.opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(t.out, sb.String())
+	return err
+}
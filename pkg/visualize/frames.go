@@ -0,0 +1,90 @@
+package visualize
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+)
+
+// FrameFormat is the image format frames are written in
+type FrameFormat int
+
+const (
+	// FrameFormatSVG writes frames as SVG images
+	FrameFormatSVG FrameFormat = iota
+
+	// FrameFormatPNG writes frames as PNG images
+	FrameFormatPNG
+)
+
+// extension returns the file extension for the format
+func (f FrameFormat) extension() string {
+	if f == FrameFormatPNG {
+		return ".png"
+	}
+	return ".svg"
+}
+
+// FrameRecorder writes an image of the state to a directory every N ticks,
+// e.g., by calling Tick from the game's Tick function
+type FrameRecorder struct {
+	// Dir is the directory frames are written to, which is created if it
+	// doesn't exist. Frames are named frame-<tick>.<ext>.
+	Dir string
+
+	// Every is the number of ticks between frames; values below 1 write a
+	// frame every tick
+	Every int
+
+	// Format of the frames
+	Format FrameFormat
+
+	// Options for rendering the frames, which may be modified between
+	// ticks, e.g., to update overlays
+	Options ImageOptions
+
+	ticks int
+}
+
+// NewFrameRecorder creates a recorder which writes a frame every given number
+// of ticks to the given directory
+func NewFrameRecorder(dir string, every int, format FrameFormat) *FrameRecorder {
+	return &FrameRecorder{Dir: dir, Every: every, Format: format}
+}
+
+// Tick counts a tick, writing a frame for the given state if it's due.
+// Returns the path to the frame if one was written.
+func (r *FrameRecorder) Tick(state *client.State) (string, error) {
+	tick := r.ticks
+	r.ticks++
+	if r.Every > 1 && tick%r.Every != 0 {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if r.Format == FrameFormatPNG {
+		err = WritePNG(&buf, state, &r.Options)
+	} else {
+		err = WriteSVG(&buf, state, &r.Options)
+	}
+	if err != nil {
+		return "", fmt.Errorf("rendering frame %d: %w", tick, err)
+	}
+
+	err = os.MkdirAll(r.Dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(r.Dir, fmt.Sprintf("frame-%06d%s", tick, r.Format.extension()))
+	err = ioutil.WriteFile(path, buf.Bytes(), 0644)
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,111 @@
+package visualize
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/jakecoffman/cp"
+)
+
+// RenderImage rasterizes the state. Labels aren't drawn; use SVG when they
+// are needed.
+func RenderImage(state *client.State, opts *ImageOptions) *image.RGBA {
+	if opts == nil {
+		opts = &ImageOptions{}
+	}
+	s := buildScene(state, &opts.Options)
+	scale := opts.pixelsPerUnit()
+	size := s.max.Sub(s.min)
+	width := int(size.X * scale)
+	height := int(size.Y * scale)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = background.R, background.G, background.B, background.A
+	}
+
+	toPixel := func(p cp.Vector) (int, int) {
+		return int(math.Floor((p.X - s.min.X) * scale)), int(math.Floor((p.Y - s.min.Y) * scale))
+	}
+	toWorld := func(x, y int) cp.Vector {
+		return cp.Vector{X: s.min.X + (float64(x)+0.5)/scale, Y: s.min.Y + (float64(y)+0.5)/scale}
+	}
+	line := func(a, b cp.Vector, col color.RGBA) {
+		steps := int(a.Distance(b)*scale) + 1
+		for i := 0; i <= steps; i++ {
+			x, y := toPixel(a.Lerp(b, float64(i)/float64(steps)))
+			img.SetRGBA(x, y, col)
+		}
+	}
+
+	for _, item := range s.items {
+		fill := wallColor
+		switch item.kind {
+		case kindGeneric:
+			fill = genericColor
+		case kindSmartObject, kindPlayer:
+			fill = TeamColor(item.team)
+		}
+
+		for _, shape := range item.shapes {
+			bb := shape.BB()
+			minX, minY := toPixel(cp.Vector{X: bb.L, Y: bb.B})
+			maxX, maxY := toPixel(cp.Vector{X: bb.R, Y: bb.T})
+			rect := image.Rect(minX, minY, maxX+1, maxY+1).Intersect(img.Rect)
+			for y := rect.Min.Y; y < rect.Max.Y; y++ {
+				for x := rect.Min.X; x < rect.Max.X; x++ {
+					if shape.PointQuery(toWorld(x, y)).Distance <= 0 {
+						img.SetRGBA(x, y, fill)
+					}
+				}
+			}
+		}
+
+		if item.mine {
+			// mark what we control so it stands out from the rest of the team
+			x, y := toPixel(item.position)
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					img.SetRGBA(x+dx, y+dy, visionColor)
+				}
+			}
+		}
+	}
+
+	if s.vision != nil {
+		bb := *s.vision
+		corners := []cp.Vector{{X: bb.L, Y: bb.B}, {X: bb.R, Y: bb.B}, {X: bb.R, Y: bb.T}, {X: bb.L, Y: bb.T}}
+		for i := range corners {
+			line(corners[i], corners[(i+1)%len(corners)], visionColor)
+		}
+	}
+
+	for _, overlay := range s.overlays {
+		col := overlayRGBA(&overlay)
+		for i := 1; i < len(overlay.Points); i++ {
+			line(overlay.Points[i-1], overlay.Points[i], col)
+		}
+		if overlay.Closed && len(overlay.Points) > 2 {
+			line(overlay.Points[len(overlay.Points)-1], overlay.Points[0], col)
+		}
+		if len(overlay.Points) == 1 {
+			x, y := toPixel(overlay.Points[0])
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					img.SetRGBA(x+dx, y+dy, col)
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// WritePNG writes the state as a PNG image to the given writer
+func WritePNG(w io.Writer, state *client.State, opts *ImageOptions) error {
+	return png.Encode(w, RenderImage(state, opts))
+}
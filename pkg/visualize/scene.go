@@ -0,0 +1,257 @@
+// Package visualize renders a client.State for debugging, either as text for
+// a terminal or as SVG or PNG images. World coordinates are drawn with x to
+// the right and y downward, matching the web client.
+package visualize
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+	"github.com/jakecoffman/cp"
+)
+
+// Overlay is something the caller wants drawn on top of the state, such as
+// a planned path or an area of interest.
+type Overlay struct {
+	// Label describes the overlay. It is drawn next to the first point in
+	// images and listed in the legend of text renders.
+	Label string
+
+	// Points of the overlay in world coordinates. A single point is drawn
+	// as a marker and multiple points as a line through them.
+	Points []cp.Vector
+
+	// Closed connects the last point back to the first, e.g., for areas
+	Closed bool
+
+	// Color of the overlay in images. Defaults to magenta.
+	Color color.RGBA
+
+	// Rune used for the overlay in text renders. Defaults to '*'.
+	Rune rune
+}
+
+// Options configures what is rendered. The zero value renders the area
+// around our player.
+type Options struct {
+	// Center of the view in world coordinates. If nil, the view is centered
+	// on our player, or the origin if our player isn't known.
+	Center *cp.Vector
+
+	// Radius is half the side length of the square view in world units.
+	// Defaults to 1.5 times utils.VISION_DISTANCE so the vision square is
+	// visible.
+	Radius float64
+
+	// Overlays are drawn on top of everything else
+	Overlays []Overlay
+
+	// HideVision disables drawing our vision square
+	HideVision bool
+}
+
+// TeamColors are the colors used for each team in images, indexed by team.
+// Teams beyond the end of the slice use the last color.
+var TeamColors = []color.RGBA{
+	{R: 0x99, G: 0x99, B: 0x99, A: 0xff},
+	{R: 0xe6, G: 0x19, B: 0x4b, A: 0xff},
+	{R: 0x43, G: 0x63, B: 0xd8, A: 0xff},
+	{R: 0x3c, G: 0xb4, B: 0x4b, A: 0xff},
+	{R: 0xf5, G: 0x82, B: 0x31, A: 0xff},
+	{R: 0x91, G: 0x1e, B: 0xb4, A: 0xff},
+}
+
+// TeamColor returns the color for the given team
+func TeamColor(team int) color.RGBA {
+	if team < 0 {
+		return TeamColors[0]
+	}
+	if team >= len(TeamColors) {
+		return TeamColors[len(TeamColors)-1]
+	}
+	return TeamColors[team]
+}
+
+var (
+	wallColor    = color.RGBA{R: 0x44, G: 0x44, B: 0x44, A: 0xff}
+	genericColor = color.RGBA{R: 0xbb, G: 0xaa, B: 0x88, A: 0xff}
+	visionColor  = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	overlayColor = color.RGBA{R: 0xff, G: 0x00, B: 0xff, A: 0xff}
+	background   = color.RGBA{R: 0x1e, G: 0x1e, B: 0x1e, A: 0xff}
+)
+
+// itemKind is the kind of thing a sceneItem is, in the order they're drawn
+type itemKind int
+
+const (
+	kindWall itemKind = iota
+	kindGeneric
+	kindSmartObject
+	kindPlayer
+)
+
+// sceneItem is a single object to render
+type sceneItem struct {
+	kind     itemKind
+	uid      string
+	shapes   []*cp.Shape
+	position cp.Vector
+	team     int
+
+	// label is drawn next to smart objects and players in images
+	label string
+
+	// mine is true for our player and the smart objects we control
+	mine bool
+}
+
+// scene is everything to render from a state, in world coordinates
+type scene struct {
+	min    cp.Vector
+	max    cp.Vector
+	items  []sceneItem
+	vision *cp.BB
+
+	overlays []Overlay
+}
+
+// buildScene collects the items to render from the given state
+func buildScene(state *client.State, opts *Options) *scene {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	me := state.PlayersByUID[state.MyUID]
+
+	center := cp.Vector{}
+	if opts.Center != nil {
+		center = *opts.Center
+	} else if me != nil {
+		center = me.GameObject.Body.Position()
+	}
+
+	radius := opts.Radius
+	if radius <= 0 {
+		radius = 1.5 * utils.VISION_DISTANCE
+	}
+
+	res := &scene{
+		min:      cp.Vector{X: center.X - radius, Y: center.Y - radius},
+		max:      cp.Vector{X: center.X + radius, Y: center.Y + radius},
+		overlays: opts.Overlays,
+	}
+
+	if me != nil && !opts.HideVision {
+		pos := me.GameObject.Body.Position()
+		vision := cp.NewBBForExtents(pos, utils.VISION_DISTANCE, utils.VISION_DISTANCE)
+		res.vision = &vision
+	}
+
+	for idx := range state.StaticObjects {
+		obj := &state.StaticObjects[idx]
+		res.items = append(res.items, gameObjectItem(kindWall, obj))
+	}
+
+	for _, uid := range sortedKeys(len(state.GenericObjectsByUID), func(add func(string)) {
+		for uid := range state.GenericObjectsByUID {
+			add(uid)
+		}
+	}) {
+		res.items = append(res.items, gameObjectItem(kindGeneric, state.GenericObjectsByUID[uid]))
+	}
+
+	for _, uid := range sortedKeys(len(state.SmartObjectsByUID), func(add func(string)) {
+		for uid := range state.SmartObjectsByUID {
+			add(uid)
+		}
+	}) {
+		so := state.SmartObjectsByUID[uid]
+		item := gameObjectItem(kindSmartObject, so.GameObject)
+		item.team = so.ControllingTeam
+		item.label = fmt.Sprintf("%s %d/%d", so.UnitType, so.CurrentHealth, so.MaxHealth)
+		item.mine = so.ControllingTeam == state.MyTeam && so.ControllingRole == state.MyRole
+		res.items = append(res.items, item)
+	}
+
+	for _, uid := range sortedKeys(len(state.PlayersByUID), func(add func(string)) {
+		for uid := range state.PlayersByUID {
+			add(uid)
+		}
+	}) {
+		plyr := state.PlayersByUID[uid]
+		item := gameObjectItem(kindPlayer, plyr.GameObject)
+		item.team = plyr.Team
+		item.label = plyr.Role.Name()
+		item.mine = uid == state.MyUID
+		res.items = append(res.items, item)
+	}
+
+	return res
+}
+
+// gameObjectItem creates the item for the given game object
+func gameObjectItem(kind itemKind, obj *client.GameObject) sceneItem {
+	item := sceneItem{kind: kind, uid: obj.UID}
+	if obj.Body == nil {
+		return item
+	}
+
+	item.position = obj.Body.Position()
+	obj.Body.EachShape(func(s *cp.Shape) {
+		item.shapes = append(item.shapes, s)
+	})
+	return item
+}
+
+// sortedKeys returns the keys produced by each, sorted, so renders are
+// deterministic
+func sortedKeys(size int, each func(add func(string))) []string {
+	res := make([]string, 0, size)
+	each(func(key string) {
+		res = append(res, key)
+	})
+	sort.Strings(res)
+	return res
+}
+
+// inView returns true if the given point is within the scene
+func (s *scene) inView(p cp.Vector) bool {
+	return p.X >= s.min.X && p.X < s.max.X && p.Y >= s.min.Y && p.Y < s.max.Y
+}
+
+// shapeOutline returns the vertices of the given shape in world coordinates,
+// or for circles a single vertex and the radius
+func shapeOutline(shape *cp.Shape) ([]cp.Vector, float64) {
+	switch v := shape.Class.(type) {
+	case *cp.PolyShape:
+		res := make([]cp.Vector, v.Count())
+		for i := range res {
+			res[i] = v.TransformVert(i)
+		}
+		return res, v.Radius()
+	case *cp.Circle:
+		return []cp.Vector{v.TransformC()}, v.Radius()
+	case *cp.Segment:
+		return []cp.Vector{v.TransformA(), v.TransformB()}, v.Radius()
+	default:
+		return nil, 0
+	}
+}
+
+// containsPoint returns true if any of the given shapes contains the point,
+// within the given tolerance
+func containsPoint(shapes []*cp.Shape, p cp.Vector, tolerance float64) bool {
+	for _, shape := range shapes {
+		bb := shape.BB()
+		if p.X < bb.L-tolerance || p.X > bb.R+tolerance || p.Y < bb.B-tolerance || p.Y > bb.T+tolerance {
+			continue
+		}
+		if shape.PointQuery(p).Distance <= tolerance {
+			return true
+		}
+	}
+	return false
+}
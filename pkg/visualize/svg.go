@@ -0,0 +1,176 @@
+package visualize
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/jakecoffman/cp"
+)
+
+// DefaultPixelsPerUnit is the scale of images when not specified
+const DefaultPixelsPerUnit = 20
+
+// ImageOptions configures SVG and PNG renders
+type ImageOptions struct {
+	Options
+
+	// PixelsPerUnit is the number of pixels per world unit, defaulting to
+	// DefaultPixelsPerUnit
+	PixelsPerUnit float64
+}
+
+// pixelsPerUnit returns the scale to use for images
+func (o *ImageOptions) pixelsPerUnit() float64 {
+	if o.PixelsPerUnit <= 0 {
+		return DefaultPixelsPerUnit
+	}
+	return o.PixelsPerUnit
+}
+
+// RenderSVG renders the state as an SVG image
+func RenderSVG(state *client.State, opts *ImageOptions) []byte {
+	var buf bytes.Buffer
+	WriteSVG(&buf, state, opts)
+	return buf.Bytes()
+}
+
+// WriteSVG writes the state as an SVG image to the given writer. The image
+// uses world coordinates, so overlays can be added by editing the output.
+func WriteSVG(w io.Writer, state *client.State, opts *ImageOptions) error {
+	if opts == nil {
+		opts = &ImageOptions{}
+	}
+	s := buildScene(state, &opts.Options)
+	size := s.max.Sub(s.min)
+	scale := opts.pixelsPerUnit()
+	stroke := 1 / scale
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(
+		bw,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="%g %g %g %g">`+"\n",
+		int(size.X*scale), int(size.Y*scale), s.min.X, s.min.Y, size.X, size.Y,
+	)
+	fmt.Fprintf(bw, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`+"\n", s.min.X, s.min.Y, size.X, size.Y, cssColor(background))
+
+	for _, item := range s.items {
+		fill := wallColor
+		switch item.kind {
+		case kindGeneric:
+			fill = genericColor
+		case kindSmartObject, kindPlayer:
+			fill = TeamColor(item.team)
+		}
+
+		attrs := fmt.Sprintf(`fill="%s"`, cssColor(fill))
+		if item.mine {
+			attrs += fmt.Sprintf(` stroke="%s" stroke-width="%g"`, cssColor(visionColor), 2*stroke)
+		}
+		for _, shape := range item.shapes {
+			writeSVGShape(bw, shape, attrs)
+		}
+
+		if item.label != "" {
+			fmt.Fprintf(
+				bw,
+				`<text x="%g" y="%g" font-size="%g" fill="%s">%s</text>`+"\n",
+				item.position.X+0.6, item.position.Y-0.6, 12*stroke, cssColor(visionColor), escapeXML(item.label),
+			)
+		}
+	}
+
+	if s.vision != nil {
+		bb := *s.vision
+		fmt.Fprintf(
+			bw,
+			`<rect x="%g" y="%g" width="%g" height="%g" fill="none" stroke="%s" stroke-width="%g" stroke-dasharray="%g"/>`+"\n",
+			bb.L, bb.B, bb.R-bb.L, bb.T-bb.B, cssColor(visionColor), stroke, 4*stroke,
+		)
+	}
+
+	for _, overlay := range s.overlays {
+		col := overlayRGBA(&overlay)
+		switch {
+		case len(overlay.Points) == 1:
+			p := overlay.Points[0]
+			fmt.Fprintf(bw, `<circle cx="%g" cy="%g" r="%g" fill="%s"/>`+"\n", p.X, p.Y, 4*stroke, cssColor(col))
+		case len(overlay.Points) > 1:
+			tag := "polyline"
+			if overlay.Closed {
+				tag = "polygon"
+			}
+			fmt.Fprintf(
+				bw,
+				`<%s points="%s" fill="none" stroke="%s" stroke-width="%g"/>`+"\n",
+				tag, svgPoints(overlay.Points), cssColor(col), 2*stroke,
+			)
+		}
+
+		if overlay.Label != "" && len(overlay.Points) > 0 {
+			p := overlay.Points[0]
+			fmt.Fprintf(
+				bw,
+				`<text x="%g" y="%g" font-size="%g" fill="%s">%s</text>`+"\n",
+				p.X+0.3, p.Y-0.3, 12*stroke, cssColor(col), escapeXML(overlay.Label),
+			)
+		}
+	}
+
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+// writeSVGShape writes the element for the given shape with the given
+// attributes
+func writeSVGShape(w io.Writer, shape *cp.Shape, attrs string) {
+	verts, radius := shapeOutline(shape)
+	switch shape.Class.(type) {
+	case *cp.PolyShape:
+		fmt.Fprintf(w, `<polygon points="%s" %s/>`+"\n", svgPoints(verts), attrs)
+	case *cp.Circle:
+		fmt.Fprintf(w, `<circle cx="%g" cy="%g" r="%g" %s/>`+"\n", verts[0].X, verts[0].Y, radius, attrs)
+	case *cp.Segment:
+		fmt.Fprintf(
+			w,
+			`<line x1="%g" y1="%g" x2="%g" y2="%g" stroke-width="%g" stroke-linecap="round" %s/>`+"\n",
+			verts[0].X, verts[0].Y, verts[1].X, verts[1].Y, 2*radius, attrs,
+		)
+	}
+}
+
+// svgPoints formats the given points for a points attribute
+func svgPoints(points []cp.Vector) string {
+	var buf bytes.Buffer
+	for idx, p := range points {
+		if idx > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%g,%g", p.X, p.Y)
+	}
+	return buf.String()
+}
+
+// cssColor formats the given color as a CSS hex color
+func cssColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// overlayRGBA returns the color to draw the given overlay with
+func overlayRGBA(overlay *Overlay) color.RGBA {
+	if overlay.Color == (color.RGBA{}) {
+		return overlayColor
+	}
+	return overlay.Color
+}
+
+// escapeXML escapes the given text for use in an element
+func escapeXML(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
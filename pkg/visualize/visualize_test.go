@@ -0,0 +1,211 @@
+package visualize_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/calamity-of-subterfuge/cos/pkg/client"
+	"github.com/calamity-of-subterfuge/cos/pkg/srvpkts"
+	"github.com/calamity-of-subterfuge/cos/pkg/utils"
+	"github.com/calamity-of-subterfuge/cos/pkg/visualize"
+	"github.com/jakecoffman/cp"
+)
+
+// box creates a game object which is a box with the given half size
+func box(uid string, x, y, half float64) *client.GameObject {
+	return (&client.GameObject{}).Sync(&srvpkts.GameObjectSync{
+		UID:      uid,
+		Position: srvpkts.Vector{X: x, Y: y},
+		Shapes: []srvpkts.Shape{{
+			ShapeType: "polygon",
+			Mass:      1,
+			Details: srvpkts.PolygonDetails{
+				Vertices: []srvpkts.Vector{
+					{X: -half, Y: -half}, {X: half, Y: -half}, {X: half, Y: half}, {X: -half, Y: half},
+				},
+			},
+		}},
+	})
+}
+
+// testState creates a state with us at the origin, an enemy to the right,
+// a villager we control to the left and a wall above
+func testState() *client.State {
+	state := client.NewState()
+	state.MyUID = "me"
+	state.MyTeam = 1
+	state.MyRole = utils.RoleEconomyAI
+	state.PlayersByUID = make(map[string]*client.Player)
+	state.SmartObjectsByUID = make(map[string]*client.SmartObject)
+	state.GenericObjectsByUID = make(map[string]*client.GameObject)
+
+	state.PlayersByUID["me"] = &client.Player{GameObject: box("me", 0, 0, 0.5), Role: utils.RoleEconomyAI, Team: 1}
+	state.PlayersByUID["enemy"] = &client.Player{GameObject: box("enemy", 5, 0, 0.5), Role: utils.RoleMilitaryAI, Team: 2}
+	state.SmartObjectsByUID["villager"] = &client.SmartObject{
+		GameObject:      box("villager", -5, 3, 0.5),
+		UnitType:        "villager",
+		CurrentHealth:   80,
+		MaxHealth:       100,
+		ControllingTeam: 1,
+		ControllingRole: utils.RoleEconomyAI,
+	}
+	state.StaticObjects = append(state.StaticObjects, *box("wall", 0, -7, 1))
+	return state
+}
+
+func TestRenderASCII(t *testing.T) {
+	state := testState()
+	opts := &visualize.ASCIIOptions{
+		Width:  30,
+		Height: 30,
+		Options: visualize.Options{
+			Radius: 15,
+			Overlays: []visualize.Overlay{{
+				Label:  "path home",
+				Points: []cp.Vector{{X: 0, Y: 5}, {X: 0, Y: 10}},
+				Rune:   '+',
+			}},
+		},
+	}
+
+	rendered := visualize.RenderASCII(state, opts)
+	lines := strings.Split(rendered, "\n")
+	if len(lines) < 30 {
+		t.Fatalf("expected at least 30 lines, got:\n%s", rendered)
+	}
+
+	// each cell is a 1x1 world unit and the view is centered on us
+	expectCell := func(row, col int, expected rune) {
+		t.Helper()
+		actual := []rune(lines[row])[col]
+		if actual != expected {
+			t.Errorf("expected %q at row %d col %d, got %q in:\n%s", expected, row, col, actual, rendered)
+		}
+	}
+	expectCell(15, 15, '@')
+	expectCell(15, 20, '2')
+	expectCell(18, 10, 'V')
+	expectCell(7, 15, '#')
+	expectCell(22, 15, '+')
+	expectCell(5, 15, '.')
+	expectCell(15, 25, '.')
+
+	for _, expected := range []string{"villager 80/100 (team 1)", "enemy military (team 2)", "+ path home"} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("expected legend to contain %q, got:\n%s", expected, rendered)
+		}
+	}
+}
+
+func TestTerminal_Draw(t *testing.T) {
+	var buf bytes.Buffer
+	term := visualize.NewTerminal(&buf, &visualize.ASCIIOptions{Color: true})
+	err := term.Draw(testState())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "\x1b[H\x1b[2J") {
+		t.Errorf("expected the terminal to be cleared first, got %q", out[:10])
+	}
+	if !strings.Contains(out, "\x1b[1;34m2\x1b[0m") {
+		t.Errorf("expected the enemy to be colored for team 2")
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	svg := visualize.RenderSVG(testState(), &visualize.ImageOptions{
+		Options: visualize.Options{
+			Overlays: []visualize.Overlay{{Label: "<target>", Points: []cp.Vector{{X: 1, Y: 1}}}},
+		},
+	})
+
+	decoder := xml.NewDecoder(bytes.NewReader(svg))
+	counts := make(map[string]int)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid svg: %v\n%s", err, svg)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			counts[start.Name.Local]++
+		}
+	}
+
+	if counts["polygon"] != 4 {
+		t.Errorf("expected 4 polygons, got %d", counts["polygon"])
+	}
+	if counts["text"] != 4 {
+		t.Errorf("expected 4 labels, got %d", counts["text"])
+	}
+	for _, expected := range []string{"villager 80/100", "&lt;target&gt;", `stroke-dasharray`} {
+		if !bytes.Contains(svg, []byte(expected)) {
+			t.Errorf("expected svg to contain %q, got:\n%s", expected, svg)
+		}
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	var buf bytes.Buffer
+	err := visualize.WritePNG(&buf, testState(), &visualize.ImageOptions{PixelsPerUnit: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := int(2 * 1.5 * utils.VISION_DISTANCE * 4)
+	if img.Bounds().Dx() != size || img.Bounds().Dy() != size {
+		t.Fatalf("expected a %dx%d image, got %v", size, size, img.Bounds())
+	}
+
+	// the enemy is 5 units to the right of the center
+	r, g, b, _ := img.At(size/2+5*4, size/2).RGBA()
+	expected := visualize.TeamColor(2)
+	if uint8(r>>8) != expected.R || uint8(g>>8) != expected.G || uint8(b>>8) != expected.B {
+		t.Errorf("expected the enemy to be drawn in %v, got %d %d %d", expected, r>>8, g>>8, b>>8)
+	}
+}
+
+func TestFrameRecorder_Tick(t *testing.T) {
+	dir := t.TempDir()
+	rec := visualize.NewFrameRecorder(dir, 3, visualize.FrameFormatSVG)
+	state := testState()
+
+	var written []string
+	for i := 0; i < 7; i++ {
+		path, err := rec.Tick(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != "" {
+			written = append(written, filepath.Base(path))
+		}
+	}
+
+	expected := []string{"frame-000000.svg", "frame-000003.svg", "frame-000006.svg"}
+	if strings.Join(written, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected frames %v, got %v", expected, written)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(expected) {
+		t.Errorf("expected %d files, got %d", len(expected), len(files))
+	}
+}